@@ -327,6 +327,7 @@ type generateConfigConfig struct {
 	rules              string
 	rulesFile          string
 	resourceNames      string
+	audience           string
 	expiry             time.Duration
 	output             string
 	keyFile            string
@@ -371,7 +372,10 @@ func generateConfigCmd() *cobra.Command {
   # Rule with both list and get - list works on all, get restricted to specific names
   dctl generate-config --user=viewer --namespaces=default \
     --rules='[{"verbs":["get","list"],"apiGroups":[""],"resources":["pods"],"resourceNames":["pod-1"]}]' \
-    > viewer.config`,
+    > viewer.config
+
+  # Token for the BuildEvent webhook source of the ci-op operator, with no API server access
+  dctl generate-config --user=ci --audience=ci-op/BuildEvent --rules='[]' > ci.config`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runGenerateConfig(cfg)
 		},
@@ -383,6 +387,9 @@ func generateConfigCmd() *cobra.Command {
 	cmd.Flags().StringVar(&cfg.rulesFile, "rules-file", "", "Path to file containing RBAC PolicyRules JSON")
 	cmd.Flags().StringVar(&cfg.resourceNames, "resource-names", "",
 		"Comma-separated resource names to restrict access (applies to last rule only, ignored for list/watch/create verbs)")
+	cmd.Flags().StringVar(&cfg.audience, "audience", "",
+		"Comma-separated token audiences, e.g., <operator>/<kind> for webhook sources (empty = no audience, "+
+			"requires --rules or --rules-file)")
 	cmd.Flags().DurationVar(&cfg.expiry, "expiry", 24*365*time.Hour, "Token expiry duration")
 	cmd.Flags().StringVar(&cfg.output, "output", "", "Output kubeconfig file (default: stdout)")
 	cmd.Flags().StringVar(&cfg.keyFile, "tls-key-file", "apiserver.key",
@@ -398,6 +405,12 @@ func generateConfigCmd() *cobra.Command {
 }
 
 func runGenerateConfig(cfg generateConfigConfig) error {
+	// Tokens issued for an audience must not default to full access
+	if cfg.audience != "" && cfg.rules == "" && cfg.rulesFile == "" {
+		return fmt.Errorf("--audience requires explicit --rules or --rules-file, " +
+			"use --rules='[]' for a token with no permissions")
+	}
+
 	// Parse namespaces
 	var namespacesList []string
 	if cfg.namespaces != "" {
@@ -447,7 +460,8 @@ func runGenerateConfig(cfg generateConfigConfig) error {
 
 	// Generate JWT token
 	generator := auth.NewTokenGenerator(privateKey)
-	token, err := generator.GenerateToken(cfg.username, namespacesList, rulesList, cfg.expiry)
+	token, err := generator.GenerateTokenForAudience(cfg.username, parseCommaSeparated(cfg.audience),
+		namespacesList, rulesList, cfg.expiry)
 	if err != nil {
 		return fmt.Errorf("failed to generate token: %w", err)
 	}
@@ -500,6 +514,9 @@ func runGenerateConfig(cfg generateConfigConfig) error {
 		} else {
 			fmt.Fprintf(os.Stderr, "   Rules: <none - full access>\n")
 		}
+		if cfg.audience != "" {
+			fmt.Fprintf(os.Stderr, "   Audience: %s\n", cfg.audience)
+		}
 		fmt.Fprintf(os.Stderr, "   Expiry: %s\n", cfg.expiry)
 		fmt.Fprintf(os.Stderr, "   Server: %s\n", cfg.serverAddress)
 	}
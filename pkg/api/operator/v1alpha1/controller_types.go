@@ -50,12 +50,47 @@ type Source struct {
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Predicate *predicate.Predicate `json:"predicate,omitempty"`
 	// Parameters contains arbitrary source-specific parameters for virtual sources.
-	// For example, Periodic sources use {"period": "5m"}.
+	// For example, Periodic sources use {"period": "5m"} and Cron sources use
+	// {"schedule": "0 2 * * 1-5", "timeZone": "Europe/Budapest"}.
 	//
 	// +kubebuilder:validation:Schemaless
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +optional
 	Parameters *apiextensionsv1.JSON `json:"parameters,omitempty"`
+	// Cluster, if given, makes the source watch the resource in a remote cluster instead of the
+	// cluster of the operator. Objects from a remote cluster are annotated with the name of the
+	// cluster, and objects with the same name in different clusters are distinct. Only
+	// applicable to Watcher sources.
+	//
+	// +optional
+	Cluster *ClusterReference `json:"cluster,omitempty"`
+}
+
+// ClusterReference refers to a remote cluster by a kubeconfig. Exactly one of SecretRef and
+// File must be given.
+type ClusterReference struct {
+	// Name identifies the cluster. Objects watched in the cluster are annotated with this name.
+	Name string `json:"name"`
+	// SecretRef refers to a Secret holding the kubeconfig of the cluster.
+	//
+	// +optional
+	SecretRef *KubeconfigSecretReference `json:"secretRef,omitempty"`
+	// File is the path of the kubeconfig file of the cluster.
+	//
+	// +optional
+	File string `json:"file,omitempty"`
+}
+
+// KubeconfigSecretReference refers to a key of a Secret holding a kubeconfig.
+type KubeconfigSecretReference struct {
+	// Name is the name of the Secret.
+	Name string `json:"name"`
+	// Namespace is the namespace of the Secret.
+	Namespace string `json:"namespace"`
+	// Key is the key in the Secret that holds the kubeconfig. Default is "kubeconfig".
+	//
+	// +optional
+	Key string `json:"key,omitempty"`
 }
 
 // SourceType represents the type of a source.
@@ -68,6 +103,14 @@ const (
 	Periodic SourceType = "Periodic"
 	// OneShot is a source that emits a single empty object for initialization.
 	OneShot SourceType = "OneShot"
+	// Cron is a source that emits a trigger object on a cron schedule.
+	Cron SourceType = "Cron"
+	// Webhook is a source that turns the JSON documents posted to an HTTP endpoint into objects.
+	Webhook SourceType = "Webhook"
+	// File is a source that loads objects from the YAML manifests in a directory.
+	File SourceType = "File"
+	// Events is a source that replays Kubernetes events or API server audit logs into objects.
+	Events SourceType = "Events"
 )
 
 // Target is the target reource type in which the controller writes.
@@ -8,16 +8,50 @@ import (
 )
 
 // Pipeline is a sequence of pipeline operations that process objects.
-// The first operation may optionally be a @join operation.
+// The first operation may optionally be a @join, @leftJoin, @antiJoin or @union operation,
+// optionally preceded by @define operations that bind named sub-expressions for the subsequent
+// operations. The @distinct operation removes duplicate objects from the stream, the @window
+// operation aggregates objects over time windows and the @recurse operation evaluates recursive
+// queries.
 // The pipeline can be specified as:
 //   - A single operation: pipeline: {"@project": ...}
 //   - An array of operations: pipeline: [{"@join": ...}, {"@select": ...}]
+//   - An object with named branches that pre-process the sources before the operations:
+//     pipeline: {"branches": [{"name": "Pod", "ops": [{"@select": ...}]}], "ops": [{"@join": ...}]}
 //
 // +kubebuilder:object:generate=false
 type Pipeline struct {
+	// Branches is an ordered list of named sub-pipelines evaluated before the operations.
+	Branches []Branch
+	// Ops is the list of operations.
 	Ops []PipelineOp
 }
 
+// Branch is a named sub-pipeline. A branch consumes the sources, or the branches defined before
+// it, listed in Sources (by default, the source with the same name as the branch), and produces a
+// stream that the subsequent branches and the operations of the pipeline refer to by the name of
+// the branch. A branch with the same name as a source or a previous branch shadows it. The
+// operations of the pipeline consume the streams that are not consumed by any branch.
+//
+// +kubebuilder:object:generate=false
+type Branch struct {
+	// Name is the name of the stream produced by the branch.
+	Name string `json:"name"`
+	// Sources is the list of the sources or branches consumed by the branch. A branch with
+	// multiple sources must start with a join.
+	Sources []string `json:"sources,omitempty"`
+	// Ops is the list of operations of the branch.
+	Ops []PipelineOp `json:"-"`
+}
+
+// GetSources returns the names of the streams consumed by the branch.
+func (b *Branch) GetSources() []string {
+	if len(b.Sources) == 0 {
+		return []string{b.Name}
+	}
+	return b.Sources
+}
+
 // PipelineOp represents a pipeline operation that wraps one or more expressions.
 //
 // +kubebuilder:object:generate=false
@@ -40,6 +74,49 @@ func (o *JoinOp) GetExpression() *expression.Expression {
 }
 func (o *JoinOp) OpType() string { return "@join" }
 
+// LeftJoinOp represents a @leftJoin operation with a join condition expression. Objects of the
+// first source that do not join with any of the objects of the other sources are passed on as a
+// compound object that contains only the first source.
+//
+// +kubebuilder:object:generate=false
+type LeftJoinOp struct {
+	Expression expression.Expression
+}
+
+func (o *LeftJoinOp) GetExpression() *expression.Expression {
+	return &o.Expression
+}
+func (o *LeftJoinOp) OpType() string { return "@leftJoin" }
+
+// AntiJoinOp represents an @antiJoin operation with a join condition expression. Only the objects
+// of the first source that do not join with any of the objects of the other sources are passed on,
+// as a compound object that contains only the first source.
+//
+// +kubebuilder:object:generate=false
+type AntiJoinOp struct {
+	Expression expression.Expression
+}
+
+func (o *AntiJoinOp) GetExpression() *expression.Expression {
+	return &o.Expression
+}
+func (o *AntiJoinOp) OpType() string { return "@antiJoin" }
+
+// UnionOp represents a @union operation that merges the objects of multiple sources without
+// joining them. The expression is a map from source names to optional projection expressions
+// that are applied to the objects of the respective source before the merge; objects of sources
+// without a projection are passed on unchanged.
+//
+// +kubebuilder:object:generate=false
+type UnionOp struct {
+	Expression expression.Expression
+}
+
+func (o *UnionOp) GetExpression() *expression.Expression {
+	return &o.Expression
+}
+func (o *UnionOp) OpType() string { return "@union" }
+
 // SelectionOp represents a @select operation with a filter expression.
 //
 // +kubebuilder:object:generate=false
@@ -88,15 +165,83 @@ func (o *GatherOp) GetExpression() *expression.Expression {
 }
 func (o *GatherOp) OpType() string { return "@gather" }
 
+// WindowOp represents a @window operation that aggregates the objects in time windows. The
+// expression must be a map with the grouping key, the timestamp, the size and optionally the slide
+// of the window, and the aggregation functions.
+//
+// +kubebuilder:object:generate=false
+type WindowOp struct {
+	Expression expression.Expression
+}
+
+func (o *WindowOp) GetExpression() *expression.Expression {
+	return &o.Expression
+}
+func (o *WindowOp) OpType() string { return "@window" }
+
+// RecurseOp represents a @recurse operation that computes the least fixpoint of a recursive query,
+// e.g., the transitive closure of a relation. The result is the set of objects produced by the
+// base operations on the input of the op, plus the objects produced by the step operations on the
+// join of the result with the input, repeated until no new objects are found. The step operations
+// see the result as "self" and the input as "input".
+//
+// +kubebuilder:object:generate=false
+type RecurseOp struct {
+	Expression expression.Expression
+	// Base is the list of operations applied to the input. If empty, the input is used as is.
+	Base []PipelineOp
+	// Step is the list of operations applied to the result and the input. The list must start
+	// with a join or a union.
+	Step []PipelineOp
+	// MaxIterations bounds the number of iterations. Zero means the default bound.
+	MaxIterations int
+}
+
+func (o *RecurseOp) GetExpression() *expression.Expression {
+	return &o.Expression
+}
+func (o *RecurseOp) OpType() string { return "@recurse" }
+
+// DefineOp represents a @define operation that binds named sub-expressions. The expression must be
+// a map from names to expressions. The subsequent operations can refer to the definitions as
+// "$<name>", which evaluates the sub-expression on the object processed by the operation.
+//
+// +kubebuilder:object:generate=false
+type DefineOp struct {
+	Expression expression.Expression
+}
+
+func (o *DefineOp) GetExpression() *expression.Expression {
+	return &o.Expression
+}
+func (o *DefineOp) OpType() string { return "@define" }
+
+// DistinctOp represents a @distinct operation that removes duplicate objects. The operation takes
+// no arguments, the canonical form is an empty map: {"@distinct": {}}.
+//
+// +kubebuilder:object:generate=false
+type DistinctOp struct {
+	Expression expression.Expression
+}
+
+func (o *DistinctOp) GetExpression() *expression.Expression {
+	if o.Expression.Op == "" {
+		return &expression.Expression{Op: "@dict", Literal: map[string]expression.Expression{}}
+	}
+	return &o.Expression
+}
+func (o *DistinctOp) OpType() string { return "@distinct" }
+
 // DeepCopyInto is a manual deepcopy implementation for Pipeline since it contains interfaces.
 func (in *Pipeline) DeepCopyInto(out *Pipeline) {
 	*out = *in
-	if in.Ops != nil {
-		out.Ops = make([]PipelineOp, len(in.Ops))
-		for i := range in.Ops {
-			out.Ops[i] = deepCopyPipelineOp(in.Ops[i])
+	if in.Branches != nil {
+		out.Branches = make([]Branch, len(in.Branches))
+		for i := range in.Branches {
+			in.Branches[i].DeepCopyInto(&out.Branches[i])
 		}
 	}
+	out.Ops = deepCopyPipelineOps(in.Ops)
 }
 
 // DeepCopy is a manual deepcopy implementation for Pipeline.
@@ -109,6 +254,28 @@ func (in *Pipeline) DeepCopy() *Pipeline {
 	return out
 }
 
+// DeepCopyInto is a manual deepcopy implementation for Branch since it contains interfaces.
+func (in *Branch) DeepCopyInto(out *Branch) {
+	*out = *in
+	if in.Sources != nil {
+		out.Sources = make([]string, len(in.Sources))
+		copy(out.Sources, in.Sources)
+	}
+	out.Ops = deepCopyPipelineOps(in.Ops)
+}
+
+// deepCopyPipelineOps creates a deep copy of a list of PipelineOps.
+func deepCopyPipelineOps(ops []PipelineOp) []PipelineOp {
+	if ops == nil {
+		return nil
+	}
+	ret := make([]PipelineOp, len(ops))
+	for i := range ops {
+		ret[i] = deepCopyPipelineOp(ops[i])
+	}
+	return ret
+}
+
 // deepCopyPipelineOp creates a deep copy of a PipelineOp.
 func deepCopyPipelineOp(op PipelineOp) PipelineOp {
 	if op == nil {
@@ -118,6 +285,12 @@ func deepCopyPipelineOp(op PipelineOp) PipelineOp {
 	switch v := op.(type) {
 	case *JoinOp:
 		return &JoinOp{Expression: v.Expression}
+	case *LeftJoinOp:
+		return &LeftJoinOp{Expression: v.Expression}
+	case *AntiJoinOp:
+		return &AntiJoinOp{Expression: v.Expression}
+	case *UnionOp:
+		return &UnionOp{Expression: v.Expression}
 	case *SelectionOp:
 		return &SelectionOp{Expression: v.Expression}
 	case *ProjectionOp:
@@ -126,35 +299,122 @@ func deepCopyPipelineOp(op PipelineOp) PipelineOp {
 		return &UnwindOp{Expression: v.Expression}
 	case *GatherOp:
 		return &GatherOp{Expression: v.Expression}
+	case *WindowOp:
+		return &WindowOp{Expression: v.Expression}
+	case *RecurseOp:
+		return &RecurseOp{
+			Expression:    v.Expression,
+			Base:          deepCopyPipelineOps(v.Base),
+			Step:          deepCopyPipelineOps(v.Step),
+			MaxIterations: v.MaxIterations,
+		}
+	case *DefineOp:
+		return &DefineOp{Expression: v.Expression}
+	case *DistinctOp:
+		return &DistinctOp{Expression: v.Expression}
 	default:
 		return nil
 	}
 }
 
 // UnmarshalJSON implements custom JSON unmarshaling for Pipeline.
-// It handles both single operations and arrays of operations.
+// It handles single operations, arrays of operations and objects with branches.
 func (p *Pipeline) UnmarshalJSON(data []byte) error {
+	// Try to unmarshal as an object with branches first.
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err == nil && !isPipelineOp(obj) {
+		var branched struct {
+			Branches []Branch        `json:"branches"`
+			Ops      json.RawMessage `json:"ops"`
+		}
+		if err := json.Unmarshal(data, &branched); err != nil {
+			return err
+		}
+		for k := range obj {
+			if k != "branches" && k != "ops" {
+				return fmt.Errorf("invalid pipeline: unknown field %q", k)
+			}
+		}
+		p.Branches = branched.Branches
+		p.Ops = []PipelineOp{}
+		if len(branched.Ops) > 0 {
+			ops, err := unmarshalPipelineOps(branched.Ops)
+			if err != nil {
+				return err
+			}
+			p.Ops = ops
+		}
+		return nil
+	}
+
+	ops, err := unmarshalPipelineOps(data)
+	if err != nil {
+		return err
+	}
+	p.Branches = nil
+	p.Ops = ops
+	return nil
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Branch.
+func (b *Branch) UnmarshalJSON(data []byte) error {
+	var branch struct {
+		Name    string          `json:"name"`
+		Sources []string        `json:"sources,omitempty"`
+		Ops     json.RawMessage `json:"ops"`
+	}
+	if err := json.Unmarshal(data, &branch); err != nil {
+		return err
+	}
+	if branch.Name == "" {
+		return fmt.Errorf("invalid branch %q: empty name", string(data))
+	}
+
+	b.Name = branch.Name
+	b.Sources = branch.Sources
+	b.Ops = []PipelineOp{}
+	if len(branch.Ops) > 0 {
+		ops, err := unmarshalPipelineOps(branch.Ops)
+		if err != nil {
+			return err
+		}
+		b.Ops = ops
+	}
+	return nil
+}
+
+// isPipelineOp checks whether an object is a single pipeline operation.
+func isPipelineOp(obj map[string]json.RawMessage) bool {
+	for k := range obj {
+		if len(k) > 0 && k[0] == '@' {
+			return true
+		}
+	}
+	return false
+}
+
+// unmarshalPipelineOps unmarshals a single operation or an array of operations.
+func unmarshalPipelineOps(data []byte) ([]PipelineOp, error) {
 	// Try to unmarshal as array first.
 	var rawArray []json.RawMessage
 	if err := json.Unmarshal(data, &rawArray); err == nil {
-		p.Ops = make([]PipelineOp, 0, len(rawArray))
+		ops := make([]PipelineOp, 0, len(rawArray))
 		for _, raw := range rawArray {
 			op, err := unmarshalPipelineOp(raw)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			p.Ops = append(p.Ops, op)
+			ops = append(ops, op)
 		}
-		return nil
+		return ops, nil
 	}
 
 	// Try to unmarshal as single operation.
 	op, err := unmarshalPipelineOp(data)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	p.Ops = []PipelineOp{op}
-	return nil
+	return []PipelineOp{op}, nil
 }
 
 // unmarshalPipelineOp unmarshals a single pipeline operation.
@@ -179,6 +439,12 @@ func unmarshalPipelineOp(data []byte) (PipelineOp, error) {
 	switch opName {
 	case "@join":
 		return &JoinOp{Expression: expr}, nil
+	case "@leftJoin":
+		return &LeftJoinOp{Expression: expr}, nil
+	case "@antiJoin":
+		return &AntiJoinOp{Expression: expr}, nil
+	case "@union":
+		return &UnionOp{Expression: expr}, nil
 	case "@select":
 		return &SelectionOp{Expression: expr}, nil
 	case "@project":
@@ -187,20 +453,98 @@ func unmarshalPipelineOp(data []byte) (PipelineOp, error) {
 		return &UnwindOp{Expression: expr}, nil
 	case "@gather", "@mux":
 		return &GatherOp{Expression: expr}, nil
+	case "@window":
+		return &WindowOp{Expression: expr}, nil
+	case "@recurse":
+		return unmarshalRecurseOp(data, expr)
+	case "@define":
+		return &DefineOp{Expression: expr}, nil
+	case "@distinct":
+		return &DistinctOp{Expression: expr}, nil
 	default:
 		return nil, fmt.Errorf("unknown pipeline op %q", string(data))
 	}
 }
 
+// unmarshalRecurseOp unmarshals the operation lists of a @recurse operation.
+func unmarshalRecurseOp(data []byte, expr expression.Expression) (PipelineOp, error) {
+	var op map[string]json.RawMessage
+	if err := json.Unmarshal(data, &op); err != nil {
+		return nil, err
+	}
+
+	var args map[string]json.RawMessage
+	if err := json.Unmarshal(op["@recurse"], &args); err != nil {
+		return nil, fmt.Errorf("invalid @recurse op %q: expected a map", string(data))
+	}
+
+	ret := &RecurseOp{Expression: expr}
+	for k, v := range args {
+		switch k {
+		case "base", "step":
+			ops, err := unmarshalPipelineOps(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid @recurse op: %s: %w", k, err)
+			}
+			if k == "base" {
+				ret.Base = ops
+			} else {
+				ret.Step = ops
+			}
+		case "maxIterations":
+			if err := json.Unmarshal(v, &ret.MaxIterations); err != nil {
+				return nil, fmt.Errorf("invalid @recurse op: maxIterations: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("invalid @recurse op: unknown field %q", k)
+		}
+	}
+	if len(ret.Step) == 0 {
+		return nil, fmt.Errorf("invalid @recurse op %q: no step operations", string(data))
+	}
+
+	return ret, nil
+}
+
 // MarshalJSON implements custom JSON marshaling for Pipeline.
 // It marshals as an array if there are multiple operations, otherwise as a single operation.
+// Pipelines with branches are marshaled as an object.
 func (p Pipeline) MarshalJSON() ([]byte, error) {
+	if len(p.Branches) > 0 {
+		ops, err := marshalPipelineOps(p.Ops)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(struct {
+			Branches []Branch        `json:"branches"`
+			Ops      json.RawMessage `json:"ops"`
+		}{Branches: p.Branches, Ops: ops})
+	}
+
 	if len(p.Ops) == 1 {
 		return marshalPipelineOp(p.Ops[0])
 	}
 
-	ops := make([]json.RawMessage, len(p.Ops))
-	for i, op := range p.Ops {
+	return marshalPipelineOps(p.Ops)
+}
+
+// MarshalJSON implements custom JSON marshaling for Branch.
+func (b Branch) MarshalJSON() ([]byte, error) {
+	ops, err := marshalPipelineOps(b.Ops)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Name    string          `json:"name"`
+		Sources []string        `json:"sources,omitempty"`
+		Ops     json.RawMessage `json:"ops"`
+	}{Name: b.Name, Sources: b.Sources, Ops: ops})
+}
+
+// marshalPipelineOps marshals a list of pipeline operations as an array.
+func marshalPipelineOps(pipelineOps []PipelineOp) ([]byte, error) {
+	ops := make([]json.RawMessage, len(pipelineOps))
+	for i, op := range pipelineOps {
 		data, err := marshalPipelineOp(op)
 		if err != nil {
 			return nil, err
@@ -16,6 +16,7 @@ import (
 // JWTAuthenticator validates JWT tokens and extracts user info
 type JWTAuthenticator struct {
 	publicKey *rsa.PublicKey
+	audience  string // Required audience (empty = tokens without an audience only)
 }
 
 // Claims represents the JWT claims we use
@@ -26,11 +27,18 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// NewJWTAuthenticator creates a new JWT authenticator
+// NewJWTAuthenticator creates a new JWT authenticator. It rejects the tokens issued for an
+// audience, e.g., for a webhook source, so that they cannot be used to access the API server.
 func NewJWTAuthenticator(publicKey *rsa.PublicKey) *JWTAuthenticator {
 	return &JWTAuthenticator{publicKey: publicKey}
 }
 
+// NewJWTAuthenticatorForAudience creates a new JWT authenticator that accepts only the tokens
+// issued for the given audience
+func NewJWTAuthenticatorForAudience(publicKey *rsa.PublicKey, audience string) *JWTAuthenticator {
+	return &JWTAuthenticator{publicKey: publicKey, audience: audience}
+}
+
 // AuthenticateRequest implements authenticator.Request
 func (a *JWTAuthenticator) AuthenticateRequest(req *http.Request) (*authenticator.Response, bool, error) {
 	// Extract bearer token
@@ -47,17 +55,26 @@ func (a *JWTAuthenticator) AuthenticateRequest(req *http.Request) (*authenticato
 
 	// Parse and validate JWT
 	claims := &Claims{}
+	opts := []jwt.ParserOption{}
+	if a.audience != "" {
+		opts = append(opts, jwt.WithAudience(a.audience))
+	}
 	jwtToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return a.publicKey, nil
-	})
+	}, opts...)
 
 	if err != nil || !jwtToken.Valid {
 		return nil, false, fmt.Errorf("invalid token: %w", err)
 	}
 
+	if a.audience == "" && len(claims.Audience) > 0 {
+		return nil, false, fmt.Errorf("invalid token: token is issued for audience %v",
+			[]string(claims.Audience))
+	}
+
 	// Build user info with namespace and RBAC rules
 	extra := make(map[string][]string)
 	if len(claims.Namespaces) > 0 {
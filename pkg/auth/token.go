@@ -28,12 +28,19 @@ func NewTokenGenerator(privateKey *rsa.PrivateKey) *TokenGenerator {
 
 // GenerateToken creates a JWT token for a user with namespace and RBAC rules
 func (g *TokenGenerator) GenerateToken(username string, namespaces []string, rules []rbacv1.PolicyRule, expiry time.Duration) (string, error) {
+	return g.GenerateTokenForAudience(username, nil, namespaces, rules, expiry)
+}
+
+// GenerateTokenForAudience creates a JWT token for a user that is issued for the given audience,
+// e.g., a webhook source
+func (g *TokenGenerator) GenerateTokenForAudience(username string, audience []string, namespaces []string, rules []rbacv1.PolicyRule, expiry time.Duration) (string, error) {
 	now := time.Now()
 	claims := Claims{
 		Username:   username,
 		Namespaces: namespaces,
 		Rules:      rules,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  audience,
 			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
@@ -1,6 +1,8 @@
 package auth_test
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -187,4 +189,55 @@ var _ = Describe("Token Generation and Parsing", func() {
 			Expect(claims.Issuer).To(Equal("dcontroller"))
 		})
 	})
+
+	Context("Token audience", func() {
+		request := func(token string) *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			return req
+		}
+
+		It("should accept only the tokens issued for the audience", func() {
+			token, err := generator.GenerateTokenForAudience("ci", []string{"op/BuildEvent"}, nil, nil, 1*time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mustParseToken(token, publicKey).Audience).To(ConsistOf("op/BuildEvent"))
+
+			authenticator := auth.NewJWTAuthenticatorForAudience(publicKey, "op/BuildEvent")
+			resp, ok, err := authenticator.AuthenticateRequest(request(token))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(resp.User.GetName()).To(Equal("ci"))
+
+			// other audience
+			other, err := generator.GenerateTokenForAudience("ci", []string{"op/Other"}, nil, nil, 1*time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			_, ok, err = authenticator.AuthenticateRequest(request(other))
+			Expect(err).To(HaveOccurred())
+			Expect(ok).To(BeFalse())
+
+			// no audience
+			unscoped, err := generator.GenerateToken("ci", nil, nil, 1*time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			_, ok, err = authenticator.AuthenticateRequest(request(unscoped))
+			Expect(err).To(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should reject the tokens issued for an audience without an audience", func() {
+			authenticator := auth.NewJWTAuthenticator(publicKey)
+
+			token, err := generator.GenerateTokenForAudience("ci", []string{"op/BuildEvent"}, nil, nil, 1*time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			_, ok, err := authenticator.AuthenticateRequest(request(token))
+			Expect(err).To(HaveOccurred())
+			Expect(ok).To(BeFalse())
+
+			unscoped, err := generator.GenerateToken("admin", nil, nil, 1*time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			resp, ok, err := authenticator.AuthenticateRequest(request(unscoped))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(resp.User.GetName()).To(Equal("admin"))
+		})
+	})
 })
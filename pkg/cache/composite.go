@@ -2,6 +2,8 @@ package cache
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -14,12 +16,21 @@ import (
 
 var _ cache.Cache = &CompositeCache{}
 
+// OriginClusterAnnotation is the annotation that holds the name of the remote cluster an object
+// was watched in.
+const OriginClusterAnnotation = "dcontroller.io/origin-cluster"
+
 // CompositeCache is a cache for storing view objects. It delegates native objects to a default
-// cache.
+// cache. Native objects watched in remote clusters are served from per-cluster caches, see
+// NewClusterCache.
 type CompositeCache struct {
-	defaultCache cache.Cache
-	viewCache    ViewCacheInterface
-	logger, log  logr.Logger
+	defaultCache  cache.Cache
+	viewCache     ViewCacheInterface
+	clusterCaches map[string]cache.Cache
+	clusterOpts   cache.Options
+	ctx           context.Context // non-nil once the cache is started
+	mu            sync.Mutex
+	logger, log   logr.Logger
 }
 
 // CacheOptions are generic caching options.
@@ -65,10 +76,13 @@ func NewCompositeCache(config *rest.Config, opts CacheOptions) (*CompositeCache,
 	}
 
 	return &CompositeCache{
-		defaultCache: defaultCache,
-		viewCache:    viewCache,
-		logger:       logger,
-		log:          logger.WithName("cache"),
+		defaultCache:  defaultCache,
+		viewCache:     viewCache,
+		clusterCaches: map[string]cache.Cache{},
+		// The REST mapper and the HTTP client of the options belong to the local cluster.
+		clusterOpts: cache.Options{Scheme: opts.Scheme, SyncPeriod: opts.SyncPeriod},
+		logger:      logger,
+		log:         logger.WithName("cache"),
 	}, nil
 }
 
@@ -87,6 +101,66 @@ func (cc *CompositeCache) GetViewCache() ViewCacheInterface {
 	return cc.viewCache
 }
 
+// GetClusterCache returns the cache for the objects of a remote cluster.
+func (cc *CompositeCache) GetClusterCache(cluster string) (Cache, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	c, ok := cc.clusterCaches[cluster]
+	return c, ok
+}
+
+// NewClusterCache returns the cache for the objects of a remote cluster, creating a new
+// controller-runtime cache from the REST config if the cluster does not have a cache yet.
+func (cc *CompositeCache) NewClusterCache(cluster string, config *rest.Config) (Cache, error) {
+	if c, ok := cc.GetClusterCache(cluster); ok {
+		return c, nil
+	}
+
+	c, err := cache.New(config, cc.clusterOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache for cluster %q: %w", cluster, err)
+	}
+
+	if err := cc.AddClusterCache(cluster, c); err != nil {
+		// lost a race: use the cache added concurrently
+		if c, ok := cc.GetClusterCache(cluster); ok {
+			return c, nil
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// AddClusterCache registers a cache for the objects of a remote cluster. The cache is started
+// with the composite cache, or right away if the composite cache is already running.
+func (cc *CompositeCache) AddClusterCache(cluster string, c Cache) error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if _, ok := cc.clusterCaches[cluster]; ok {
+		return fmt.Errorf("cache for cluster %q already exists", cluster)
+	}
+	cc.clusterCaches[cluster] = c
+
+	cc.log.V(2).Info("adding cluster cache", "cluster", cluster)
+
+	if cc.ctx != nil {
+		cc.startClusterCache(cc.ctx, cluster, c)
+	}
+
+	return nil
+}
+
+// startClusterCache starts a cluster cache in the background.
+func (cc *CompositeCache) startClusterCache(ctx context.Context, cluster string, c Cache) {
+	go func() {
+		if err := c.Start(ctx); err != nil {
+			cc.log.Error(err, "cluster cache failed", "cluster", cluster)
+		}
+	}()
+}
+
 // GetInformer fetches or constructs an informer for the given object.
 func (cc *CompositeCache) GetInformer(ctx context.Context, obj client.Object, opts ...cache.InformerGetOption) (cache.Informer, error) {
 	gvk := obj.GetObjectKind().GroupVersionKind()
@@ -132,12 +206,35 @@ func (cc *CompositeCache) Start(ctx context.Context) error {
 		go cc.defaultCache.Start(ctx) //nolint:errcheck
 	}
 
+	cc.mu.Lock()
+	cc.ctx = ctx
+	for cluster, c := range cc.clusterCaches {
+		cc.startClusterCache(ctx, cluster, c)
+	}
+	cc.mu.Unlock()
+
 	return cc.viewCache.Start(ctx)
 }
 
 // WaitForCacheSync waits for all the caches to sync. Returns false if it could not sync a cache.c
 func (cc *CompositeCache) WaitForCacheSync(ctx context.Context) bool {
-	return cc.viewCache.WaitForCacheSync(ctx) && cc.defaultCache.WaitForCacheSync(ctx)
+	if !cc.viewCache.WaitForCacheSync(ctx) || !cc.defaultCache.WaitForCacheSync(ctx) {
+		return false
+	}
+
+	cc.mu.Lock()
+	caches := make([]cache.Cache, 0, len(cc.clusterCaches))
+	for _, c := range cc.clusterCaches {
+		caches = append(caches, c)
+	}
+	cc.mu.Unlock()
+
+	for _, c := range caches {
+		if !c.WaitForCacheSync(ctx) {
+			return false
+		}
+	}
+	return true
 }
 
 // IndexField adds an index with the given field name on the given object type.
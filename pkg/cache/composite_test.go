@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -10,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/l7mp/dcontroller/pkg/object"
@@ -174,4 +176,42 @@ var _ = Describe("CompositeCache", func() {
 		})
 	})
 
+	Describe("Cluster caches", func() {
+		It("should register a cache per cluster", func() {
+			_, ok := cache.GetClusterCache("cluster-b")
+			Expect(ok).To(BeFalse())
+
+			clusterCache := NewFakeRuntimeCache(scheme.Scheme)
+			Expect(cache.AddClusterCache("cluster-b", clusterCache)).To(Succeed())
+			Expect(cache.AddClusterCache("cluster-b", NewFakeRuntimeCache(scheme.Scheme))).NotTo(Succeed())
+
+			c, ok := cache.GetClusterCache("cluster-b")
+			Expect(ok).To(BeTrue())
+			Expect(c).To(BeIdenticalTo(clusterCache))
+
+			// the existing cache is reused
+			c, err := cache.NewClusterCache("cluster-b", &rest.Config{Host: "https://cluster-b.example.com"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c).To(BeIdenticalTo(clusterCache))
+
+			// objects in the cluster cache are not served from the composite cache
+			Expect(clusterCache.Add(pod)).To(Succeed())
+			retrieved := object.DeepCopy(pod)
+			Expect(cache.Get(ctx, client.ObjectKeyFromObject(retrieved), retrieved)).NotTo(Succeed())
+		})
+
+		It("should wait for the cluster caches to sync", func() {
+			synced := false
+			clusterCache := NewFakeRuntimeCache(scheme.Scheme)
+			clusterCache.Synced = &synced
+			Expect(cache.AddClusterCache("cluster-b", clusterCache)).To(Succeed())
+
+			ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer cancel()
+			Expect(cache.WaitForCacheSync(ctx)).To(BeFalse())
+
+			synced = true
+			Expect(cache.WaitForCacheSync(ctx)).To(BeTrue())
+		})
+	})
 })
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	toolscache "k8s.io/client-go/tools/cache"
 
 	"github.com/l7mp/dcontroller/pkg/object"
@@ -16,6 +17,30 @@ func NewStore() *Store {
 	return &Store{Store: toolscache.NewStore(toolscache.MetaNamespaceKeyFunc)}
 }
 
+// NewClusterStore creates a new Store that keys the objects watched in remote clusters by the
+// origin cluster, the namespace and the name, see ClusterKeyFunc.
+func NewClusterStore() *Store {
+	return &Store{Store: toolscache.NewStore(ClusterKeyFunc)}
+}
+
+// ClusterKeyFunc is a key function that prefixes the namespace/name key of the objects watched in
+// remote clusters with the name of the origin cluster, so that the objects with the same name in
+// different clusters are stored under different keys.
+func ClusterKeyFunc(obj any) (string, error) {
+	key, err := toolscache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return "", err
+	}
+
+	if o, ok := obj.(metav1.Object); ok {
+		if cluster := o.GetAnnotations()[OriginClusterAnnotation]; cluster != "" {
+			return cluster + ":" + key, nil
+		}
+	}
+
+	return key, nil
+}
+
 // Add adds the given object to the database associated with the given object's key.
 func (s *Store) Add(obj object.Object) error { return s.Store.Add(object.DeepCopy(obj)) }
 
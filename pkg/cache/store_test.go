@@ -83,4 +83,33 @@ var _ = Describe("Store", func() {
 			Expect(objs).To(BeEmpty())
 		})
 	})
+
+	Describe("Cluster store", func() {
+		It("should keep the objects with the same name in different clusters apart", func() {
+			store = NewClusterStore()
+
+			objA := object.DeepCopy(obj1)
+			objA.SetAnnotations(map[string]string{OriginClusterAnnotation: "cluster-a"})
+			objB := object.DeepCopy(obj1)
+			objB.SetAnnotations(map[string]string{OriginClusterAnnotation: "cluster-b"})
+
+			for _, obj := range []object.Object{obj1, objA, objB} {
+				Expect(store.Add(obj)).To(Succeed())
+			}
+			Expect(store.List()).To(HaveLen(3))
+
+			key, err := ClusterKeyFunc(objA)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key).To(Equal("cluster-a:ns/name"))
+
+			Expect(store.Delete(objB)).To(Succeed())
+			_, ok, err := store.Get(objB)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			retrieved, ok, err := store.Get(objA)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(retrieved.GetAnnotations()).To(HaveKeyWithValue(OriginClusterAnnotation, "cluster-a"))
+		})
+	})
 })
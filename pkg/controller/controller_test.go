@@ -455,6 +455,41 @@ target:
 			err = yaml.Unmarshal([]byte(yamlData), &config)
 			Expect(err).To(HaveOccurred())
 		})
+
+		It("should reject a controller with an ill-typed pipeline", func() {
+			mgr, err := manager.NewFakeManager(manager.Options{Logger: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mgr).NotTo(BeNil())
+
+			go func() { mgr.Start(ctx) }()
+
+			yamlData := `
+name: test
+sources:
+  - apiGroup: ""
+    kind: Pod
+pipeline:
+  - '@select':
+      '@eq': ["$.metadata.namespace", "default"]
+  - '@project':
+      metadata: "$.metadata"
+      spec:
+        ports:
+          '@len': 12
+target:
+  apiGroup: ""
+  kind: Pod
+  type: Patcher`
+
+			var config opv1a1.Controller
+			err = yaml.Unmarshal([]byte(yamlData), &config)
+			Expect(err).NotTo(HaveOccurred())
+
+			c, err := NewDeclarative(mgr, "test", config, Options{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("pipeline[1].@project.spec.ports.@len"))
+			Expect(c.(*DeclarativeController).sources).To(BeEmpty())
+		})
 	})
 
 	Describe("With complex Controllers", func() {
@@ -7,6 +7,7 @@ import (
 	"github.com/go-logr/logr"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -66,6 +67,11 @@ func NewDeclarative(mgr manager.Manager, operator string, config opv1a1.Controll
 		return c, c.PushCriticalError("invalid controller configuration: no target")
 	}
 
+	// Reject invalid pipelines before setting up the watches.
+	if err := pipeline.Validate(config.Pipeline); err != nil {
+		return c, c.PushCriticalErrorf("invalid controller configuration: %w", err)
+	}
+
 	// Create the target.
 	c.target = reconciler.NewTarget(mgr, c.op, config.Target)
 	targetGVK, err := c.target.GetGVK()
@@ -94,12 +100,16 @@ func NewDeclarative(mgr manager.Manager, operator string, config opv1a1.Controll
 
 		// Choose reconciler based on source type.
 		var rec reconcile.TypedReconciler[reconciler.Request]
+		var newQueue func(string, workqueue.TypedRateLimiter[reconciler.Request]) workqueue.TypedRateLimitingInterface[reconciler.Request]
 		if s.Type() == opv1a1.Periodic {
 			// State-of-the-world reconciler for periodic sources.
 			rec = NewStateOfTheWorldReconciler(mgr, c)
 		} else {
-			// Incremental reconciler for watcher and oneshot sources.
-			rec = NewIncrementalReconciler(mgr, c)
+			// Incremental reconciler for watcher and oneshot sources. The reconciler owns the
+			// queue of the controller to coalesce the queued requests into batches.
+			incRec := NewIncrementalReconciler(mgr, c)
+			newQueue = incRec.NewQueue
+			rec = incRec
 			// Only incremental sources flow through the pipeline.
 			baseviews = append(baseviews, gvk)
 		}
@@ -112,6 +122,7 @@ func NewDeclarative(mgr manager.Manager, operator string, config opv1a1.Controll
 		ctrl, err := controller.NewTyped(ctrlName, mgr, controller.TypedOptions[reconciler.Request]{
 			SkipNameValidation: &on,
 			Reconciler:         rec,
+			NewQueue:           newQueue,
 		})
 		if err != nil {
 			return c, c.PushCriticalErrorf("failed to create runtime controller for resource %s: %w",
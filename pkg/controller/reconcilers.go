@@ -6,6 +6,7 @@ import (
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -15,74 +16,164 @@ import (
 	"github.com/l7mp/dcontroller/pkg/util"
 )
 
+// DefaultMaxBatchSize is the default limit on the number of requests the incremental reconciler
+// coalesces into a single batch.
+const DefaultMaxBatchSize = 64
+
 // IncrementalReconciler is a reconciler for incremental updates from watcher and oneshot sources.
-// It processes requests directly through the pipeline.
+// It processes requests directly through the pipeline. If the reconciler owns the workqueue of the
+// controller, see NewQueue, the requests waiting in the queue are coalesced into a batch and the
+// pipeline is evaluated once per batch.
 type IncrementalReconciler struct {
-	manager    manager.Manager
-	controller *DeclarativeController
-	log        logr.Logger
+	manager      manager.Manager
+	controller   *DeclarativeController
+	queue        workqueue.TypedRateLimitingInterface[reconciler.Request]
+	maxBatchSize int
+	log          logr.Logger
 }
 
 // NewIncrementalReconciler creates a new incremental reconciler.
 func NewIncrementalReconciler(mgr manager.Manager, c *DeclarativeController) *IncrementalReconciler {
 	return &IncrementalReconciler{
-		manager:    mgr,
-		controller: c,
-		log:        mgr.GetLogger().WithName("incremental-reconciler").WithValues("name", c.name),
+		manager:      mgr,
+		controller:   c,
+		maxBatchSize: DefaultMaxBatchSize,
+		log:          mgr.GetLogger().WithName("incremental-reconciler").WithValues("name", c.name),
 	}
 }
 
+// NewQueue creates the workqueue for the controller of the reconciler. Install it as the NewQueue
+// option of the controller to let the reconciler coalesce the queued requests into batches. The
+// controller must run a single worker, otherwise workers would compete for the queued requests.
+func (r *IncrementalReconciler) NewQueue(name string, rateLimiter workqueue.TypedRateLimiter[reconciler.Request]) workqueue.TypedRateLimitingInterface[reconciler.Request] {
+	r.queue = workqueue.NewTypedRateLimitingQueueWithConfig(rateLimiter,
+		workqueue.TypedRateLimitingQueueConfig[reconciler.Request]{Name: name})
+	return r.queue
+}
+
 // Reconcile implements the reconciler for incremental updates.
 // It processes the request directly (pipeline has internal locking for thread safety).
 func (r *IncrementalReconciler) Reconcile(ctx context.Context, req reconciler.Request) (reconcile.Result, error) {
-	r.log.V(2).Info("processing request", "request", util.Stringify(req))
-
-	obj := req.Object
-	if obj == nil {
-		// Fallback: if Object is nil (shouldn't happen), create a minimal object for the key.
-		obj = &unstructured.Unstructured{}
-		obj.SetGroupVersionKind(req.GVK)
-		obj.SetNamespace(req.Namespace)
-		obj.SetName(req.Name)
+	// Coalesce the request with the requests waiting in the queue.
+	batch := append([]reconciler.Request{req}, r.dequeue()...)
+
+	err := r.reconcile(ctx, batch)
+
+	// The controller handles the first request, handle the rest.
+	for _, req := range batch[1:] {
+		if err != nil {
+			r.queue.AddRateLimited(req)
+		} else {
+			r.queue.Forget(req)
+		}
+		r.queue.Done(req)
+	}
+
+	return reconcile.Result{}, err
+}
+
+// dequeue removes the requests waiting in the queue, up to the max batch size.
+func (r *IncrementalReconciler) dequeue() []reconciler.Request {
+	if r.queue == nil {
+		return nil
+	}
+
+	reqs := []reconciler.Request{}
+	for len(reqs) < r.maxBatchSize-1 && r.queue.Len() > 0 {
+		req, shutdown := r.queue.Get()
+		if shutdown {
+			break
+		}
+		reqs = append(reqs, req)
 	}
 
-	switch req.EventType {
-	case object.Added, object.Updated, object.Replaced, object.Deleted:
-		// Do nothing: Object snapshot already captured in req.Object at event generation time
-	default:
-		r.log.Info("ignoring event", "event-type", req.EventType)
-		return reconcile.Result{}, nil
+	return reqs
+}
+
+// reconcile processes a batch of requests.
+func (r *IncrementalReconciler) reconcile(ctx context.Context, batch []reconciler.Request) error {
+	deltas := make([]object.Delta, 0, len(batch))
+	for _, req := range batch {
+		r.log.V(2).Info("processing request", "request", util.Stringify(req))
+
+		obj := req.Object
+		if obj == nil {
+			// Fallback: if Object is nil (shouldn't happen), create a minimal object for the key.
+			obj = &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(req.GVK)
+			obj.SetNamespace(req.Namespace)
+			obj.SetName(req.Name)
+		}
+
+		switch req.EventType {
+		case object.Added, object.Updated, object.Replaced, object.Deleted:
+			// Do nothing: Object snapshot already captured in req.Object at event generation time
+		default:
+			r.log.Info("ignoring event", "event-type", req.EventType)
+			continue
+		}
+
+		deltas = append(deltas, object.Delta{
+			Type:   req.EventType,
+			Object: obj,
+		})
 	}
 
-	delta := object.Delta{
-		Type:   req.EventType,
-		Object: obj,
+	if len(deltas) == 0 {
+		return nil
 	}
 
-	// Process the delta through the pipeline (pipeline has internal locking).
-	deltas, err := r.controller.pipeline.Evaluate(delta)
+	// Process the deltas through the pipeline (pipeline has internal locking).
+	var res []object.Delta
+	var err error
+	if len(deltas) == 1 {
+		res, err = r.controller.pipeline.Evaluate(deltas[0])
+	} else {
+		r.log.V(2).Info("coalesced requests into a batch", "batch-size", len(deltas))
+		res, err = r.controller.pipeline.EvaluateBatch(deltas)
+	}
 	if err != nil {
-		err = fmt.Errorf("error evaluating pipeline for object %s/%s: %w", req.GVK,
-			client.ObjectKeyFromObject(obj), err)
-		r.log.Error(r.controller.Push(err), "error", "request", req)
-		return reconcile.Result{}, err
+		if len(deltas) == 1 {
+			err = fmt.Errorf("error evaluating pipeline for object %s/%s: %w",
+				deltas[0].Object.GetObjectKind().GroupVersionKind(),
+				client.ObjectKeyFromObject(deltas[0].Object), err)
+		} else {
+			err = fmt.Errorf("error evaluating pipeline for a batch of %d objects: %w",
+				len(deltas), err)
+		}
+		r.log.Error(r.controller.Push(err), "error", "request", batch[0])
+		return err
 	}
 
 	// Apply the resultant deltas.
-	for _, d := range deltas {
+	for _, d := range res {
 		r.log.V(4).Info("writing delta to target", "target", r.controller.target.String(),
 			"delta-type", d.Type, "object", object.Dump(d.Object))
 
-		// Pass the original object from the request for optimistic concurrency control.
-		if err := r.controller.target.Write(ctx, d, req.Object); err != nil {
-			err = fmt.Errorf("cannot update target %s for delta %s: %w", req.GVK,
+		// Pass the original object from the requests for optimistic concurrency control.
+		if err := r.controller.target.Write(ctx, d, originalObject(batch, d.Object)); err != nil {
+			err = fmt.Errorf("cannot update target %s for delta %s: %w", r.controller.target.String(),
 				d.String(), err)
-			r.log.Error(r.controller.Push(err), "error", "request", req)
-			return reconcile.Result{}, err
+			r.log.Error(r.controller.Push(err), "error", "request", batch[0])
+			return err
 		}
 	}
 
-	return reconcile.Result{}, nil
+	return nil
+}
+
+// originalObject returns the object of the last request in the batch that refers to the same
+// object as the delta, or nil if there is no such request.
+func originalObject(batch []reconciler.Request, obj object.Object) object.Object {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	for i := len(batch) - 1; i >= 0; i-- {
+		req := batch[i]
+		if req.Object != nil && req.GVK == gvk && req.Namespace == obj.GetNamespace() &&
+			req.Name == obj.GetName() {
+			return req.Object
+		}
+	}
+	return nil
 }
 
 // StateOfTheWorldReconciler is a reconciler for periodic sources that triggers full reconciliation.
@@ -0,0 +1,450 @@
+package dbsp
+
+import (
+	"cmp"
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Aggregate is a named aggregation function computed over the values extracted from the documents
+// of a group.
+type Aggregate struct {
+	Name      string
+	Extractor Extractor
+	Func      AggregateFunc
+}
+
+// AggregateFunc is an aggregation function that can be maintained incrementally.
+type AggregateFunc interface {
+	// NewState returns an empty aggregation state.
+	NewState() AggregateState
+	fmt.Stringer
+}
+
+// AggregateState is the incremental state of an aggregation function over a single group.
+type AggregateState interface {
+	// Update adds a value to the state with the given multiplicity. Negative multiplicities
+	// remove the value.
+	Update(value any, mult int) error
+	// Result returns the current value of the aggregate, or nil if the state is empty.
+	Result() any
+}
+
+// count
+
+type countFunc struct{}
+
+// NewCount returns an aggregation function that counts the values.
+func NewCount() AggregateFunc { return countFunc{} }
+
+func (countFunc) NewState() AggregateState { return &countState{} }
+func (countFunc) String() string           { return "count" }
+
+type countState struct{ n int }
+
+func (s *countState) Update(_ any, mult int) error { s.n += mult; return nil }
+func (s *countState) Result() any                  { return int64(s.n) }
+
+// sum and avg
+
+type sumFunc struct{ avg bool }
+
+// NewSum returns an aggregation function that sums numeric values. The sum is an integer as long
+// as all the values are integers.
+func NewSum() AggregateFunc { return sumFunc{} }
+
+// NewAvg returns an aggregation function that computes the average of numeric values.
+func NewAvg() AggregateFunc { return sumFunc{avg: true} }
+
+func (f sumFunc) NewState() AggregateState { return &sumState{avg: f.avg} }
+func (f sumFunc) String() string {
+	if f.avg {
+		return "avg"
+	}
+	return "sum"
+}
+
+type sumState struct {
+	avg       bool
+	n, floats int
+	intSum    int64
+	floatSum  float64
+}
+
+func (s *sumState) Update(value any, mult int) error {
+	switch v := value.(type) {
+	case int64:
+		s.intSum += v * int64(mult)
+	case int:
+		s.intSum += int64(v) * int64(mult)
+	case float64:
+		s.floatSum += v * float64(mult)
+		s.floats += mult
+	default:
+		return fmt.Errorf("cannot aggregate non-numeric value %v of type %T", value, value)
+	}
+	s.n += mult
+	return nil
+}
+
+func (s *sumState) Result() any {
+	if s.n <= 0 {
+		return nil
+	}
+	if s.avg {
+		return (float64(s.intSum) + s.floatSum) / float64(s.n)
+	}
+	if s.floats <= 0 {
+		return s.intSum
+	}
+	return float64(s.intSum) + s.floatSum
+}
+
+// min and max
+
+type extremumFunc struct{ max bool }
+
+// NewMin returns an aggregation function that computes the minimum of numeric or string values.
+func NewMin() AggregateFunc { return extremumFunc{} }
+
+// NewMax returns an aggregation function that computes the maximum of numeric or string values.
+func NewMax() AggregateFunc { return extremumFunc{max: true} }
+
+func (f extremumFunc) NewState() AggregateState {
+	less := func(a, b orderedValue) bool { return a.compare(b) < 0 }
+	if f.max {
+		less = func(a, b orderedValue) bool { return a.compare(b) > 0 }
+	}
+	return &extremumState{
+		values: map[string]orderedValue{},
+		counts: map[string]int{},
+		heap:   &lazyHeap[orderedValue]{less: less},
+		queued: map[string]bool{},
+	}
+}
+
+func (f extremumFunc) String() string {
+	if f.max {
+		return "max"
+	}
+	return "min"
+}
+
+// extremumState keeps the distinct values in a heap. Removed values are dropped lazily when they
+// reach the top of the heap.
+type extremumState struct {
+	values map[string]orderedValue // values present
+	counts map[string]int
+	heap   *lazyHeap[orderedValue]
+	queued map[string]bool // values in the heap
+}
+
+func (s *extremumState) Update(value any, mult int) error {
+	v, err := newOrderedValue(value)
+	if err != nil {
+		return err
+	}
+
+	s.counts[v.key] += mult
+	if s.counts[v.key] <= 0 {
+		delete(s.values, v.key)
+		if s.counts[v.key] == 0 {
+			delete(s.counts, v.key)
+		}
+		return nil
+	}
+
+	s.values[v.key] = v
+	if !s.queued[v.key] {
+		heap.Push(s.heap, v)
+		s.queued[v.key] = true
+	}
+
+	// Rebuild the heap if it is dominated by removed entries.
+	if s.heap.Len() > 2*len(s.values)+16 {
+		s.heap.items = s.heap.items[:0]
+		s.queued = make(map[string]bool, len(s.values))
+		for key, v := range s.values {
+			s.heap.items = append(s.heap.items, v)
+			s.queued[key] = true
+		}
+		heap.Init(s.heap)
+	}
+
+	return nil
+}
+
+func (s *extremumState) Result() any {
+	for s.heap.Len() > 0 {
+		top := s.heap.items[0]
+		if _, ok := s.values[top.key]; ok {
+			return top.value
+		}
+		heap.Pop(s.heap)
+		delete(s.queued, top.key)
+	}
+	return nil
+}
+
+// first and last
+
+type orderFunc struct{ last bool }
+
+// NewFirst returns an aggregation function that returns the earliest added value still present.
+func NewFirst() AggregateFunc { return orderFunc{} }
+
+// NewLast returns an aggregation function that returns the latest added value still present.
+func NewLast() AggregateFunc { return orderFunc{last: true} }
+
+func (f orderFunc) NewState() AggregateState {
+	less := func(a, b uint64) bool { return a < b }
+	if f.last {
+		less = func(a, b uint64) bool { return a > b }
+	}
+	return &orderState{
+		seqs:   map[string][]uint64{},
+		live:   map[uint64]any{},
+		pushed: &lazyHeap[uint64]{less: less},
+	}
+}
+
+func (f orderFunc) String() string {
+	if f.last {
+		return "last"
+	}
+	return "first"
+}
+
+// orderState assigns a sequence number to each added value. Removing a value drops the copy that
+// was added last.
+type orderState struct {
+	next   uint64
+	seqs   map[string][]uint64 // value key -> sequence numbers of the copies present
+	live   map[uint64]any      // sequence number -> value
+	pushed *lazyHeap[uint64]
+}
+
+func (s *orderState) Update(value any, mult int) error {
+	key, err := computeJSONAny(value)
+	if err != nil {
+		return err
+	}
+
+	for ; mult > 0; mult-- {
+		s.next++
+		s.seqs[key] = append(s.seqs[key], s.next)
+		s.live[s.next] = value
+		heap.Push(s.pushed, s.next)
+	}
+	for ; mult < 0 && len(s.seqs[key]) > 0; mult++ {
+		seqs := s.seqs[key]
+		delete(s.live, seqs[len(seqs)-1])
+		s.seqs[key] = seqs[:len(seqs)-1]
+	}
+	if len(s.seqs[key]) == 0 {
+		delete(s.seqs, key)
+	}
+
+	// Rebuild the heap if it is dominated by removed entries.
+	if s.pushed.Len() > 2*len(s.live)+16 {
+		s.pushed.items = s.pushed.items[:0]
+		for seq := range s.live {
+			s.pushed.items = append(s.pushed.items, seq)
+		}
+		heap.Init(s.pushed)
+	}
+
+	return nil
+}
+
+func (s *orderState) Result() any {
+	for s.pushed.Len() > 0 {
+		if value, ok := s.live[s.pushed.items[0]]; ok {
+			return value
+		}
+		heap.Pop(s.pushed)
+	}
+	return nil
+}
+
+// topK
+
+type topKFunc struct{ k int }
+
+// NewTopK returns an aggregation function that returns the list of the k largest numeric or string
+// values in descending order.
+func NewTopK(k int) AggregateFunc { return topKFunc{k: k} }
+
+func (f topKFunc) NewState() AggregateState {
+	return &topKState{k: f.k, values: map[string]orderedValue{}, counts: map[string]int{}}
+}
+
+func (f topKFunc) String() string { return fmt.Sprintf("topK(%d)", f.k) }
+
+// topKState keeps the multiplicities of all the values in a map and the at most k largest distinct
+// values in a sorted list. All the values not in the list are not larger than the smallest value
+// in the list, so an update costs O(k). Removing values from the list may leave it with fewer than
+// k copies, in which case the next Result rescans the values in O(n log k) time to refill it.
+type topKState struct {
+	k      int
+	values map[string]orderedValue // values present
+	counts map[string]int
+	top    []orderedValue // the largest distinct values in descending order
+}
+
+func (s *topKState) Update(value any, mult int) error {
+	v, err := newOrderedValue(value)
+	if err != nil {
+		return err
+	}
+
+	_, present := s.values[v.key]
+	s.counts[v.key] += mult
+	if s.counts[v.key] <= 0 {
+		delete(s.values, v.key)
+		if s.counts[v.key] == 0 {
+			delete(s.counts, v.key)
+		}
+		if i := s.find(v); i >= 0 {
+			s.top = append(s.top[:i], s.top[i+1:]...)
+		}
+		return nil
+	}
+	if present {
+		return nil
+	}
+
+	// Add the new value to the list if it is not smaller than the values left out from it.
+	evicted := len(s.values) > len(s.top)
+	s.values[v.key] = v
+	if evicted && (len(s.top) == 0 || v.compare(s.top[len(s.top)-1]) < 0) {
+		return nil
+	}
+	i := sort.Search(len(s.top), func(i int) bool { return s.top[i].compare(v) <= 0 })
+	s.top = append(s.top, orderedValue{})
+	copy(s.top[i+1:], s.top[i:])
+	s.top[i] = v
+	if len(s.top) > s.k {
+		s.top = s.top[:s.k]
+	}
+
+	return nil
+}
+
+// find returns the index of a value in the list or -1 if the value is not in the list.
+func (s *topKState) find(v orderedValue) int {
+	for i := range s.top {
+		if s.top[i].key == v.key {
+			return i
+		}
+	}
+	return -1
+}
+
+// rescan refills the list with the k largest distinct values.
+func (s *topKState) rescan() {
+	h := &lazyHeap[orderedValue]{less: func(a, b orderedValue) bool { return a.compare(b) < 0 }}
+	for _, v := range s.values {
+		if h.Len() < s.k {
+			heap.Push(h, v)
+		} else if v.compare(h.items[0]) > 0 {
+			h.items[0] = v
+			heap.Fix(h, 0)
+		}
+	}
+	s.top = make([]orderedValue, h.Len())
+	for i := len(s.top) - 1; i >= 0; i-- {
+		s.top[i] = heap.Pop(h).(orderedValue)
+	}
+}
+
+func (s *topKState) Result() any {
+	if len(s.values) == 0 {
+		return nil
+	}
+
+	copies := 0
+	for _, v := range s.top {
+		copies += s.counts[v.key]
+	}
+	if copies < s.k && len(s.values) > len(s.top) {
+		s.rescan()
+	}
+
+	ret := []any{}
+	for _, v := range s.top {
+		for c := 0; c < s.counts[v.key] && len(ret) < s.k; c++ {
+			ret = append(ret, v.value)
+		}
+	}
+	return ret
+}
+
+// orderedValue is a numeric or string value that can be compared to other ordered values. Numbers
+// are ordered before strings.
+type orderedValue struct {
+	value any
+	key   string
+	num   float64
+	str   string
+	isStr bool
+}
+
+func newOrderedValue(value any) (orderedValue, error) {
+	v := orderedValue{value: value}
+	switch x := value.(type) {
+	case int64:
+		v.num = float64(x)
+	case int:
+		v.num = float64(x)
+	case float64:
+		if math.IsNaN(x) {
+			return v, fmt.Errorf("cannot order NaN")
+		}
+		v.num = x
+	case string:
+		v.str, v.isStr = x, true
+	default:
+		return v, fmt.Errorf("cannot order value %v of type %T", value, value)
+	}
+
+	key, err := computeJSONAny(value)
+	if err != nil {
+		return v, err
+	}
+	v.key = key
+
+	return v, nil
+}
+
+func (v orderedValue) compare(w orderedValue) int {
+	switch {
+	case v.isStr && w.isStr:
+		return cmp.Compare(v.str, w.str)
+	case !v.isStr && !w.isStr:
+		return cmp.Compare(v.num, w.num)
+	case w.isStr:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// lazyHeap is a binary heap that implements heap.Interface.
+type lazyHeap[T any] struct {
+	items []T
+	less  func(a, b T) bool
+}
+
+func (h *lazyHeap[T]) Len() int           { return len(h.items) }
+func (h *lazyHeap[T]) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+func (h *lazyHeap[T]) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *lazyHeap[T]) Push(x any)         { h.items = append(h.items, x.(T)) }
+func (h *lazyHeap[T]) Pop() any {
+	n := len(h.items)
+	x := h.items[n-1]
+	h.items = h.items[:n-1]
+	return x
+}
@@ -0,0 +1,173 @@
+package dbsp
+
+import (
+	"math/rand"
+	"sort"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Aggregate Functions", func() {
+	update := func(state AggregateState, value any, mult int) {
+		Expect(state.Update(value, mult)).To(Succeed())
+	}
+
+	It("should count values", func() {
+		state := NewCount().NewState()
+		Expect(state.Result()).To(Equal(int64(0)))
+		update(state, "a", 2)
+		update(state, "b", 1)
+		update(state, "a", -1)
+		Expect(state.Result()).To(Equal(int64(2)))
+	})
+
+	It("should sum values", func() {
+		state := NewSum().NewState()
+		Expect(state.Result()).To(BeNil())
+		update(state, int64(1), 2)
+		update(state, int64(5), 1)
+		Expect(state.Result()).To(Equal(int64(7)))
+		update(state, 1.5, 1)
+		Expect(state.Result()).To(Equal(8.5))
+		update(state, 1.5, -1)
+		Expect(state.Result()).To(Equal(int64(7)))
+		update(state, int64(1), -2)
+		update(state, int64(5), -1)
+		Expect(state.Result()).To(BeNil())
+
+		Expect(state.Update("a", 1)).NotTo(Succeed())
+	})
+
+	It("should average values", func() {
+		state := NewAvg().NewState()
+		update(state, int64(1), 1)
+		update(state, int64(2), 1)
+		update(state, 3.0, 1)
+		Expect(state.Result()).To(Equal(2.0))
+		update(state, int64(1), -1)
+		Expect(state.Result()).To(Equal(2.5))
+	})
+
+	It("should compute the minimum and the maximum", func() {
+		min, max := NewMin().NewState(), NewMax().NewState()
+		for _, state := range []AggregateState{min, max} {
+			Expect(state.Result()).To(BeNil())
+			update(state, int64(3), 1)
+			update(state, 1.5, 2)
+			update(state, int64(7), 1)
+		}
+		Expect(min.Result()).To(Equal(1.5))
+		Expect(max.Result()).To(Equal(int64(7)))
+
+		for _, state := range []AggregateState{min, max} {
+			update(state, 1.5, -1)
+			update(state, int64(7), -1)
+		}
+		Expect(min.Result()).To(Equal(1.5))
+		Expect(max.Result()).To(Equal(int64(3)))
+
+		for _, state := range []AggregateState{min, max} {
+			update(state, 1.5, -1)
+		}
+		Expect(min.Result()).To(Equal(int64(3)))
+
+		// removed values may come back
+		update(max, int64(7), 1)
+		Expect(max.Result()).To(Equal(int64(7)))
+
+		strs := NewMax().NewState()
+		update(strs, "a", 1)
+		update(strs, "c", 1)
+		update(strs, "b", 1)
+		Expect(strs.Result()).To(Equal("c"))
+
+		Expect(min.Update(map[string]any{"a": 1}, 1)).NotTo(Succeed())
+	})
+
+	It("should return the first and the last value", func() {
+		first, last := NewFirst().NewState(), NewLast().NewState()
+		for _, state := range []AggregateState{first, last} {
+			Expect(state.Result()).To(BeNil())
+			update(state, "a", 1)
+			update(state, "b", 1)
+			update(state, "c", 1)
+		}
+		Expect(first.Result()).To(Equal("a"))
+		Expect(last.Result()).To(Equal("c"))
+
+		for _, state := range []AggregateState{first, last} {
+			update(state, "a", -1)
+			update(state, "c", -1)
+		}
+		Expect(first.Result()).To(Equal("b"))
+		Expect(last.Result()).To(Equal("b"))
+
+		update(first, "a", 1)
+		Expect(first.Result()).To(Equal("b"))
+	})
+
+	It("should return the top k values", func() {
+		state := NewTopK(3).NewState()
+		Expect(state.Result()).To(BeNil())
+		update(state, int64(1), 1)
+		update(state, int64(5), 2)
+		update(state, int64(3), 1)
+		update(state, int64(4), 1)
+		Expect(state.Result()).To(Equal([]any{int64(5), int64(5), int64(4)}))
+		update(state, int64(5), -2)
+		Expect(state.Result()).To(Equal([]any{int64(4), int64(3), int64(1)}))
+		update(state, int64(3), -1)
+		update(state, int64(4), -1)
+		Expect(state.Result()).To(Equal([]any{int64(1)}))
+	})
+
+	It("should agree with sorting all the values under random updates", func() {
+		rnd := rand.New(rand.NewSource(1))
+		state := NewTopK(3).NewState()
+		counts := map[int64]int{}
+		for i := 0; i < 2000; i++ {
+			v := int64(rnd.Intn(20))
+			mult := 1
+			if counts[v] > 0 && rnd.Intn(2) == 0 {
+				mult = -1
+			}
+			counts[v] += mult
+			update(state, v, mult)
+
+			all := []int64{}
+			for v, c := range counts {
+				for ; c > 0; c-- {
+					all = append(all, v)
+				}
+			}
+			sort.Slice(all, func(i, j int) bool { return all[i] > all[j] })
+			if len(all) == 0 {
+				Expect(state.Result()).To(BeNil())
+				continue
+			}
+			expected := []any{}
+			for j := 0; j < len(all) && j < 3; j++ {
+				expected = append(expected, all[j])
+			}
+			Expect(state.Result()).To(Equal(expected), "step %d", i)
+			Expect(len(state.(*topKState).top)).To(BeNumerically("<=", 3))
+		}
+	})
+
+	It("should keep the state bounded under churn", func() {
+		min, first := NewMin().NewState(), NewFirst().NewState()
+		for i := 0; i < 1000; i++ {
+			update(min, int64(i), 1)
+			update(first, int64(i), 1)
+			if i > 0 {
+				update(min, int64(i-1), -1)
+				update(first, int64(i-1), -1)
+			}
+		}
+		Expect(min.Result()).To(Equal(int64(999)))
+		Expect(first.Result()).To(Equal(int64(999)))
+		Expect(min.(*extremumState).heap.Len()).To(BeNumerically("<", 100))
+		Expect(first.(*orderState).pushed.Len()).To(BeNumerically("<", 100))
+	})
+})
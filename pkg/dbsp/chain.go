@@ -2,19 +2,27 @@ package dbsp
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 )
 
 // ChainGraph represents your specialized graph structure directly.
-// Always: [Inputs] -> [Optional N-ary Join] -> [Chain of Linear Ops] -> [Output]
+// Always: [Inputs] -> [Optional Input Chains] -> [Optional N-ary Join] -> [Chain of Linear Ops] -> [Output]
+//
+// The input chains hold the linear ops applied to an input before the join. They are normally
+// created by the rewrite engine, e.g., by pushing selections below the join.
 type ChainGraph struct {
-	inputs   []string              // Input node IDs
-	inputIdx map[string]string     // Map internal id to external input name
-	joinNode string                // Optional N-ary join node ID (empty if no join)
-	chain    []string              // Ordered chain of operation node IDs
-	output   string                // Output node ID
-	nodes    map[string]*GraphNode // All nodes by ID
-	nextID   int                   // For generating unique node IDs
+	inputs      []string              // Input node IDs
+	inputIdx    map[string]string     // Map internal id to external input name
+	inputChains map[string][]string   // Ops applied to an input before the join, by input node ID
+	joinNode    string                // Optional N-ary join node ID (empty if no join)
+	chain       []string              // Ordered chain of operation node IDs
+	output      string                // Output node ID
+	nodes       map[string]*GraphNode // All nodes by ID
+	nextID      int                   // For generating unique node IDs
+	rewrites    []string              // Rewrite rules applied by the rewrite engine
+	cost        float64               // Estimated cost of the plan, zero if unknown
 }
 
 // GraphNode is a node on the op chain.
@@ -28,11 +36,12 @@ type GraphNode struct {
 // NewChainGraph returns a new op chain.
 func NewChainGraph() *ChainGraph {
 	return &ChainGraph{
-		inputs:   make([]string, 0),
-		inputIdx: map[string]string{},
-		chain:    make([]string, 0),
-		nodes:    make(map[string]*GraphNode),
-		nextID:   0,
+		inputs:      make([]string, 0),
+		inputIdx:    map[string]string{},
+		inputChains: map[string][]string{},
+		chain:       make([]string, 0),
+		nodes:       make(map[string]*GraphNode),
+		nextID:      0,
 	}
 }
 
@@ -68,7 +77,45 @@ func (g *ChainGraph) GetInput(name string) *InputOp {
 	return nil
 }
 
-// SetJoin sets the join node (if multiple inputs)
+// AddToInput adds an operation to the chain of ops applied to an input before the join. Returns
+// an empty string if there is no such input.
+func (g *ChainGraph) AddToInput(name string, op Operator) string {
+	inputID := g.getInputID(name)
+	if inputID == "" {
+		return ""
+	}
+
+	id := fmt.Sprintf("op_%d", g.nextID)
+	g.nextID++
+
+	g.nodes[id] = &GraphNode{ID: id, Op: op}
+	g.inputChains[inputID] = append(g.inputChains[inputID], id)
+	return id
+}
+
+// GetInputChain returns the operations applied to an input before the join.
+func (g *ChainGraph) GetInputChain(name string) []Operator {
+	ids := g.inputChains[g.getInputID(name)]
+	ret := make([]Operator, len(ids))
+	for i, id := range ids {
+		ret[i] = g.nodes[id].Op
+	}
+	return ret
+}
+
+// getInputID returns the internal id of a named input, or an empty string if there is no such
+// input.
+func (g *ChainGraph) getInputID(name string) string {
+	for _, inputID := range g.inputs {
+		if g.inputIdx[inputID] == name {
+			return inputID
+		}
+	}
+	return ""
+}
+
+// SetJoin sets the join node (if multiple inputs). The join node may be any op that combines the
+// inputs, e.g., an N-ary AddOp merges the inputs without joining them.
 func (g *ChainGraph) SetJoin(op Operator) string {
 	id := fmt.Sprintf("join_%d", g.nextID)
 	g.nextID++
@@ -148,13 +195,12 @@ func (g *ChainGraph) String() string {
 	// Build the flow description
 	if len(g.inputs) == 1 {
 		// Single input: Input -> [Join] -> Chain -> Output
-		inputName := g.nodes[g.inputs[0]].Op.id()
-		parts = append(parts, inputName)
+		parts = append(parts, g.inputString(g.inputs[0]))
 	} else {
 		// Multiple inputs: (Input1, Input2, ...) -> Join -> Chain -> Output
 		inputNames := make([]string, len(g.inputs))
 		for i, inputID := range g.inputs {
-			inputNames[i] = g.nodes[inputID].Op.id()
+			inputNames[i] = g.inputString(inputID)
 		}
 		parts = append(parts, fmt.Sprintf("(%s)", strings.Join(inputNames, ", ")))
 	}
@@ -172,6 +218,40 @@ func (g *ChainGraph) String() string {
 	return strings.Join(parts, " → ")
 }
 
+// inputString returns the description of an input along with its input chain.
+func (g *ChainGraph) inputString(inputID string) string {
+	parts := []string{g.nodes[inputID].Op.id()}
+	for _, id := range g.inputChains[inputID] {
+		parts = append(parts, g.nodes[id].Op.id())
+	}
+	return strings.Join(parts, " → ")
+}
+
+// clone returns a copy of the graph that shares the ops but not the structure of the graph, so
+// that rewriting the copy leaves the original intact.
+func (g *ChainGraph) clone() *ChainGraph {
+	ret := &ChainGraph{
+		inputs:      slices.Clone(g.inputs),
+		inputIdx:    maps.Clone(g.inputIdx),
+		inputChains: make(map[string][]string, len(g.inputChains)),
+		joinNode:    g.joinNode,
+		chain:       slices.Clone(g.chain),
+		output:      g.output,
+		nodes:       make(map[string]*GraphNode, len(g.nodes)),
+		nextID:      g.nextID,
+		rewrites:    slices.Clone(g.rewrites),
+		cost:        g.cost,
+	}
+	for id, ids := range g.inputChains {
+		ret.inputChains[id] = slices.Clone(ids)
+	}
+	for id, node := range g.nodes {
+		n := *node
+		ret.nodes[id] = &n
+	}
+	return ret
+}
+
 // getNode returns a node from its id. Names are internal to the graph, only inputs have proper
 // names.
 func (g *ChainGraph) getNode(id string) *GraphNode {
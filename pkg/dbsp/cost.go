@@ -0,0 +1,119 @@
+package dbsp
+
+// Statistics maps the names of the inputs of a chain graph to the estimated number of documents
+// in the integral of the input, i.e., in the current state of the input.
+type Statistics map[string]int
+
+// CardinalityEstimator is an optional interface for incremental ops that keep the integrals of
+// their inputs as state, which provide the cardinality estimates for the cost model.
+type CardinalityEstimator interface {
+	// InputCardinalities returns the number of documents in the integral of each input.
+	InputCardinalities() []int
+}
+
+// CostModel estimates the cost of evaluating a chain graph. The rewrite engine uses the cost model
+// to choose between alternative plans.
+type CostModel interface {
+	// Cost returns the estimated cost of evaluating the graph given the cardinality estimates of
+	// the inputs. Inputs missing from the statistics have an unknown cardinality.
+	Cost(graph *ChainGraph, stats Statistics) float64
+}
+
+// Defaults for the DefaultCostModel.
+const (
+	DefaultCardinality = 100
+	DefaultSelectivity = 0.5
+	DefaultHashCost    = 2.0
+)
+
+// DefaultCostModel estimates the cost of a graph as the number of documents processed by its ops
+// when evaluating the graph on the integrated inputs. Inputs without statistics are assumed to hold
+// Cardinality documents and selections are assumed to pass a Selectivity fraction of their input.
+// Nested-loop joins evaluate the join predicate on the cartesian product of their inputs, while
+// hash joins process each input document at a cost of HashCost and evaluate the predicate only on
+// the matching documents. Joins are assumed to produce as many documents as their largest input.
+type DefaultCostModel struct {
+	Cardinality int
+	Selectivity float64
+	HashCost    float64
+}
+
+// NewDefaultCostModel returns a new default cost model.
+func NewDefaultCostModel() *DefaultCostModel {
+	return &DefaultCostModel{
+		Cardinality: DefaultCardinality,
+		Selectivity: DefaultSelectivity,
+		HashCost:    DefaultHashCost,
+	}
+}
+
+// Cost returns the estimated cost of a graph.
+func (m *DefaultCostModel) Cost(graph *ChainGraph, stats Statistics) float64 {
+	cost := 0.0
+
+	cards := make([]float64, len(graph.inputs))
+	for i, inputID := range graph.inputs {
+		card := float64(m.Cardinality)
+		if n, ok := stats[graph.inputIdx[inputID]]; ok {
+			card = float64(n)
+		}
+		for _, nodeID := range graph.inputChains[inputID] {
+			var c float64
+			c, card = m.opCost(graph.nodes[nodeID].Op, card)
+			cost += c
+		}
+		cards[i] = card
+	}
+
+	var card float64
+	switch {
+	case graph.joinNode != "":
+		var c float64
+		c, card = m.joinCost(graph.nodes[graph.joinNode].Op, cards)
+		cost += c
+	case len(cards) > 0:
+		card = cards[0]
+	}
+
+	for _, nodeID := range graph.chain {
+		var c float64
+		c, card = m.opCost(graph.nodes[nodeID].Op, card)
+		cost += c
+	}
+
+	return cost
+}
+
+// joinCost returns the cost and the output cardinality of a join.
+func (m *DefaultCostModel) joinCost(op Operator, cards []float64) (float64, float64) {
+	sum, product, largest := 0.0, 1.0, 0.0
+	for _, card := range cards {
+		sum += card
+		product *= card
+		largest = max(largest, card)
+	}
+
+	switch op.(type) {
+	case *AddOp:
+		return sum, sum
+	case *HashJoinOp, *IncrementalHashJoinOp:
+		return m.HashCost*sum + largest, largest
+	default:
+		return product, largest
+	}
+}
+
+// opCost returns the cost and the output cardinality of a chain op.
+func (m *DefaultCostModel) opCost(op Operator, card float64) (float64, float64) {
+	switch o := op.(type) {
+	case *SelectionOp, *ProjectThenSelectOp:
+		return card, card * m.Selectivity
+	case *SelectThenProjectionsOp:
+		if o.selEval != nil {
+			return card, card * m.Selectivity
+		}
+		return card, card
+	default:
+		return card, card
+	}
+}
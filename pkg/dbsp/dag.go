@@ -0,0 +1,289 @@
+package dbsp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// DAG is a directed acyclic graph of named nodes, each holding a ChainGraph. The inputs of a node
+// are fed from the external inputs of the DAG or from the outputs of other nodes. This allows
+// pre-processing inputs before a join, or joining several inputs in multiple steps, e.g.:
+//
+//	pods:     Pod → σ
+//	podnodes: (pods, Node) → ⋈ → π
+//	output:   (podnodes, Service) → ⋈ → π
+//
+// Nodes can only refer to inputs and nodes that have already been added, so the nodes are always
+// kept in topological order.
+type DAG struct {
+	inputs []string            // External input names
+	nodes  map[string]*DAGNode // All nodes by name
+	order  []string            // Node names in topological order
+	output string              // Output node name
+}
+
+// DAGNode is a named node of a DAG.
+type DAGNode struct {
+	Name  string
+	Graph *ChainGraph
+	// Inputs maps each input of the graph to the name of the external input or the node that
+	// feeds it.
+	Inputs map[string]string
+}
+
+// NewDAG returns a new empty DAG.
+func NewDAG() *DAG {
+	return &DAG{
+		inputs: []string{},
+		nodes:  map[string]*DAGNode{},
+		order:  []string{},
+	}
+}
+
+// NewDAGFromChain returns a DAG with a single node that wraps a ChainGraph.
+func NewDAGFromChain(name string, graph *ChainGraph) (*DAG, error) {
+	d := NewDAG()
+	for _, inputID := range graph.inputs {
+		if err := d.AddInput(graph.inputIdx[inputID]); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.AddNode(name, graph, nil); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// AddInput adds an external input.
+func (d *DAG) AddInput(name string) error {
+	if d.hasName(name) {
+		return fmt.Errorf("duplicate name %q", name)
+	}
+	d.inputs = append(d.inputs, name)
+	return nil
+}
+
+// AddNode adds a named node that wraps a ChainGraph. The inputs map the input names of the graph to
+// the names of existing external inputs or nodes. Inputs missing from the map are fed from the
+// external input or node of the same name. The last node added becomes the output of the DAG.
+func (d *DAG) AddNode(name string, graph *ChainGraph, inputs map[string]string) error {
+	if d.hasName(name) {
+		return fmt.Errorf("duplicate name %q", name)
+	}
+
+	node := &DAGNode{Name: name, Graph: graph, Inputs: make(map[string]string, len(graph.inputs))}
+	for _, inputID := range graph.inputs {
+		input := graph.inputIdx[inputID]
+		from, ok := inputs[input]
+		if !ok {
+			from = input
+		}
+		if !d.hasName(from) {
+			return fmt.Errorf("node %q: unknown input %q", name, from)
+		}
+		node.Inputs[input] = from
+	}
+
+	d.nodes[name] = node
+	d.order = append(d.order, name)
+	d.output = name
+
+	return nil
+}
+
+// SetOutput sets the output node of the DAG.
+func (d *DAG) SetOutput(name string) error {
+	if _, ok := d.nodes[name]; !ok {
+		return fmt.Errorf("unknown node %q", name)
+	}
+	d.output = name
+	return nil
+}
+
+// GetNode returns a named node of the DAG.
+func (d *DAG) GetNode(name string) *DAGNode {
+	return d.nodes[name]
+}
+
+// GetOutput returns the output node of the DAG.
+func (d *DAG) GetOutput() *DAGNode {
+	return d.nodes[d.output]
+}
+
+// Inputs returns the names of the external inputs.
+func (d *DAG) Inputs() []string {
+	return slices.Clone(d.inputs)
+}
+
+// Nodes returns the names of the nodes in topological order.
+func (d *DAG) Nodes() []string {
+	return slices.Clone(d.order)
+}
+
+// Validate checks the DAG structure and the graphs of all nodes.
+func (d *DAG) Validate() error {
+	if len(d.order) == 0 {
+		return fmt.Errorf("no nodes")
+	}
+	if _, ok := d.nodes[d.output]; !ok {
+		return fmt.Errorf("no output node")
+	}
+	for _, name := range d.order {
+		if err := d.nodes[name].Graph.Validate(); err != nil {
+			return fmt.Errorf("node %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// String representation for debugging. A DAG with a single node is printed as the node's graph.
+func (d *DAG) String() string {
+	if len(d.order) == 1 {
+		return d.nodes[d.order[0]].Graph.String()
+	}
+
+	parts := make([]string, len(d.order))
+	for i, name := range d.order {
+		node := d.nodes[name]
+		ins := make([]string, 0, len(node.Inputs))
+		for _, inputID := range node.Graph.inputs {
+			input := node.Graph.inputIdx[inputID]
+			if from := node.Inputs[input]; from != input {
+				ins = append(ins, fmt.Sprintf("%s=%s", input, from))
+			}
+		}
+		prefix := name
+		if len(ins) > 0 {
+			prefix = fmt.Sprintf("%s(%s)", name, strings.Join(ins, ","))
+		}
+		parts[i] = fmt.Sprintf("%s: %s", prefix, node.Graph.String())
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func (d *DAG) hasName(name string) bool {
+	_, ok := d.nodes[name]
+	return ok || slices.Contains(d.inputs, name)
+}
+
+// ToSnapshotDAG converts an incremental DAG to a snapshot DAG by converting the graph of each node.
+func ToSnapshotDAG(incrementalDAG *DAG) (*DAG, error) {
+	return convertDAG(incrementalDAG, ToSnapshotGraph)
+}
+
+// ToIncrementalDAG converts a snapshot DAG to an incremental DAG by converting the graph of each
+// node.
+func ToIncrementalDAG(snapshotDAG *DAG) (*DAG, error) {
+	return convertDAG(snapshotDAG, ToIncrementalGraph)
+}
+
+func convertDAG(sourceDAG *DAG, graphConverter func(*ChainGraph) (*ChainGraph, error)) (*DAG, error) {
+	targetDAG := NewDAG()
+	targetDAG.inputs = slices.Clone(sourceDAG.inputs)
+
+	for _, name := range sourceDAG.order {
+		node := sourceDAG.nodes[name]
+		graph, err := graphConverter(node.Graph)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert node %q: %w", name, err)
+		}
+		if err := targetDAG.AddNode(name, graph, node.Inputs); err != nil {
+			return nil, err
+		}
+	}
+	targetDAG.output = sourceDAG.output
+
+	return targetDAG, nil
+}
+
+// DAGExecutor executes incremental or snapshot queries on a DAG. Each node is evaluated by an
+// Executor in topological order, feeding the result of a node into the nodes that consume it.
+type DAGExecutor struct {
+	dag       *DAG
+	executors map[string]*Executor
+	log       logr.Logger
+}
+
+// NewDAGExecutor returns a new executor for evaluating incremental or snapshot DAGs.
+func NewDAGExecutor(dag *DAG, log logr.Logger) (*DAGExecutor, error) {
+	if err := dag.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid DAG: %w", err)
+	}
+
+	e := &DAGExecutor{dag: dag, executors: make(map[string]*Executor, len(dag.order)), log: log}
+	for _, name := range dag.order {
+		executor, err := NewExecutor(dag.nodes[name].Graph, log.WithValues("node", name))
+		if err != nil {
+			return nil, fmt.Errorf("node %q: %w", name, err)
+		}
+		e.executors[name] = executor
+	}
+
+	return e, nil
+}
+
+// Process processes the external inputs through the DAG and returns the result of the output
+// node. For incremental executors, inputs are deltas. For snapshot executors, inputs are complete
+// states.
+func (e *DAGExecutor) Process(inputs DeltaZSet) (*DocumentZSet, error) {
+	results := make(DeltaZSet, len(e.dag.inputs)+len(e.dag.order))
+	for _, input := range e.dag.inputs {
+		zset, ok := inputs[input]
+		if !ok {
+			return nil, fmt.Errorf("missing input for node %s", input)
+		}
+		results[input] = zset
+	}
+
+	for _, name := range e.dag.order {
+		node := e.dag.nodes[name]
+		nodeInputs := make(DeltaZSet, len(node.Inputs))
+		for input, from := range node.Inputs {
+			nodeInputs[input] = results[from]
+		}
+
+		res, err := e.executors[name].Process(nodeInputs)
+		if err != nil {
+			return nil, fmt.Errorf("node %s failed: %w", name, err)
+		}
+		results[name] = res
+
+		if name == e.dag.output {
+			break
+		}
+	}
+
+	return results[e.dag.output], nil
+}
+
+// Reset resets all stateful nodes (for incremental computation).
+func (e *DAGExecutor) Reset() {
+	for _, executor := range e.executors {
+		executor.Reset()
+	}
+}
+
+// GetExecutionPlan returns a human-readable execution plan.
+func (e *DAGExecutor) GetExecutionPlan() string {
+	if len(e.dag.order) == 1 {
+		return e.executors[e.dag.order[0]].GetExecutionPlan()
+	}
+
+	plan := ""
+	for _, name := range e.dag.order {
+		node := e.dag.nodes[name]
+		ins := make([]string, 0, len(node.Inputs))
+		for _, inputID := range node.Graph.inputs {
+			input := node.Graph.inputIdx[inputID]
+			ins = append(ins, fmt.Sprintf("%s<-%s", input, node.Inputs[input]))
+		}
+		plan += fmt.Sprintf("Node %s [%s]:\n", name, strings.Join(ins, ", "))
+		plan += e.executors[name].GetExecutionPlan()
+	}
+
+	return plan
+}
@@ -0,0 +1,187 @@
+package dbsp
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// MergeJoinEvaluator joins documents with the same value in a field and merges the joined
+// documents into a single document.
+type MergeJoinEvaluator struct {
+	field  string
+	inputs []string
+}
+
+func NewMergeJoin(field string, inputs ...string) *MergeJoinEvaluator {
+	return &MergeJoinEvaluator{field: field, inputs: inputs}
+}
+
+func (e *MergeJoinEvaluator) Evaluate(doc Document) ([]Document, error) {
+	result := Document{}
+	for _, input := range e.inputs {
+		sub, ok := doc[input].(Document)
+		if !ok {
+			return nil, fmt.Errorf("missing input %q", input)
+		}
+		if sub[e.field] == nil || sub[e.field] != doc[e.inputs[0]].(Document)[e.field] {
+			return []Document{}, nil
+		}
+		for k, v := range sub {
+			result[k] = v
+		}
+	}
+	return []Document{result}, nil
+}
+
+func (e *MergeJoinEvaluator) String() string {
+	return fmt.Sprintf("MergeJoin(%s)", e.field)
+}
+
+var _ = Describe("DAG", func() {
+	var dag *DAG
+
+	// adults:       users → σ(age ≥ 18)
+	// userprojects: (adults, projects) → ⋈ → π
+	// output:       (userprojects, tasks) → ⋈
+	BeforeEach(func() {
+		dag = NewDAG()
+		for _, input := range []string{"users", "projects", "tasks"} {
+			Expect(dag.AddInput(input)).To(Succeed())
+		}
+
+		adults := NewChainGraph()
+		adults.AddInput(NewInput("users"))
+		adults.AddToChain(NewSelection(NewRangeFilter("age", 18, 200)))
+		Expect(dag.AddNode("adults", adults, nil)).To(Succeed())
+
+		userProjects := NewChainGraph()
+		userProjects.AddInput(NewInput("users"))
+		userProjects.AddInput(NewInput("projects"))
+		userProjects.SetJoin(NewJoin(NewMergeJoin("id", "users", "projects"), []string{"users", "projects"}))
+		userProjects.AddToChain(NewProjection(NewFieldProjection("id", "name", "title")))
+		Expect(dag.AddNode("userprojects", userProjects, map[string]string{"users": "adults"})).To(Succeed())
+
+		output := NewChainGraph()
+		output.AddInput(NewInput("userprojects"))
+		output.AddInput(NewInput("tasks"))
+		output.SetJoin(NewJoin(NewMergeJoin("id", "userprojects", "tasks"), []string{"userprojects", "tasks"}))
+		Expect(dag.AddNode("output", output, nil)).To(Succeed())
+	})
+
+	It("should reject invalid nodes", func() {
+		graph := NewChainGraph()
+		graph.AddInput(NewInput("unknown"))
+		Expect(dag.AddNode("invalid", graph, nil)).NotTo(Succeed())
+
+		graph = NewChainGraph()
+		graph.AddInput(NewInput("users"))
+		Expect(dag.AddNode("adults", graph, nil)).NotTo(Succeed())
+		Expect(dag.AddNode("users", graph, nil)).NotTo(Succeed())
+		Expect(dag.AddInput("adults")).NotTo(Succeed())
+	})
+
+	It("should list the nodes in topological order", func() {
+		Expect(dag.Inputs()).To(Equal([]string{"users", "projects", "tasks"}))
+		Expect(dag.Nodes()).To(Equal([]string{"adults", "userprojects", "output"}))
+		Expect(dag.GetOutput().Name).To(Equal("output"))
+		Expect(dag.GetNode("userprojects").Inputs).To(Equal(map[string]string{
+			"users": "adults", "projects": "projects"}))
+		Expect(dag.String()).To(ContainSubstring("userprojects(users=adults): "))
+	})
+
+	It("should evaluate a snapshot DAG", func() {
+		executor, err := NewDAGExecutor(dag, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		users := NewDocumentZSet()
+		Expect(users.AddDocumentMutate(Document{"id": int64(1), "name": "alice", "age": int64(30)}, 1)).To(Succeed())
+		Expect(users.AddDocumentMutate(Document{"id": int64(2), "name": "bob", "age": int64(12)}, 1)).To(Succeed())
+		projects := NewDocumentZSet()
+		Expect(projects.AddDocumentMutate(Document{"id": int64(1), "title": "p1"}, 1)).To(Succeed())
+		Expect(projects.AddDocumentMutate(Document{"id": int64(2), "title": "p2"}, 1)).To(Succeed())
+		tasks := NewDocumentZSet()
+		Expect(tasks.AddDocumentMutate(Document{"id": int64(1), "task": "t1"}, 1)).To(Succeed())
+		Expect(tasks.AddDocumentMutate(Document{"id": int64(2), "task": "t2"}, 1)).To(Succeed())
+
+		res, err := executor.Process(DeltaZSet{"users": users, "projects": projects, "tasks": tasks})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Size()).To(Equal(1))
+		docs, err := res.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(docs[0].Document).To(Equal(Document{"id": int64(1), "name": "alice", "title": "p1",
+			"task": "t1"}))
+
+		_, err = executor.Process(DeltaZSet{"users": users, "projects": projects})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should evaluate an incremental DAG consistently with the snapshot DAG", func() {
+		snapshotExecutor, err := NewDAGExecutor(dag, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		incrementalDAG, err := ToIncrementalDAG(dag)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(NewLinearChainRewriteEngine().OptimizeDAG(incrementalDAG)).To(Succeed())
+		executor, err := NewDAGExecutor(incrementalDAG, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		// the original DAG must not change
+		_, ok := dag.GetNode("output").Graph.nodes[dag.GetNode("output").Graph.joinNode].Op.(*JoinOp)
+		Expect(ok).To(BeTrue())
+
+		states := DeltaZSet{"users": NewDocumentZSet(), "projects": NewDocumentZSet(), "tasks": NewDocumentZSet()}
+		output := NewDocumentZSet()
+		for step := 0; step < 12; step++ {
+			id := int64(step % 3)
+			deltas := DeltaZSet{"users": NewDocumentZSet(), "projects": NewDocumentZSet(), "tasks": NewDocumentZSet()}
+			var input string
+			var doc Document
+			switch step % 3 {
+			case 0:
+				input, doc = "users", Document{"id": id, "name": fmt.Sprintf("user-%d", id), "age": int64(10 + step*2)}
+			case 1:
+				input, doc = "projects", Document{"id": id, "title": fmt.Sprintf("project-%d", id)}
+			default:
+				input, doc = "tasks", Document{"id": id, "task": fmt.Sprintf("task-%d", step)}
+			}
+			mult := 1
+			if step >= 9 {
+				// remove the docs added in the first steps
+				mult = -1
+				docs, err := states[input].List()
+				Expect(err).NotTo(HaveOccurred())
+				doc = docs[0].Document
+			}
+			Expect(deltas[input].AddDocumentMutate(doc, mult)).To(Succeed())
+			states[input], err = states[input].Add(deltas[input])
+			Expect(err).NotTo(HaveOccurred())
+
+			delta, err := executor.Process(deltas)
+			Expect(err).NotTo(HaveOccurred())
+			output, err = output.Add(delta)
+			Expect(err).NotTo(HaveOccurred())
+
+			expected, err := snapshotExecutor.Process(states)
+			Expect(err).NotTo(HaveOccurred())
+			diff, err := output.Subtract(expected)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff.IsZero()).To(BeTrue(), fmt.Sprintf("step %d: %s", step, diff.String()))
+		}
+	})
+
+	It("should convert DAGs to snapshot DAGs", func() {
+		incrementalDAG, err := ToIncrementalDAG(dag)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(NewLinearChainRewriteEngine().OptimizeDAG(incrementalDAG)).To(Succeed())
+
+		snapshotDAG, err := ToSnapshotDAG(incrementalDAG)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshotDAG.Nodes()).To(Equal(dag.Nodes()))
+		Expect(snapshotDAG.GetNode("userprojects").Inputs).To(Equal(dag.GetNode("userprojects").Inputs))
+		graph := snapshotDAG.GetNode("output").Graph
+		_, ok := graph.nodes[graph.joinNode].Op.(*JoinOp)
+		Expect(ok).To(BeTrue())
+	})
+})
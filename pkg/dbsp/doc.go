@@ -11,12 +11,15 @@
 //   - Operator: Interface for DBSP operators (linear, bilinear, nonlinear ops).
 //   - Executor: Orchestrates operator chains and manages incremental computation.
 //   - ChainGraph: Represents computation graphs with optimization support.
-//   - RewriteEngine: Performs operator fusion and optimization.
+//   - DAG: Composes named ChainGraphs into a directed acyclic graph, evaluated by a DAGExecutor.
+//   - RewriteEngine: Performs operator fusion and optimization, choosing between alternative plans
+//     using a CostModel fed by the cardinality estimates of the integrated inputs.
 //
 // Operator types:
 //   - Linear: Selection, projection, etc (preserve zero, commute with addition).
 //   - Bilinear: Join operations (multiplication-like semantics).
 //   - Nonlinear: Complex transformations that don't preserve linearity.
+//   - Recursive: Fixpoint of a recursive query, iterated through a z^(-1) delay in a bounded loop.
 //
 // The DBSP implementation supports incremental view maintenance (IVM) with O(|changes|) complexity
 // and operator fusion for performance optimization.
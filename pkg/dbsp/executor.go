@@ -36,7 +36,8 @@ func (e *Executor) Process(inputs DeltaZSet) (*DocumentZSet, error) {
 	}
 
 	inputStrs := []string{}
-	for _, inputID := range e.graph.inputs {
+	joinInputs := make([]*DocumentZSet, len(e.graph.inputs))
+	for i, inputID := range e.graph.inputs {
 		inputName, ok := e.graph.inputIdx[inputID]
 		if !ok {
 			return nil, fmt.Errorf("internal error: no input for ID %s", inputID)
@@ -46,6 +47,13 @@ func (e *Executor) Process(inputs DeltaZSet) (*DocumentZSet, error) {
 			return nil, fmt.Errorf("missing input for node %s", inputName)
 		}
 		inputStrs = append(inputStrs, fmt.Sprintf("%s->%s", inputName, zset.String()))
+
+		// Apply the ops pushed below the join
+		zset, err := e.processInput(inputID, zset)
+		if err != nil {
+			return nil, err
+		}
+		joinInputs[i] = zset
 	}
 
 	// Step 2: Execute join (if exists) on inputs
@@ -57,11 +65,6 @@ func (e *Executor) Process(inputs DeltaZSet) (*DocumentZSet, error) {
 
 		// Execute N-ary join
 		joinNode := e.graph.nodes[e.graph.joinNode]
-		joinInputs := make([]*DocumentZSet, len(e.graph.inputs))
-		for i, inputID := range e.graph.inputs {
-			joinInputs[i] = inputs[e.graph.inputIdx[inputID]]
-		}
-
 		currentResult, err = joinNode.Op.Process(joinInputs...)
 		if err != nil {
 			return nil, fmt.Errorf("join operation %s failed: %w", joinNode.Op.id(), err)
@@ -70,7 +73,7 @@ func (e *Executor) Process(inputs DeltaZSet) (*DocumentZSet, error) {
 		e.log.V(4).Info("join ready", "result", currentResult.String())
 	} else {
 		// Single input, no join needed
-		currentResult = inputs[e.graph.inputIdx[e.graph.inputs[0]]]
+		currentResult = joinInputs[0]
 	}
 
 	e.log.V(2).Info("processing aggregations", "input-delta", strings.Join(inputStrs, ","))
@@ -94,8 +97,48 @@ func (e *Executor) Process(inputs DeltaZSet) (*DocumentZSet, error) {
 	return currentResult, nil
 }
 
+// processInput applies the input chain of an input.
+func (e *Executor) processInput(inputID string, zset *DocumentZSet) (*DocumentZSet, error) {
+	for _, nodeID := range e.graph.inputChains[inputID] {
+		node := e.graph.nodes[nodeID]
+		var err error
+		zset, err = node.Op.Process(zset)
+		if err != nil {
+			return nil, fmt.Errorf("operation %s on input %s failed: %w", node.Op.id(),
+				e.graph.inputIdx[inputID], err)
+		}
+	}
+	return zset, nil
+}
+
+// Statistics returns the cardinality estimates of the inputs of the join, taken from the
+// integrated inputs kept by incremental joins. Returns an empty map if the join keeps no state.
+func (e *Executor) Statistics() Statistics {
+	stats := Statistics{}
+	if e.graph.joinNode == "" {
+		return stats
+	}
+
+	estimator, ok := e.graph.nodes[e.graph.joinNode].Op.(CardinalityEstimator)
+	if !ok {
+		return stats
+	}
+	for i, n := range estimator.InputCardinalities() {
+		stats[e.graph.inputIdx[e.graph.inputs[i]]] = n
+	}
+
+	return stats
+}
+
 // Reset resets all stateful nodes (for incremental computation).
 func (e *Executor) Reset() {
+	// Reset the ops applied to the inputs
+	for _, ids := range e.graph.inputChains {
+		for _, nodeID := range ids {
+			e.resetOperator(e.graph.nodes[nodeID].Op)
+		}
+	}
+
 	// Reset join node if it's stateful
 	if e.graph.joinNode != "" {
 		e.resetOperator(e.graph.nodes[e.graph.joinNode].Op)
@@ -115,10 +158,26 @@ func (e *Executor) resetOperator(op Operator) {
 		o.Reset()
 	case *DelayOp:
 		o.Reset()
+	case *IncrementalJoinOp:
+		o.Reset()
 	case *IncrementalBinaryJoinOp:
 		o.Reset()
+	case *IncrementalHashJoinOp:
+		o.Reset()
+	case *IncrementalLeftJoinOp:
+		o.Reset()
+	case *IncrementalAntiJoinOp:
+		o.Reset()
 	case *IncrementalGatherOp:
 		o.Reset()
+	case *IncrementalAggregateOp:
+		o.Reset()
+	case *IncrementalWindowedAggregateOp:
+		o.Reset()
+	case *IncrementalDistinctOp:
+		o.Reset()
+	case *IncrementalFixpointOp:
+		o.Reset()
 		// Add other stateful operators as needed
 	}
 }
@@ -127,13 +186,21 @@ func (e *Executor) resetOperator(op Operator) {
 func (e *Executor) GetExecutionPlan() string {
 	plan := "Execution Plan:\n"
 
-	// Show inputs
+	// Show inputs, along with the ops pushed below the join
 	plan += fmt.Sprintf("1. Inputs (%d): ", len(e.graph.inputs))
 	for i, inputID := range e.graph.inputs {
 		if i > 0 {
 			plan += ", "
 		}
 		plan += e.graph.nodes[inputID].Op.id()
+		if ids := e.graph.inputChains[inputID]; len(ids) > 0 {
+			ops := make([]string, len(ids))
+			for j, id := range ids {
+				op := e.graph.nodes[id].Op
+				ops[j] = fmt.Sprintf("%s (%s)", op.id(), getOpTypeString(op))
+			}
+			plan += fmt.Sprintf(" → %s", strings.Join(ops, " → "))
+		}
 	}
 	plan += "\n"
 
@@ -151,6 +218,22 @@ func (e *Executor) GetExecutionPlan() string {
 		plan += fmt.Sprintf("%d. %s (%s)\n", step+i, op.id(), getOpTypeString(op))
 	}
 
+	// Show the choices of the optimizer
+	if len(e.graph.rewrites) > 0 {
+		plan += fmt.Sprintf("Rewrites: %s\n", strings.Join(e.graph.rewrites, ", "))
+	}
+	if e.graph.cost > 0 {
+		plan += fmt.Sprintf("Estimated cost: %.0f\n", e.graph.cost)
+	}
+	if stats := e.Statistics(); len(stats) > 0 {
+		cards := make([]string, len(e.graph.inputs))
+		for i, inputID := range e.graph.inputs {
+			name := e.graph.inputIdx[inputID]
+			cards[i] = fmt.Sprintf("%s=%d", name, stats[name])
+		}
+		plan += fmt.Sprintf("Input cardinalities: %s\n", strings.Join(cards, ", "))
+	}
+
 	return plan
 }
 
@@ -180,12 +263,17 @@ func (e *Executor) GetNodeResult(nodeID string, deltaInputs map[string]*Document
 	// For debugging: execute up to this specific node
 	// This is inefficient but useful for testing
 
+	joinInputs := make([]*DocumentZSet, len(e.graph.inputs))
+	for i, inputID := range e.graph.inputs {
+		zset, err := e.processInput(inputID, deltaInputs[inputID])
+		if err != nil {
+			return nil, err
+		}
+		joinInputs[i] = zset
+	}
+
 	if nodeID == e.graph.joinNode {
 		// Execute join
-		joinInputs := make([]*DocumentZSet, len(e.graph.inputs))
-		for i, inputID := range e.graph.inputs {
-			joinInputs[i] = deltaInputs[inputID]
-		}
 		return node.Op.Process(joinInputs...)
 	}
 
@@ -209,16 +297,12 @@ func (e *Executor) GetNodeResult(nodeID string, deltaInputs map[string]*Document
 	// Start with join result or first input
 	if e.graph.joinNode != "" {
 		joinNode := e.graph.nodes[e.graph.joinNode]
-		joinInputs := make([]*DocumentZSet, len(e.graph.inputs))
-		for i, inputID := range e.graph.inputs {
-			joinInputs[i] = deltaInputs[inputID]
-		}
 		currentResult, err = joinNode.Op.Process(joinInputs...)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		currentResult = deltaInputs[e.graph.inputs[0]]
+		currentResult = joinInputs[0]
 	}
 
 	// Execute chain up to target position
@@ -571,6 +571,70 @@ var _ = Describe("LinearChainExecutor", func() {
 		})
 	})
 
+	Context("Union Pipeline", func() {
+		BeforeEach(func() {
+			// Build: (Input1, Input2, Input3) -> + -> Project -> Output
+			graph.AddInput(NewInput("users"))
+			graph.AddInput(NewInput("admins"))
+			graph.AddInput(NewInput("guests"))
+			graph.SetJoin(NewNaryAdd(3))
+			graph.AddToChain(NewProjection(NewFieldProjection("name")))
+
+			var err error
+			snapshotGraph, err = ToSnapshotGraph(graph)
+			Expect(err).NotTo(HaveOccurred())
+
+			snapshotExecutor, err = NewSnapshotExecutor(snapshotGraph, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = rewriter.Optimize(graph)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor, err = NewExecutor(graph, logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should sum the inputs", func() {
+			alice, err := newDocumentFromPairs("name", "Alice", "age", int64(25))
+			Expect(err).NotTo(HaveOccurred())
+			bob, err := newDocumentFromPairs("name", "Bob", "age", int64(30))
+			Expect(err).NotTo(HaveOccurred())
+			alice2, err := newDocumentFromPairs("name", "Alice", "role", "admin")
+			Expect(err).NotTo(HaveOccurred())
+
+			users, err := SingletonZSet(alice)
+			Expect(err).NotTo(HaveOccurred())
+			admins := NewDocumentZSet()
+			Expect(admins.AddDocumentMutate(bob, 1)).To(Succeed())
+			Expect(admins.AddDocumentMutate(alice2, 1)).To(Succeed())
+
+			inputs := DeltaZSet{"users": users, "admins": admins, "guests": NewDocumentZSet()}
+			result, err := executor.Process(inputs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Size()).To(Equal(3))
+			Expect(result.UniqueCount()).To(Equal(2))
+
+			snapshotResult, err := snapshotExecutor.Process(inputs)
+			Expect(err).NotTo(HaveOccurred())
+			diff, err := snapshotResult.Subtract(result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff.IsZero()).To(BeTrue())
+
+			// removing a document from one input removes one copy
+			users, err = SingletonZSet(alice)
+			Expect(err).NotTo(HaveOccurred())
+			users, err = NewDocumentZSet().Subtract(users)
+			Expect(err).NotTo(HaveOccurred())
+			result, err = executor.Process(DeltaZSet{"users": users, "admins": NewDocumentZSet(),
+				"guests": NewDocumentZSet()})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.TotalSize()).To(Equal(1))
+			mult, err := result.GetMultiplicity(Document{"name": "Alice"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mult).To(Equal(-1))
+		})
+	})
+
 	Context("Gather Operations", func() {
 		BeforeEach(func() {
 			// Build: Input -> Gather -> Output
@@ -6,15 +6,19 @@ import "fmt"
 type opConverter func(Operator) (Operator, error)
 
 // ToSnapshotGraph converts an incremental ChainGraph to a snapshot ChainGraph.
-// This replaces incremental operators (IncrementalJoinOp, IncrementalGatherOp) with their
-// snapshot equivalents (JoinOp, GatherOp). Linear operators remain unchanged.
+// This replaces incremental operators (IncrementalJoinOp, IncrementalHashJoinOp, IncrementalLeftJoinOp,
+// IncrementalAntiJoinOp, IncrementalGatherOp, IncrementalAggregateOp, IncrementalDistinctOp) with their
+// snapshot equivalents (JoinOp, HashJoinOp, LeftJoinOp, AntiJoinOp, GatherOp, AggregateOp,
+// DistinctOp). Linear operators remain unchanged.
 func ToSnapshotGraph(incrementalGraph *ChainGraph) (*ChainGraph, error) {
 	return convertGraph(incrementalGraph, "incremental", convertToSnapshotJoin, convertToSnapshotOperator)
 }
 
 // ToIncrementalGraph converts a snapshot ChainGraph to an incremental ChainGraph.
-// This replaces snapshot operators (JoinOp, GatherOp) with their incremental equivalents
-// (IncrementalJoinOp, IncrementalGatherOp). Linear operators remain unchanged.
+// This replaces snapshot operators (JoinOp, HashJoinOp, LeftJoinOp, AntiJoinOp, GatherOp, AggregateOp,
+// DistinctOp) with their incremental equivalents (IncrementalJoinOp, IncrementalHashJoinOp,
+// IncrementalLeftJoinOp, IncrementalAntiJoinOp, IncrementalGatherOp, IncrementalAggregateOp,
+// IncrementalDistinctOp). Linear operators remain unchanged.
 func ToIncrementalGraph(snapshotGraph *ChainGraph) (*ChainGraph, error) {
 	return convertGraph(snapshotGraph, "snapshot", convertToIncrementalJoin, convertToIncrementalOperator)
 }
@@ -32,6 +36,16 @@ func convertGraph(sourceGraph *ChainGraph, sourceType string, joinConverter, opC
 		inputNode := sourceGraph.nodes[inputID]
 		inputOp := inputNode.Op.(*InputOp)
 		targetGraph.AddInput(inputOp)
+
+		// Convert the ops applied to the input before the join.
+		for _, nodeID := range sourceGraph.inputChains[inputID] {
+			node := sourceGraph.nodes[nodeID]
+			convertedOp, err := opConverter(node.Op)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert operator %s: %w", node.Op.id(), err)
+			}
+			targetGraph.AddToInput(inputOp.Name(), convertedOp)
+		}
 	}
 
 	// Convert join node if present.
@@ -66,9 +80,21 @@ func convertToSnapshotJoin(op Operator) (Operator, error) {
 	case *IncrementalBinaryJoinOp:
 		// Convert IncrementalBinaryJoinOp -> BinaryJoinOp.
 		return NewBinaryJoin(o.eval, o.inputs), nil
-	case *JoinOp, *BinaryJoinOp:
+	case *IncrementalHashJoinOp:
+		// Convert IncrementalHashJoinOp -> HashJoinOp.
+		return NewHashJoin(o.eval, o.inputs, o.keys), nil
+	case *IncrementalLeftJoinOp:
+		// Convert IncrementalLeftJoinOp -> LeftJoinOp.
+		return NewLeftJoin(o.eval, o.inputs), nil
+	case *IncrementalAntiJoinOp:
+		// Convert IncrementalAntiJoinOp -> AntiJoinOp.
+		return NewAntiJoin(o.eval, o.inputs), nil
+	case *JoinOp, *BinaryJoinOp, *HashJoinOp, *LeftJoinOp, *AntiJoinOp:
 		// Already snapshot, return as-is.
 		return op, nil
+	case *AddOp:
+		// Addition is the same for snapshot and incremental.
+		return op, nil
 	default:
 		return nil, fmt.Errorf("unsupported join operator type: %T", op)
 	}
@@ -83,9 +109,22 @@ func convertToIncrementalJoin(op Operator) (Operator, error) {
 	case *BinaryJoinOp:
 		// Convert BinaryJoinOp -> IncrementalBinaryJoinOp.
 		return NewIncrementalBinaryJoin(o.eval, o.inputs), nil
-	case *IncrementalJoinOp, *IncrementalBinaryJoinOp:
+	case *HashJoinOp:
+		// Convert HashJoinOp -> IncrementalHashJoinOp.
+		return NewIncrementalHashJoin(o.eval, o.inputs, o.keys), nil
+	case *LeftJoinOp:
+		// Convert LeftJoinOp -> IncrementalLeftJoinOp.
+		return NewIncrementalLeftJoin(o.eval, o.inputs), nil
+	case *AntiJoinOp:
+		// Convert AntiJoinOp -> IncrementalAntiJoinOp.
+		return NewIncrementalAntiJoin(o.eval, o.inputs), nil
+	case *IncrementalJoinOp, *IncrementalBinaryJoinOp, *IncrementalHashJoinOp, *IncrementalLeftJoinOp,
+		*IncrementalAntiJoinOp:
 		// Already incremental, return as-is.
 		return op, nil
+	case *AddOp:
+		// Addition is the same for snapshot and incremental.
+		return op, nil
 	default:
 		return nil, fmt.Errorf("unsupported join operator type: %T", op)
 	}
@@ -97,9 +136,21 @@ func convertToSnapshotOperator(op Operator) (Operator, error) {
 	case *IncrementalGatherOp:
 		// Convert IncrementalGatherOp -> GatherOp.
 		return NewGather(o.keyExtractor, o.valueExtractor, o.aggregator), nil
+	case *IncrementalAggregateOp:
+		// Convert IncrementalAggregateOp -> AggregateOp.
+		return NewAggregate(o.keyExtractor, o.aggregates, o.aggregator), nil
+	case *IncrementalWindowedAggregateOp:
+		// Convert IncrementalWindowedAggregateOp -> WindowedAggregateOp.
+		return NewWindowedAggregate(o.keyExtractor, o.tsExtractor, o.window, o.aggregates, o.aggregator, o.clock), nil
+	case *IncrementalDistinctOp:
+		// Convert IncrementalDistinctOp -> DistinctOp.
+		return NewDistinct(), nil
+	case *IncrementalFixpointOp:
+		// Convert IncrementalFixpointOp -> FixpointOp.
+		return newFixpointOp(o.body), nil
 
 	// Linear operators are the same for snapshot and incremental.
-	case *ProjectionOp, *SelectionOp, *DistinctOp:
+	case *ProjectionOp, *SelectionOp, *UnwindOp:
 		return op, nil
 
 	// Fused operators are also the same.
@@ -115,7 +166,7 @@ func convertToSnapshotOperator(op Operator) (Operator, error) {
 		return nil, fmt.Errorf("DelayOp cannot be converted to snapshot (only valid in incremental mode)")
 
 	// Already snapshot operator.
-	case *GatherOp:
+	case *GatherOp, *AggregateOp, *WindowedAggregateOp, *DistinctOp, *FixpointOp:
 		return op, nil
 
 	default:
@@ -129,9 +180,22 @@ func convertToIncrementalOperator(op Operator) (Operator, error) {
 	case *GatherOp:
 		// Convert GatherOp -> IncrementalGatherOp.
 		return NewIncrementalGather(o.keyExtractor, o.valueExtractor, o.aggregator), nil
+	case *AggregateOp:
+		// Convert AggregateOp -> IncrementalAggregateOp.
+		return NewIncrementalAggregate(o.keyExtractor, o.aggregates, o.aggregator), nil
+	case *WindowedAggregateOp:
+		// Convert WindowedAggregateOp -> IncrementalWindowedAggregateOp.
+		return NewIncrementalWindowedAggregate(o.keyExtractor, o.tsExtractor, o.window, o.aggregates,
+			o.aggregator, o.clock), nil
+	case *DistinctOp:
+		// Convert DistinctOp -> IncrementalDistinctOp.
+		return NewIncrementalDistinct(), nil
+	case *FixpointOp:
+		// Convert FixpointOp -> IncrementalFixpointOp.
+		return newIncrementalFixpointOp(o.body), nil
 
 	// Linear operators are the same for snapshot and incremental.
-	case *ProjectionOp, *SelectionOp, *DistinctOp:
+	case *ProjectionOp, *SelectionOp, *UnwindOp:
 		return op, nil
 
 	// Fused operators are also the same.
@@ -139,7 +203,8 @@ func convertToIncrementalOperator(op Operator) (Operator, error) {
 		return op, nil
 
 	// Already incremental operator.
-	case *IncrementalGatherOp:
+	case *IncrementalGatherOp, *IncrementalAggregateOp, *IncrementalWindowedAggregateOp, *IncrementalDistinctOp,
+		*IncrementalFixpointOp:
 		return op, nil
 
 	// Structural operators remain unchanged (only used in incremental mode).
@@ -43,6 +43,41 @@ var _ = Describe("GraphConverter", func() {
 			Expect(joinOp).To(BeAssignableToTypeOf(&BinaryJoinOp{}))
 		})
 
+		It("should convert incremental outer joins to snapshot outer joins", func() {
+			inputs := []string{"users", "projects"}
+			for _, join := range []Operator{NewIncrementalLeftJoin(NewFlexibleJoin("id", inputs), inputs),
+				NewIncrementalAntiJoin(NewFlexibleJoin("id", inputs), inputs)} {
+				incrementalGraph := NewChainGraph()
+				incrementalGraph.AddInput(NewInput(inputs[0]))
+				incrementalGraph.AddInput(NewInput(inputs[1]))
+				incrementalGraph.SetJoin(join)
+
+				snapshotGraph, err := ToSnapshotGraph(incrementalGraph)
+				Expect(err).NotTo(HaveOccurred())
+
+				joinOp := snapshotGraph.nodes[snapshotGraph.joinNode].Op
+				if _, ok := join.(*IncrementalLeftJoinOp); ok {
+					Expect(joinOp).To(BeAssignableToTypeOf(&LeftJoinOp{}))
+				} else {
+					Expect(joinOp).To(BeAssignableToTypeOf(&AntiJoinOp{}))
+				}
+			}
+		})
+
+		It("should convert incremental hash joins to snapshot hash joins", func() {
+			inputs := []string{"users", "projects"}
+			eval := NewKeyedJoin("id", inputs)
+			keys, _ := eval.JoinKeys(inputs)
+			incrementalGraph := NewChainGraph()
+			incrementalGraph.AddInput(NewInput(inputs[0]))
+			incrementalGraph.AddInput(NewInput(inputs[1]))
+			incrementalGraph.SetJoin(NewIncrementalHashJoin(eval, inputs, keys))
+
+			snapshotGraph, err := ToSnapshotGraph(incrementalGraph)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshotGraph.nodes[snapshotGraph.joinNode].Op).To(BeAssignableToTypeOf(&HashJoinOp{}))
+		})
+
 		It("should convert incremental gather to snapshot gather", func() {
 			// Build incremental graph with IncrementalGatherOp.
 			incrementalGraph := NewChainGraph()
@@ -174,6 +209,41 @@ var _ = Describe("GraphConverter", func() {
 			Expect(joinOp).To(BeAssignableToTypeOf(&IncrementalBinaryJoinOp{}))
 		})
 
+		It("should convert snapshot outer joins to incremental outer joins", func() {
+			inputs := []string{"users", "projects"}
+			for _, join := range []Operator{NewLeftJoin(NewFlexibleJoin("id", inputs), inputs),
+				NewAntiJoin(NewFlexibleJoin("id", inputs), inputs)} {
+				snapshotGraph := NewChainGraph()
+				snapshotGraph.AddInput(NewInput(inputs[0]))
+				snapshotGraph.AddInput(NewInput(inputs[1]))
+				snapshotGraph.SetJoin(join)
+
+				incrementalGraph, err := ToIncrementalGraph(snapshotGraph)
+				Expect(err).NotTo(HaveOccurred())
+
+				joinOp := incrementalGraph.nodes[incrementalGraph.joinNode].Op
+				if _, ok := join.(*LeftJoinOp); ok {
+					Expect(joinOp).To(BeAssignableToTypeOf(&IncrementalLeftJoinOp{}))
+				} else {
+					Expect(joinOp).To(BeAssignableToTypeOf(&IncrementalAntiJoinOp{}))
+				}
+			}
+		})
+
+		It("should convert snapshot hash joins to incremental hash joins", func() {
+			inputs := []string{"users", "projects"}
+			eval := NewKeyedJoin("id", inputs)
+			keys, _ := eval.JoinKeys(inputs)
+			snapshotGraph := NewChainGraph()
+			snapshotGraph.AddInput(NewInput(inputs[0]))
+			snapshotGraph.AddInput(NewInput(inputs[1]))
+			snapshotGraph.SetJoin(NewHashJoin(eval, inputs, keys))
+
+			incrementalGraph, err := ToIncrementalGraph(snapshotGraph)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(incrementalGraph.nodes[incrementalGraph.joinNode].Op).To(BeAssignableToTypeOf(&IncrementalHashJoinOp{}))
+		})
+
 		It("should convert snapshot gather to incremental gather", func() {
 			// Build snapshot graph with GatherOp.
 			snapshotGraph := NewChainGraph()
@@ -203,11 +273,11 @@ var _ = Describe("GraphConverter", func() {
 			incrementalGraph, err := ToIncrementalGraph(snapshotGraph)
 			Expect(err).NotTo(HaveOccurred())
 
-			// Verify all operators preserved.
+			// Verify linear operators preserved, distinct is non-linear and gets incrementalized.
 			Expect(incrementalGraph.chain).To(HaveLen(3))
 			Expect(incrementalGraph.nodes[incrementalGraph.chain[0]].Op).To(BeAssignableToTypeOf(&ProjectionOp{}))
 			Expect(incrementalGraph.nodes[incrementalGraph.chain[1]].Op).To(BeAssignableToTypeOf(&SelectionOp{}))
-			Expect(incrementalGraph.nodes[incrementalGraph.chain[2]].Op).To(BeAssignableToTypeOf(&DistinctOp{}))
+			Expect(incrementalGraph.nodes[incrementalGraph.chain[2]].Op).To(BeAssignableToTypeOf(&IncrementalDistinctOp{}))
 		})
 
 		It("should handle already-incremental operators gracefully", func() {
@@ -16,6 +16,29 @@ type Extractor interface {
 	fmt.Stringer
 }
 
+// JoinKeyEvaluator is an optional interface for join evaluators whose predicate implies the
+// equality of a key computed separately from each input document (an equi-join). Join ops with
+// such an evaluator can be rewritten into hash joins.
+type JoinKeyEvaluator interface {
+	Evaluator
+	// JoinKeys returns a key extractor per input (in the order of the inputs) so that a
+	// combination of documents may satisfy the join predicate only if all keys are equal. An
+	// extractor returns nil for documents that cannot join. Returns false if the predicate is
+	// not an equi-join.
+	JoinKeys(inputs []string) ([]Extractor, bool)
+}
+
+// PredicateEvaluator is an optional interface for evaluators that filter documents, i.e., that
+// return either the input document unchanged or nothing. The documents produced by a join with a
+// predicate evaluator hold the document of each input under the name of the input, so selections
+// with a predicate evaluator that refers to a single input can be pushed below the join.
+type PredicateEvaluator interface {
+	Evaluator
+	// InputRefs returns the names of the inputs the predicate refers to, i.e., the top-level
+	// fields of the document the result depends on. Returns false if this cannot be determined.
+	InputRefs(inputs []string) ([]string, bool)
+}
+
 // Transform documents by setting/replacing fields.
 type Transformer interface {
 	Transform(Document, any) (Document, error)
@@ -82,11 +105,27 @@ func IncrementalizeOp(in Operator) (Operator, bool) {
 	case *GatherOp:
 		// Gather, although theoretically linear,  needs a specialized incremental version for efficiency
 		return NewIncrementalGather(op.keyExtractor, op.valueExtractor, op.aggregator), true
+	case *AggregateOp:
+		return NewIncrementalAggregate(op.keyExtractor, op.aggregates, op.aggregator), true
+	case *WindowedAggregateOp:
+		return NewIncrementalWindowedAggregate(op.keyExtractor, op.tsExtractor, op.window, op.aggregates,
+			op.aggregator, op.clock), true
 	case *BinaryJoinOp:
 		// Join ops need incrementalization
 		return NewIncrementalBinaryJoin(op.eval, op.inputs), true
 	case *JoinOp:
 		return NewIncrementalJoin(op.eval, op.inputs), true
+	case *HashJoinOp:
+		return NewIncrementalHashJoin(op.eval, op.inputs, op.keys), true
+	case *LeftJoinOp:
+		return NewIncrementalLeftJoin(op.eval, op.inputs), true
+	case *AntiJoinOp:
+		return NewIncrementalAntiJoin(op.eval, op.inputs), true
+	case *DistinctOp:
+		// Distinct is non-linear: the incremental version must keep the integrated input
+		return NewIncrementalDistinct(), true
+	case *FixpointOp:
+		return newIncrementalFixpointOp(op.body), true
 	default:
 		return op, false
 	}
@@ -99,6 +99,16 @@ func (op *IncrementalJoinOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, er
 	return result, nil
 }
 
+// InputCardinalities implements CardinalityEstimator.
+func (op *IncrementalJoinOp) InputCardinalities() []int {
+	return zsetSizes(op.prevStates)
+}
+
+// Reset method for testing.
+func (op *IncrementalJoinOp) Reset() {
+	op.prevStates = make([]*DocumentZSet, op.n)
+}
+
 func (op *IncrementalJoinOp) computeTerm(inputs []*DocumentZSet, mask int) (*DocumentZSet, error) {
 	// Create the input combination for this term
 	termInputs := make([]*DocumentZSet, op.n)
@@ -313,8 +323,260 @@ func (op *IncrementalBinaryJoinOp) OpType() OperatorType              { return O
 func (op *IncrementalBinaryJoinOp) IsTimeInvariant() bool             { return true }
 func (op *IncrementalBinaryJoinOp) HasZeroPreservationProperty() bool { return true }
 
+// InputCardinalities implements CardinalityEstimator.
+func (op *IncrementalBinaryJoinOp) InputCardinalities() []int {
+	return zsetSizes([]*DocumentZSet{op.prevLeft, op.prevRight})
+}
+
 // Reset method for testing.
 func (op *IncrementalBinaryJoinOp) Reset() {
 	op.prevLeft = NewDocumentZSet()
 	op.prevRight = NewDocumentZSet()
 }
+
+// HashJoinOp is a snapshot N-ary equi-join. Documents are grouped by the join keys and the join
+// predicate is evaluated only on the combinations of documents with equal keys.
+type HashJoinOp struct {
+	BaseOp
+	eval   Evaluator
+	inputs []string
+	keys   []Extractor
+	n      int
+}
+
+// NewHashJoin returns a new snapshot hash join op. The keys are the per-input key extractors, see
+// JoinKeyEvaluator.
+func NewHashJoin(eval Evaluator, inputs []string, keys []Extractor) *HashJoinOp {
+	return &HashJoinOp{
+		BaseOp: NewBaseOp(fmt.Sprintf("snapshot_hash_⋈_%d", len(inputs)), len(inputs)),
+		eval:   eval,
+		inputs: inputs,
+		keys:   keys,
+		n:      len(inputs),
+	}
+}
+
+func (op *HashJoinOp) OpType() OperatorType              { return OpTypeBilinear }
+func (op *HashJoinOp) IsTimeInvariant() bool             { return true }
+func (op *HashJoinOp) HasZeroPreservationProperty() bool { return true }
+
+// Process evaluates the op.
+func (op *HashJoinOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := op.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	indexes := make([]joinIndex, op.n)
+	for i, input := range inputs {
+		index, err := buildJoinIndex(op.keys[i], input)
+		if err != nil {
+			return nil, err
+		}
+		indexes[i] = index
+	}
+
+	// Join the buckets that exist in all inputs
+	result := NewDocumentZSet()
+	buckets := make([]*DocumentZSet, op.n)
+	for key := range indexes[0] {
+		if !collectBuckets(buckets, key, indexes, indexes, 0) {
+			continue
+		}
+
+		term, err := cartesianJoin(op.eval, op.inputs, buckets, 0, make([]Document, op.n), make([]int, op.n))
+		if err != nil {
+			return nil, err
+		}
+
+		result, err = result.Add(term)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// IncrementalHashJoinOp implements an incremental N-ary equi-join. The op maintains a hash index
+// on the join keys for each integrated input, so that processing a delta costs time proportional
+// to the size of the delta and the matching buckets, instead of the size of the cartesian product
+// of the inputs.
+type IncrementalHashJoinOp struct {
+	BaseOp
+	eval   Evaluator
+	inputs []string
+	keys   []Extractor
+	n      int
+
+	// State: indexes of the integrated inputs
+	prevIndexes []joinIndex
+}
+
+// NewIncrementalHashJoin returns a new incremental hash join op. The keys are the per-input key
+// extractors, see JoinKeyEvaluator.
+func NewIncrementalHashJoin(eval Evaluator, inputs []string, keys []Extractor) *IncrementalHashJoinOp {
+	op := &IncrementalHashJoinOp{
+		BaseOp: NewBaseOp(fmt.Sprintf("hash_⋈_%d", len(inputs)), len(inputs)),
+		eval:   eval,
+		inputs: inputs,
+		keys:   keys,
+		n:      len(inputs),
+	}
+	op.Reset()
+	return op
+}
+
+func (op *IncrementalHashJoinOp) OpType() OperatorType              { return OpTypeBilinear }
+func (op *IncrementalHashJoinOp) IsTimeInvariant() bool             { return true }
+func (op *IncrementalHashJoinOp) HasZeroPreservationProperty() bool { return true }
+
+// Process evaluates the op.
+func (op *IncrementalHashJoinOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := op.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	deltaIndexes := make([]joinIndex, op.n)
+	for i, input := range inputs {
+		index, err := buildJoinIndex(op.keys[i], input)
+		if err != nil {
+			return nil, err
+		}
+		deltaIndexes[i] = index
+	}
+
+	// Only the keys that appear in at least one delta may produce output
+	affected := map[string]bool{}
+	for _, index := range deltaIndexes {
+		for key := range index {
+			affected[key] = true
+		}
+	}
+
+	// Generate all 2^n - 1 terms where at least one input is a delta, restricted to the buckets
+	// of the affected keys
+	result := NewDocumentZSet()
+	buckets := make([]*DocumentZSet, op.n)
+	for key := range affected {
+		for mask := 1; mask < (1 << op.n); mask++ {
+			if !collectBuckets(buckets, key, deltaIndexes, op.prevIndexes, mask) {
+				continue
+			}
+
+			term, err := cartesianJoin(op.eval, op.inputs, buckets, 0, make([]Document, op.n), make([]int, op.n))
+			if err != nil {
+				return nil, err
+			}
+
+			result, err = result.Add(term)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Update the indexes for the next iteration
+	for i, index := range deltaIndexes {
+		for key, delta := range index {
+			prev, ok := op.prevIndexes[i][key]
+			if !ok {
+				prev = NewDocumentZSet()
+			}
+			bucket, err := prev.Add(delta)
+			if err != nil {
+				return nil, err
+			}
+			if bucket.IsZero() {
+				delete(op.prevIndexes[i], key)
+			} else {
+				op.prevIndexes[i][key] = bucket
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// InputCardinalities implements CardinalityEstimator. Documents without a join key are not
+// indexed, and are not counted.
+func (op *IncrementalHashJoinOp) InputCardinalities() []int {
+	ret := make([]int, op.n)
+	for i, index := range op.prevIndexes {
+		for _, zset := range index {
+			ret[i] += zset.Size()
+		}
+	}
+	return ret
+}
+
+// Reset method for testing.
+func (op *IncrementalHashJoinOp) Reset() {
+	op.prevIndexes = make([]joinIndex, op.n)
+	for i := range op.prevIndexes {
+		op.prevIndexes[i] = joinIndex{}
+	}
+}
+
+// zsetSizes returns the number of documents in each zset, counting nil zsets as empty.
+func zsetSizes(zsets []*DocumentZSet) []int {
+	ret := make([]int, len(zsets))
+	for i, zset := range zsets {
+		if zset != nil {
+			ret[i] = zset.Size()
+		}
+	}
+	return ret
+}
+
+// joinIndex maps the serialized join keys to the documents with the given key.
+type joinIndex = map[string]*DocumentZSet
+
+// buildJoinIndex groups the documents of a zset by the join key. Documents without a key cannot
+// join and are omitted.
+func buildJoinIndex(keyExtractor Extractor, input *DocumentZSet) (joinIndex, error) {
+	index := joinIndex{}
+	for docKey, mult := range input.counts {
+		doc := input.docs[docKey]
+		key, err := keyExtractor.Extract(doc)
+		if err != nil {
+			return nil, fmt.Errorf("join key extraction failed: %w", err)
+		}
+		if key == nil {
+			continue
+		}
+
+		keyStr, err := computeJSONAny(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute join key: %w", err)
+		}
+
+		bucket, ok := index[keyStr]
+		if !ok {
+			bucket = NewDocumentZSet()
+			index[keyStr] = bucket
+		}
+		if err := bucket.AddDocumentMutate(doc, mult); err != nil {
+			return nil, err
+		}
+	}
+
+	return index, nil
+}
+
+// collectBuckets fills in the buckets for a key, taking the bucket of the i-th input from the
+// deltas if the i-th bit of the mask is set and from the states otherwise. Returns false if any
+// of the buckets is empty.
+func collectBuckets(buckets []*DocumentZSet, key string, deltas, states []joinIndex, mask int) bool {
+	for i := range buckets {
+		index := states[i]
+		if mask&(1<<i) != 0 {
+			index = deltas[i]
+		}
+		bucket, ok := index[key]
+		if !ok || bucket.IsZero() {
+			return false
+		}
+		buckets[i] = bucket
+	}
+	return true
+}
@@ -1143,3 +1143,118 @@ var _ = Describe("N-ary Join Operators", func() {
 		})
 	})
 })
+
+// KeyedJoinEvaluator is a NaryJoinEvaluator that exposes its join keys for hash joins.
+type KeyedJoinEvaluator struct {
+	*NaryJoinEvaluator
+}
+
+func NewKeyedJoin(condition string, inputs []string) *KeyedJoinEvaluator {
+	return &KeyedJoinEvaluator{NaryJoinEvaluator: NewNaryJoin(condition, inputs)}
+}
+
+func (e *KeyedJoinEvaluator) JoinKeys(inputs []string) ([]Extractor, bool) {
+	keys := make([]Extractor, len(inputs))
+	for i := range inputs {
+		keys[i] = NewFieldExtractor(e.condition)
+	}
+	return keys, true
+}
+
+var _ = Describe("Hash Join Operators", func() {
+	var (
+		inputs    []string
+		evaluator *KeyedJoinEvaluator
+		keys      []Extractor
+	)
+
+	BeforeEach(func() {
+		inputs = []string{"users", "projects", "departments"}
+		evaluator = NewKeyedJoin("id", inputs)
+		keys, _ = evaluator.JoinKeys(inputs)
+	})
+
+	randomZSet := func(rnd int, size int, name string) *DocumentZSet {
+		z := NewDocumentZSet()
+		for i := 0; i < size; i++ {
+			doc := Document{"id": int64((rnd + i*7) % 4), "name": fmt.Sprintf("%s-%d", name, (rnd+i*3)%5)}
+			mult := 1
+			if (rnd+i)%5 == 0 {
+				mult = -1
+			}
+			Expect(z.AddDocumentMutate(doc, mult)).To(Succeed())
+		}
+		return z
+	}
+
+	It("should produce the same result as the snapshot join", func() {
+		for rnd := 0; rnd < 10; rnd++ {
+			zsets := []*DocumentZSet{randomZSet(rnd, 6, "u"), randomZSet(rnd+1, 5, "p"), randomZSet(rnd+2, 4, "d")}
+
+			expected, err := NewJoin(evaluator, inputs).Process(zsets...)
+			Expect(err).NotTo(HaveOccurred())
+			result, err := NewHashJoin(evaluator, inputs, keys).Process(zsets...)
+			Expect(err).NotTo(HaveOccurred())
+
+			diff, err := result.Subtract(expected)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff.IsZero()).To(BeTrue(), diff.String())
+		}
+	})
+
+	It("should produce the same deltas as the incremental join", func() {
+		incrementalJoin := NewIncrementalJoin(evaluator, inputs)
+		hashJoin := NewIncrementalHashJoin(evaluator, inputs, keys)
+
+		for rnd := 0; rnd < 10; rnd++ {
+			deltas := []*DocumentZSet{randomZSet(rnd, 3, "u"), randomZSet(rnd*3, 2, "p"), randomZSet(rnd*5, 2, "d")}
+			if rnd%3 == 0 {
+				deltas[1] = NewDocumentZSet()
+			}
+
+			expected, err := incrementalJoin.Process(deltas...)
+			Expect(err).NotTo(HaveOccurred())
+			result, err := hashJoin.Process(deltas...)
+			Expect(err).NotTo(HaveOccurred())
+
+			diff, err := result.Subtract(expected)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff.IsZero()).To(BeTrue(), fmt.Sprintf("step %d: %s", rnd, diff.String()))
+		}
+	})
+
+	It("should skip documents without a join key", func() {
+		users := NewDocumentZSet()
+		Expect(users.AddDocumentMutate(Document{"name": "alice"}, 1)).To(Succeed())
+		projects := NewDocumentZSet()
+		Expect(projects.AddDocumentMutate(Document{"name": "p1"}, 1)).To(Succeed())
+		depts := NewDocumentZSet()
+
+		hashJoin := NewIncrementalHashJoin(evaluator, inputs, keys)
+		result, err := hashJoin.Process(users, projects, depts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+	})
+
+	It("should reset state correctly", func() {
+		hashJoin := NewIncrementalHashJoin(evaluator, inputs, keys)
+		user := Document{"id": int64(1), "name": "alice"}
+		project := Document{"id": int64(1), "title": "p1"}
+		dept := Document{"id": int64(1), "name": "eng"}
+
+		userZSet, err := SingletonZSet(user)
+		Expect(err).NotTo(HaveOccurred())
+		projectZSet, err := SingletonZSet(project)
+		Expect(err).NotTo(HaveOccurred())
+		deptZSet, err := SingletonZSet(dept)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = hashJoin.Process(userZSet, projectZSet, NewDocumentZSet())
+		Expect(err).NotTo(HaveOccurred())
+
+		hashJoin.Reset()
+		result, err := hashJoin.Process(NewDocumentZSet(), NewDocumentZSet(), deptZSet)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+	})
+})
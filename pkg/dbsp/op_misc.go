@@ -28,6 +28,66 @@ func (n *DistinctOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
 	return res, nil
 }
 
+// IncrementalDistinctOp is the incremental version of the distinct op: distinct^Δ = D ∘ distinct ∘
+// I. Instead of integrating the input and differentiating the output it keeps the integrated
+// multiplicity of each document, and emits a change only when a document enters or leaves the
+// support of the integrated Z-set.
+type IncrementalDistinctOp struct {
+	BaseOp
+	state *DocumentZSet // Integrated input
+}
+
+// NewIncrementalDistinct creates a new incremental distinct op.
+func NewIncrementalDistinct() *IncrementalDistinctOp {
+	return &IncrementalDistinctOp{
+		BaseOp: NewBaseOp("distinct^Δ", 1),
+		state:  NewDocumentZSet(),
+	}
+}
+
+func (n *IncrementalDistinctOp) OpType() OperatorType              { return OpTypeNonLinear }
+func (n *IncrementalDistinctOp) IsTimeInvariant() bool             { return true }
+func (n *IncrementalDistinctOp) HasZeroPreservationProperty() bool { return true }
+
+// Process evaluates the op.
+func (n *IncrementalDistinctOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := n.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	delta := inputs[0]
+	result := NewDocumentZSet()
+	for key, count := range delta.counts {
+		doc := delta.docs[key]
+		oldCount := n.state.counts[key]
+		newCount := oldCount + count
+
+		switch {
+		case oldCount <= 0 && newCount > 0:
+			// document enters the set
+			if err := result.AddDocumentMutate(doc, 1); err != nil {
+				return nil, err
+			}
+		case oldCount > 0 && newCount <= 0:
+			// document leaves the set
+			if err := result.AddDocumentMutate(doc, -1); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := n.state.AddDocumentMutate(DeepCopyDocument(doc), count); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Reset state (useful for testing or restarting computation).
+func (n *IncrementalDistinctOp) Reset() {
+	n.state = NewDocumentZSet()
+}
+
 // IntegratorOp implements the I operator: converts deltas to snapshots.
 // I(s)[t] = Σ(i=0 to t) s[i]
 type IntegratorOp struct {
@@ -177,19 +237,32 @@ type AddOp struct {
 
 // NewAdd creates a new Add op.
 func NewAdd() *AddOp {
+	return NewNaryAdd(2)
+}
+
+// NewNaryAdd creates a new Add op that sums n inputs. Addition is linear, so the op is its own
+// incremental version: the sum of the input deltas is the delta of the sum.
+func NewNaryAdd(n int) *AddOp {
 	return &AddOp{
-		BaseOp: NewBaseOp("+", 2),
+		BaseOp: NewBaseOp("+", n),
 	}
 }
 
+func (n *AddOp) OpType() OperatorType              { return OpTypeLinear }
+func (n *AddOp) IsTimeInvariant() bool             { return true }
+func (n *AddOp) HasZeroPreservationProperty() bool { return true }
+
 // Process evaluates the op.
 func (n *AddOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
 	if err := n.validateInputs(inputs); err != nil {
 		return nil, err
 	}
-	res, err := inputs[0].Add(inputs[1])
-	if err != nil {
-		return nil, err
+	res := NewDocumentZSet()
+	for _, input := range inputs {
+		var err error
+		if res, err = res.Add(input); err != nil {
+			return nil, err
+		}
 	}
 	return res, nil
 }
@@ -242,8 +315,9 @@ func (n *NegateOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
 	return res, nil
 }
 
-// DelayOp implements the z^(-1) operator: delays stream by one timestep.
-// PROBLEMATIC: This is stateful and needs careful handling in the execution model.
+// DelayOp implements the z^(-1) operator: delays stream by one timestep. The delay closes the
+// feedback loop of recursive queries, see FixpointOp: the output of the delay must be read with
+// Output() before the input of the current timestep is known.
 type DelayOp struct {
 	BaseOp
 	buffer *DocumentZSet // Buffered value from previous timestep
@@ -274,6 +348,11 @@ func (n *DelayOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
 	return output, nil
 }
 
+// Output returns the value buffered at the previous timestep without advancing the delay.
+func (n *DelayOp) Output() *DocumentZSet {
+	return n.buffer.DeepCopy()
+}
+
 func (n *DelayOp) OpType() OperatorType              { return OpTypeLinear }
 func (n *DelayOp) IsTimeInvariant() bool             { return true }
 func (n *DelayOp) HasZeroPreservationProperty() bool { return true }
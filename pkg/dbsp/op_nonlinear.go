@@ -2,6 +2,9 @@ package dbsp
 
 import (
 	"fmt"
+	"math"
+	"strings"
+	"time"
 )
 
 // Snapshot Gather Operation (stateless).
@@ -269,6 +272,520 @@ func (op *IncrementalGatherOp) Reset() {
 	op.currentGroups = map[string]*GroupData{}
 }
 
+// AggregateOp is a snapshot aggregation op: it groups the documents by a key and computes a set of
+// named aggregates per group. The aggregator receives a representative document of the group and
+// the map of the aggregate results by name, and returns the output document of the group.
+type AggregateOp struct {
+	BaseOp
+	keyExtractor Extractor
+	aggregates   []Aggregate
+	aggregator   Transformer
+}
+
+// NewAggregate creates a new snapshot aggregation op.
+func NewAggregate(keyExtractor Extractor, aggregates []Aggregate, aggregator Transformer) *AggregateOp {
+	return &AggregateOp{
+		BaseOp:       NewBaseOp("aggregate", 1),
+		keyExtractor: keyExtractor,
+		aggregates:   aggregates,
+		aggregator:   aggregator,
+	}
+}
+
+func (op *AggregateOp) OpType() OperatorType              { return OpTypeNonLinear }
+func (op *AggregateOp) IsTimeInvariant() bool             { return true }
+func (op *AggregateOp) HasZeroPreservationProperty() bool { return true }
+
+// Process evaluates the op.
+func (op *AggregateOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := op.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	groups := aggregateGroups{}
+	for key, multiplicity := range inputs[0].counts {
+		if _, err := groups.update(op.keyExtractor, op.aggregates, inputs[0].docs[key], multiplicity); err != nil {
+			return nil, err
+		}
+	}
+
+	result := NewDocumentZSet()
+	for _, group := range groups {
+		if group.count <= 0 {
+			continue
+		}
+		doc, err := group.result(op.aggregates, op.aggregator, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := result.AddDocumentMutate(doc, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// IncrementalAggregateOp is the incremental version of the aggregation op. The op keeps the
+// incremental state of the aggregates of each group, so the cost of processing a delta does not
+// depend on the size of the groups.
+type IncrementalAggregateOp struct {
+	BaseOp
+	keyExtractor Extractor
+	aggregates   []Aggregate
+	aggregator   Transformer
+
+	groups aggregateGroups
+}
+
+// NewIncrementalAggregate returns a new incremental aggregation op.
+func NewIncrementalAggregate(keyExtractor Extractor, aggregates []Aggregate, aggregator Transformer) *IncrementalAggregateOp {
+	return &IncrementalAggregateOp{
+		BaseOp:       NewBaseOp("aggregate^Δ", 1),
+		keyExtractor: keyExtractor,
+		aggregates:   aggregates,
+		aggregator:   aggregator,
+		groups:       aggregateGroups{},
+	}
+}
+
+func (op *IncrementalAggregateOp) OpType() OperatorType              { return OpTypeNonLinear }
+func (op *IncrementalAggregateOp) IsTimeInvariant() bool             { return true }
+func (op *IncrementalAggregateOp) HasZeroPreservationProperty() bool { return true }
+
+// Process evaluates the op.
+func (op *IncrementalAggregateOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := op.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	// Update the groups, remembering the last output of each affected group
+	affected := map[string]Document{}
+	for key, multiplicity := range inputs[0].counts {
+		groupKey, err := op.groups.update(op.keyExtractor, op.aggregates, inputs[0].docs[key], multiplicity)
+		if err != nil {
+			return nil, err
+		}
+		if groupKey == "" {
+			continue
+		}
+		if _, ok := affected[groupKey]; !ok {
+			affected[groupKey] = op.groups[groupKey].output
+		}
+	}
+
+	result := NewDocumentZSet()
+	for groupKey, oldDoc := range affected {
+		group := op.groups[groupKey]
+		if err := group.emit(result, oldDoc, op.aggregates, op.aggregator, nil); err != nil {
+			return nil, err
+		}
+		if group.count == 0 {
+			delete(op.groups, groupKey)
+		}
+	}
+
+	return result, nil
+}
+
+// Reset method for testing.
+func (op *IncrementalAggregateOp) Reset() {
+	op.groups = aggregateGroups{}
+}
+
+// aggregateGroup is the state of a group of an aggregation.
+type aggregateGroup struct {
+	key    any
+	count  int      // total multiplicity of the documents in the group
+	doc    Document // the first document of the group
+	states []AggregateState
+	output Document // the last output document
+}
+
+type aggregateGroups map[string]*aggregateGroup
+
+// update adds a document to its group and returns the group key, or an empty string if the
+// document has no key.
+func (groups aggregateGroups) update(keyExtractor Extractor, aggregates []Aggregate, doc Document, mult int) (string, error) {
+	key, err := keyExtractor.Extract(doc)
+	if err != nil {
+		return "", fmt.Errorf("key extraction failed: %w", err)
+	}
+	if key == nil {
+		return "", nil
+	}
+
+	groupKey, err := computeJSONAny(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute group key: %w", err)
+	}
+
+	group, ok := groups[groupKey]
+	if !ok {
+		group = &aggregateGroup{key: key, doc: DeepCopyDocument(doc), states: make([]AggregateState, len(aggregates))}
+		for i, a := range aggregates {
+			group.states[i] = a.Func.NewState()
+		}
+		groups[groupKey] = group
+	}
+
+	group.count += mult
+	for i, a := range aggregates {
+		value, err := a.Extractor.Extract(doc)
+		if err != nil {
+			return "", fmt.Errorf("value extraction failed for aggregate %s: %w", a.Name, err)
+		}
+		if value == nil {
+			continue // Skip documents without value
+		}
+		if err := group.states[i].Update(value, mult); err != nil {
+			return "", fmt.Errorf("aggregate %s failed: %w", a.Name, err)
+		}
+	}
+
+	return groupKey, nil
+}
+
+// result returns the output document of the group. The aggregator receives the map of the
+// aggregate results by name, or the value returned by wrap for the map if wrap is not nil.
+func (group *aggregateGroup) result(aggregates []Aggregate, aggregator Transformer, wrap func(map[string]any) any) (Document, error) {
+	results := make(map[string]any, len(aggregates))
+	for i, a := range aggregates {
+		results[a.Name] = DeepCopyAny(group.states[i].Result())
+	}
+
+	var v any = results
+	if wrap != nil {
+		v = wrap(results)
+	}
+
+	doc, err := aggregator.Transform(DeepCopyDocument(group.doc), v)
+	if err != nil {
+		return nil, fmt.Errorf("aggregation failed: %w", err)
+	}
+
+	return doc, nil
+}
+
+// emit adds the change of the output document of the group relative to the old output document
+// to the result.
+func (group *aggregateGroup) emit(result *DocumentZSet, oldDoc Document, aggregates []Aggregate, aggregator Transformer, wrap func(map[string]any) any) error {
+	var newDoc Document
+	if group.count > 0 {
+		doc, err := group.result(aggregates, aggregator, wrap)
+		if err != nil {
+			return err
+		}
+		newDoc = doc
+	}
+
+	if oldDoc != nil {
+		if err := result.AddDocumentMutate(oldDoc, -1); err != nil {
+			return err
+		}
+	}
+	if newDoc != nil {
+		if err := result.AddDocumentMutate(newDoc, 1); err != nil {
+			return err
+		}
+	}
+	group.output = newDoc
+
+	return nil
+}
+
+// Window assigns documents to time windows by a timestamp. Windows span [start, start+Size) and
+// start at the multiples of Slide. Tumbling windows have the same Size and Slide, while sliding
+// windows with a Slide smaller than the Size overlap. The Size must be a multiple of the Slide, so
+// that windows also end at the multiples of Slide.
+type Window struct {
+	Size, Slide time.Duration
+}
+
+// NewTumblingWindow returns a tumbling window of the given size.
+func NewTumblingWindow(size time.Duration) Window { return Window{Size: size, Slide: size} }
+
+// NewSlidingWindow returns a window of the given size that advances by the given slide.
+func NewSlidingWindow(size, slide time.Duration) Window { return Window{Size: size, Slide: slide} }
+
+// Validate checks the window.
+func (w Window) Validate() error {
+	if w.Size <= 0 || w.Slide <= 0 {
+		return fmt.Errorf("window size and slide must be positive, got size=%s, slide=%s", w.Size, w.Slide)
+	}
+	if w.Size%w.Slide != 0 {
+		return fmt.Errorf("window size must be a multiple of the slide, got size=%s, slide=%s", w.Size, w.Slide)
+	}
+	return nil
+}
+
+// Current returns the start and the end of the last window closed by the given time.
+func (w Window) Current(now time.Time) (time.Time, time.Time) {
+	end := now.Truncate(w.Slide)
+	return end.Add(-w.Size), end
+}
+
+// Starts returns the starts of the windows that contain a timestamp.
+func (w Window) Starts(ts time.Time) []time.Time {
+	starts := []time.Time{}
+	for start := ts.Truncate(w.Slide); start.Add(w.Size).After(ts); start = start.Add(-w.Slide) {
+		starts = append(starts, start)
+	}
+	return starts
+}
+
+func (w Window) String() string {
+	if w.Size == w.Slide {
+		return fmt.Sprintf("tumbling(%s)", w.Size)
+	}
+	return fmt.Sprintf("sliding(%s/%s)", w.Size, w.Slide)
+}
+
+// WindowResult is the input of the aggregator of a windowed aggregation.
+type WindowResult struct {
+	Start, End time.Time
+	Aggregates map[string]any
+}
+
+// WindowedAggregateOp is a snapshot windowed aggregation op. The op assigns the documents to time
+// windows by a timestamp and computes the aggregates over the documents of each group in the last
+// window closed by the clock. The aggregator receives a representative document of the group and
+// a WindowResult.
+type WindowedAggregateOp struct {
+	BaseOp
+	keyExtractor, tsExtractor Extractor
+	window                    Window
+	aggregates                []Aggregate
+	aggregator                Transformer
+	clock                     func() time.Time
+}
+
+// NewWindowedAggregate creates a new snapshot windowed aggregation op.
+func NewWindowedAggregate(keyExtractor, tsExtractor Extractor, window Window, aggregates []Aggregate, aggregator Transformer, clock func() time.Time) *WindowedAggregateOp {
+	return &WindowedAggregateOp{
+		BaseOp:       NewBaseOp(fmt.Sprintf("window_%s", window), 1),
+		keyExtractor: keyExtractor,
+		tsExtractor:  tsExtractor,
+		window:       window,
+		aggregates:   aggregates,
+		aggregator:   aggregator,
+		clock:        clock,
+	}
+}
+
+func (op *WindowedAggregateOp) OpType() OperatorType              { return OpTypeNonLinear }
+func (op *WindowedAggregateOp) IsTimeInvariant() bool             { return false }
+func (op *WindowedAggregateOp) HasZeroPreservationProperty() bool { return true }
+
+// Process evaluates the op.
+func (op *WindowedAggregateOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := op.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	start, end := op.window.Current(op.clock())
+	groups := aggregateGroups{}
+	for key, multiplicity := range inputs[0].counts {
+		doc := inputs[0].docs[key]
+		ts, err := extractTimestamp(op.tsExtractor, doc)
+		if err != nil {
+			return nil, err
+		}
+		if ts.IsZero() || ts.Before(start) || !ts.Before(end) {
+			continue
+		}
+		if _, err := groups.update(op.keyExtractor, op.aggregates, doc, multiplicity); err != nil {
+			return nil, err
+		}
+	}
+
+	wrap := windowWrapper(start, end)
+	result := NewDocumentZSet()
+	for _, group := range groups {
+		if group.count <= 0 {
+			continue
+		}
+		doc, err := group.result(op.aggregates, op.aggregator, wrap)
+		if err != nil {
+			return nil, err
+		}
+		if err := result.AddDocumentMutate(doc, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// IncrementalWindowedAggregateOp is the incremental version of the windowed aggregation op. The op
+// keeps the aggregation state of the current and the future windows. When the clock closes a new
+// window, the op retracts the output of the previous window and emits the output of the new one,
+// even if the input delta is empty.
+type IncrementalWindowedAggregateOp struct {
+	BaseOp
+	keyExtractor, tsExtractor Extractor
+	window                    Window
+	aggregates                []Aggregate
+	aggregator                Transformer
+	clock                     func() time.Time
+
+	// State: the groups of each window by the start of the window (in Unix nanoseconds), and
+	// the current window
+	start   time.Time
+	windows map[int64]aggregateGroups
+}
+
+// NewIncrementalWindowedAggregate returns a new incremental windowed aggregation op.
+func NewIncrementalWindowedAggregate(keyExtractor, tsExtractor Extractor, window Window, aggregates []Aggregate, aggregator Transformer, clock func() time.Time) *IncrementalWindowedAggregateOp {
+	return &IncrementalWindowedAggregateOp{
+		BaseOp:       NewBaseOp(fmt.Sprintf("window^Δ_%s", window), 1),
+		keyExtractor: keyExtractor,
+		tsExtractor:  tsExtractor,
+		window:       window,
+		aggregates:   aggregates,
+		aggregator:   aggregator,
+		clock:        clock,
+		windows:      map[int64]aggregateGroups{},
+	}
+}
+
+func (op *IncrementalWindowedAggregateOp) OpType() OperatorType              { return OpTypeNonLinear }
+func (op *IncrementalWindowedAggregateOp) IsTimeInvariant() bool             { return false }
+func (op *IncrementalWindowedAggregateOp) HasZeroPreservationProperty() bool { return false }
+
+// Process evaluates the op.
+func (op *IncrementalWindowedAggregateOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := op.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	result := NewDocumentZSet()
+	start, end := op.window.Current(op.clock())
+	wrap := windowWrapper(start, end)
+
+	// Close the previous window: retract its output, drop the expired windows and emit the
+	// output of the current window
+	if !start.Equal(op.start) {
+		for _, group := range op.windows[op.start.UnixNano()] {
+			if group.output != nil {
+				if err := result.AddDocumentMutate(group.output, -1); err != nil {
+					return nil, err
+				}
+				group.output = nil
+			}
+		}
+		for windowStart := range op.windows {
+			if windowStart < start.UnixNano() {
+				delete(op.windows, windowStart)
+			}
+		}
+		op.start = start
+		for _, group := range op.windows[start.UnixNano()] {
+			if err := group.emit(result, nil, op.aggregates, op.aggregator, wrap); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Update the groups of the current and the future windows, remembering the last output of
+	// each affected group
+	type groupRef struct {
+		start int64
+		key   string
+	}
+	affected := map[groupRef]Document{}
+	for key, multiplicity := range inputs[0].counts {
+		doc := inputs[0].docs[key]
+		ts, err := extractTimestamp(op.tsExtractor, doc)
+		if err != nil {
+			return nil, err
+		}
+		if ts.IsZero() {
+			continue
+		}
+
+		for _, windowStart := range op.window.Starts(ts) {
+			if windowStart.Before(start) {
+				continue // late document
+			}
+			groups, ok := op.windows[windowStart.UnixNano()]
+			if !ok {
+				groups = aggregateGroups{}
+				op.windows[windowStart.UnixNano()] = groups
+			}
+			groupKey, err := groups.update(op.keyExtractor, op.aggregates, doc, multiplicity)
+			if err != nil {
+				return nil, err
+			}
+			if groupKey == "" {
+				continue
+			}
+			ref := groupRef{start: windowStart.UnixNano(), key: groupKey}
+			if _, ok := affected[ref]; !ok {
+				affected[ref] = groups[groupKey].output
+			}
+		}
+	}
+
+	for ref, oldDoc := range affected {
+		groups := op.windows[ref.start]
+		group := groups[ref.key]
+		if ref.start == start.UnixNano() {
+			if err := group.emit(result, oldDoc, op.aggregates, op.aggregator, wrap); err != nil {
+				return nil, err
+			}
+		}
+		if group.count == 0 {
+			delete(groups, ref.key)
+		}
+	}
+
+	return result, nil
+}
+
+// Reset method for testing.
+func (op *IncrementalWindowedAggregateOp) Reset() {
+	op.start = time.Time{}
+	op.windows = map[int64]aggregateGroups{}
+}
+
+func windowWrapper(start, end time.Time) func(map[string]any) any {
+	return func(results map[string]any) any {
+		return &WindowResult{Start: start, End: end, Aggregates: results}
+	}
+}
+
+// extractTimestamp extracts a timestamp from a document. Timestamps may be RFC 3339 strings or
+// numbers of seconds since the Unix epoch. Returns a zero time if the document has no timestamp.
+func extractTimestamp(tsExtractor Extractor, doc Document) (time.Time, error) {
+	v, err := tsExtractor.Extract(doc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp extraction failed: %w", err)
+	}
+
+	switch ts := v.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return ts, nil
+	case string:
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q: %w", ts, err)
+		}
+		return t, nil
+	case int64:
+		return time.Unix(ts, 0), nil
+	case int:
+		return time.Unix(int64(ts), 0), nil
+	case float64:
+		sec, frac := math.Modf(ts)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid timestamp %v of type %T", v, v)
+	}
+}
+
 func abs(x int) int {
 	if x < 0 {
 		return -x
@@ -286,3 +803,495 @@ func removeFirstMatch(slice []any, item any) []any {
 	}
 	return slice
 }
+
+// LeftJoinOp is a snapshot N-ary left outer join. The first input is preserved: documents of the
+// first input that do not join with any combination of the other inputs are emitted as a document
+// that contains only the first input, keyed by the name of the first input.
+type LeftJoinOp struct {
+	BaseOp
+	eval   Evaluator
+	inputs []string
+	n      int
+}
+
+// NewLeftJoin returns a new snapshot left join op.
+func NewLeftJoin(eval Evaluator, inputs []string) *LeftJoinOp {
+	return &LeftJoinOp{
+		BaseOp: NewBaseOp(fmt.Sprintf("snapshot_⟕_%d", len(inputs)), len(inputs)),
+		eval:   eval,
+		inputs: inputs,
+		n:      len(inputs),
+	}
+}
+
+func (op *LeftJoinOp) OpType() OperatorType              { return OpTypeNonLinear }
+func (op *LeftJoinOp) IsTimeInvariant() bool             { return true }
+func (op *LeftJoinOp) HasZeroPreservationProperty() bool { return true }
+
+// Process evaluates the op.
+func (op *LeftJoinOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := op.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	matched, err := cartesianJoin(op.eval, op.inputs, inputs, 0, make([]Document, op.n), make([]int, op.n))
+	if err != nil {
+		return nil, err
+	}
+
+	unmatched, err := unmatchedJoin(op.eval, op.inputs, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	return matched.Add(unmatched)
+}
+
+// AntiJoinOp is a snapshot N-ary anti-join: it emits the documents of the first input that do not
+// join with any combination of the other inputs, keyed by the name of the first input.
+type AntiJoinOp struct {
+	BaseOp
+	eval   Evaluator
+	inputs []string
+}
+
+// NewAntiJoin returns a new snapshot anti-join op.
+func NewAntiJoin(eval Evaluator, inputs []string) *AntiJoinOp {
+	return &AntiJoinOp{
+		BaseOp: NewBaseOp(fmt.Sprintf("snapshot_▷_%d", len(inputs)), len(inputs)),
+		eval:   eval,
+		inputs: inputs,
+	}
+}
+
+func (op *AntiJoinOp) OpType() OperatorType              { return OpTypeNonLinear }
+func (op *AntiJoinOp) IsTimeInvariant() bool             { return true }
+func (op *AntiJoinOp) HasZeroPreservationProperty() bool { return true }
+
+// Process evaluates the op.
+func (op *AntiJoinOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := op.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	return unmatchedJoin(op.eval, op.inputs, inputs)
+}
+
+// IncrementalLeftJoinOp implements an incremental N-ary left outer join as the sum of an
+// incremental inner join and the incremental anti-join.
+type IncrementalLeftJoinOp struct {
+	BaseOp
+	eval   Evaluator
+	inputs []string
+	join   *IncrementalJoinOp
+	anti   *IncrementalAntiJoinOp
+}
+
+// NewIncrementalLeftJoin returns a new incremental left join op.
+func NewIncrementalLeftJoin(eval Evaluator, inputs []string) *IncrementalLeftJoinOp {
+	return &IncrementalLeftJoinOp{
+		BaseOp: NewBaseOp(fmt.Sprintf("⟕_%d", len(inputs)), len(inputs)),
+		eval:   eval,
+		inputs: inputs,
+		join:   NewIncrementalJoin(eval, inputs),
+		anti:   NewIncrementalAntiJoin(eval, inputs),
+	}
+}
+
+func (op *IncrementalLeftJoinOp) OpType() OperatorType              { return OpTypeNonLinear }
+func (op *IncrementalLeftJoinOp) IsTimeInvariant() bool             { return true }
+func (op *IncrementalLeftJoinOp) HasZeroPreservationProperty() bool { return true }
+
+// Process evaluates the op.
+func (op *IncrementalLeftJoinOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := op.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	matched, err := op.join.Process(inputs...)
+	if err != nil {
+		return nil, err
+	}
+
+	unmatched, err := op.anti.Process(inputs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return matched.Add(unmatched)
+}
+
+// InputCardinalities implements CardinalityEstimator.
+func (op *IncrementalLeftJoinOp) InputCardinalities() []int {
+	return op.anti.InputCardinalities()
+}
+
+// Reset method for testing.
+func (op *IncrementalLeftJoinOp) Reset() {
+	op.join.Reset()
+	op.anti.Reset()
+}
+
+// IncrementalAntiJoinOp implements an incremental N-ary anti-join. The op maintains the integrated
+// inputs, an index of the combinations of the other inputs that join with each document of the
+// first input, and the resultant match counts. The join condition is evaluated only for new
+// documents: when the multiplicity of a document changes or a document is removed, the affected
+// match counts are looked up from the index, so a delta costs time proportional to the new
+// documents and the matches they affect instead of the size of the integrated inputs.
+type IncrementalAntiJoinOp struct {
+	BaseOp
+	eval   Evaluator
+	inputs []string
+	n      int
+
+	// State: integrated inputs and match counts of the first input's documents
+	prevStates []*DocumentZSet
+	matches    map[string]int
+
+	// Match index: the combinations of the other inputs' documents that join with each document
+	// of the first input, and the reverse indices to find them by combination and by document
+	matched   map[string]map[string]bool   // left key -> combination keys
+	joined    map[string]map[string]bool   // combination key -> left keys
+	comboKeys map[string][]string          // combination key -> document keys of the other inputs
+	combos    []map[string]map[string]bool // document key of the i+1-th input -> combination keys
+}
+
+// NewIncrementalAntiJoin returns a new incremental anti-join op.
+func NewIncrementalAntiJoin(eval Evaluator, inputs []string) *IncrementalAntiJoinOp {
+	op := &IncrementalAntiJoinOp{
+		BaseOp: NewBaseOp(fmt.Sprintf("▷_%d", len(inputs)), len(inputs)),
+		eval:   eval,
+		inputs: inputs,
+		n:      len(inputs),
+	}
+	op.Reset()
+	return op
+}
+
+func (op *IncrementalAntiJoinOp) OpType() OperatorType              { return OpTypeNonLinear }
+func (op *IncrementalAntiJoinOp) IsTimeInvariant() bool             { return true }
+func (op *IncrementalAntiJoinOp) HasZeroPreservationProperty() bool { return true }
+
+// Process evaluates the op.
+func (op *IncrementalAntiJoinOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := op.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	// Integrate the inputs
+	newStates := make([]*DocumentZSet, op.n)
+	for i := range inputs {
+		s, err := op.prevStates[i].Add(inputs[i])
+		if err != nil {
+			return nil, err
+		}
+		newStates[i] = s
+	}
+	prevLeft, newLeft, deltaLeft := op.prevStates[0], newStates[0], inputs[0]
+
+	// The documents of the first input whose output may change
+	affected := make(map[string]bool)
+
+	// Changes of the other inputs: the match counts of the documents that join with a changed
+	// document change, removed documents are dropped from the index, and new documents are
+	// matched against the existing documents of the first input
+	newRight := make([][]string, op.n)
+	for i := 1; i < op.n; i++ {
+		for key := range inputs[i].counts {
+			for combo := range op.combos[i-1][key] {
+				for leftKey := range op.joined[combo] {
+					affected[leftKey] = true
+				}
+			}
+
+			_, wasPresent := op.prevStates[i].counts[key]
+			_, isPresent := newStates[i].counts[key]
+			switch {
+			case !wasPresent && isPresent:
+				newRight[i] = append(newRight[i], key)
+			case wasPresent && !isPresent:
+				for combo := range op.combos[i-1][key] {
+					op.dropCombo(combo)
+				}
+			}
+		}
+	}
+
+	// Each combination that contains new documents is evaluated once, at its first new document:
+	// the inputs before it contribute only their existing documents
+	candidates := make([][]string, op.n)
+	for i := 1; i < op.n; i++ {
+		if len(newRight[i]) == 0 {
+			continue
+		}
+		for j := 1; j < op.n; j++ {
+			switch {
+			case j < i:
+				candidates[j] = existingKeys(op.prevStates[j], newStates[j])
+			case j == i:
+				candidates[j] = newRight[i]
+			default:
+				candidates[j] = existingKeys(newStates[j], newStates[j])
+			}
+		}
+		for leftKey := range prevLeft.counts {
+			if _, ok := newLeft.counts[leftKey]; !ok {
+				continue
+			}
+			if err := op.index(leftKey, newLeft.docs[leftKey], candidates, newStates, affected); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Changes of the first input: new documents are matched against all the combinations of the
+	// other inputs and removed documents are dropped from the index
+	for j := 1; j < op.n; j++ {
+		candidates[j] = existingKeys(newStates[j], newStates[j])
+	}
+	for key := range deltaLeft.counts {
+		affected[key] = true
+
+		_, wasPresent := prevLeft.counts[key]
+		_, isPresent := newLeft.counts[key]
+		switch {
+		case !wasPresent && isPresent:
+			if err := op.index(key, newLeft.docs[key], candidates, newStates, affected); err != nil {
+				return nil, err
+			}
+		case wasPresent && !isPresent:
+			op.dropLeft(key)
+		}
+	}
+
+	// Emit the change of the unmatched documents
+	result := NewDocumentZSet()
+	for key := range affected {
+		newCount := op.count(key, newStates)
+		diff := unmatchedMult(newLeft.counts[key], newCount) - unmatchedMult(prevLeft.counts[key], op.matches[key])
+		if diff != 0 {
+			doc, ok := newLeft.docs[key]
+			if !ok {
+				doc = prevLeft.docs[key]
+			}
+			if err := result.AddDocumentMutate(Document{op.inputs[0]: DeepCopyDocument(doc)}, diff); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, ok := newLeft.counts[key]; ok && newCount != 0 {
+			op.matches[key] = newCount
+		} else {
+			delete(op.matches, key)
+		}
+	}
+
+	op.prevStates = newStates
+
+	return result, nil
+}
+
+// InputCardinalities implements CardinalityEstimator.
+func (op *IncrementalAntiJoinOp) InputCardinalities() []int {
+	return zsetSizes(op.prevStates)
+}
+
+// Reset method for testing.
+func (op *IncrementalAntiJoinOp) Reset() {
+	op.prevStates = make([]*DocumentZSet, op.n)
+	for i := range op.prevStates {
+		op.prevStates[i] = NewDocumentZSet()
+	}
+	op.matches = map[string]int{}
+	op.matched = map[string]map[string]bool{}
+	op.joined = map[string]map[string]bool{}
+	op.comboKeys = map[string][]string{}
+	op.combos = make([]map[string]map[string]bool, op.n-1)
+	for i := range op.combos {
+		op.combos[i] = map[string]map[string]bool{}
+	}
+}
+
+// index evaluates the join condition on a document of the first input and the combinations of the
+// candidate documents of the other inputs, and adds the matching combinations to the index.
+func (op *IncrementalAntiJoinOp) index(leftKey string, leftDoc Document, candidates [][]string, states []*DocumentZSet, affected map[string]bool) error {
+	keys := make([]string, op.n)
+	keys[0] = leftKey
+	docs := make([]Document, op.n)
+	docs[0] = leftDoc
+	return op.indexRec(candidates, states, 1, keys, docs, affected)
+}
+
+func (op *IncrementalAntiJoinOp) indexRec(candidates [][]string, states []*DocumentZSet, inputIndex int, keys []string, docs []Document, affected map[string]bool) error {
+	if inputIndex == op.n {
+		joinInput := make(Document)
+		for i, doc := range docs {
+			joinInput[op.inputs[i]] = doc
+		}
+
+		joinedDocs, err := op.eval.Evaluate(joinInput)
+		if err != nil {
+			return err
+		}
+		if len(joinedDocs) > 0 {
+			op.addMatch(keys[0], keys[1:])
+			affected[keys[0]] = true
+		}
+		return nil
+	}
+
+	for _, key := range candidates[inputIndex] {
+		keys[inputIndex] = key
+		docs[inputIndex] = states[inputIndex].docs[key]
+		if err := op.indexRec(candidates, states, inputIndex+1, keys, docs, affected); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addMatch adds a matching combination of the other inputs' documents to the index.
+func (op *IncrementalAntiJoinOp) addMatch(leftKey string, rightKeys []string) {
+	combo := strings.Join(rightKeys, "\x00")
+	if _, ok := op.comboKeys[combo]; !ok {
+		op.comboKeys[combo] = append([]string(nil), rightKeys...)
+		op.joined[combo] = map[string]bool{}
+		for i, key := range rightKeys {
+			if op.combos[i][key] == nil {
+				op.combos[i][key] = map[string]bool{}
+			}
+			op.combos[i][key][combo] = true
+		}
+	}
+	op.joined[combo][leftKey] = true
+
+	if op.matched[leftKey] == nil {
+		op.matched[leftKey] = map[string]bool{}
+	}
+	op.matched[leftKey][combo] = true
+}
+
+// dropCombo removes a combination of the other inputs' documents from the index.
+func (op *IncrementalAntiJoinOp) dropCombo(combo string) {
+	for leftKey := range op.joined[combo] {
+		delete(op.matched[leftKey], combo)
+		if len(op.matched[leftKey]) == 0 {
+			delete(op.matched, leftKey)
+		}
+	}
+	for i, key := range op.comboKeys[combo] {
+		delete(op.combos[i][key], combo)
+		if len(op.combos[i][key]) == 0 {
+			delete(op.combos[i], key)
+		}
+	}
+	delete(op.joined, combo)
+	delete(op.comboKeys, combo)
+}
+
+// dropLeft removes a document of the first input from the index.
+func (op *IncrementalAntiJoinOp) dropLeft(leftKey string) {
+	for combo := range op.matched[leftKey] {
+		delete(op.joined[combo], leftKey)
+		if len(op.joined[combo]) == 0 {
+			op.dropCombo(combo)
+		}
+	}
+	delete(op.matched, leftKey)
+}
+
+// count returns the match count of a document of the first input: the number of matching
+// combinations of the other inputs' documents, weighted by multiplicities.
+func (op *IncrementalAntiJoinOp) count(leftKey string, states []*DocumentZSet) int {
+	total := 0
+	for combo := range op.matched[leftKey] {
+		mult := 1
+		for i, key := range op.comboKeys[combo] {
+			mult *= states[i+1].counts[key]
+		}
+		total += mult
+	}
+	return total
+}
+
+// existingKeys returns the keys of the documents of a Z-set that are also present in another one.
+func existingKeys(dz, other *DocumentZSet) []string {
+	keys := make([]string, 0, len(dz.counts))
+	for key := range dz.counts {
+		if _, ok := other.counts[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// unmatchedJoin returns the documents of the first input that do not join with any combination of
+// the other inputs, wrapped into a document keyed by the name of the first input.
+func unmatchedJoin(eval Evaluator, inputNames []string, inputs []*DocumentZSet) (*DocumentZSet, error) {
+	result := NewDocumentZSet()
+	left := inputs[0]
+	for key, mult := range left.counts {
+		doc := left.docs[key]
+		c, err := countMatches(eval, inputNames, inputs, doc)
+		if err != nil {
+			return nil, err
+		}
+
+		if m := unmatchedMult(mult, c); m != 0 {
+			if err := result.AddDocumentMutate(Document{inputNames[0]: DeepCopyDocument(doc)}, m); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// countMatches returns the number of combinations of the documents of the second and subsequent
+// inputs that join with the given document of the first input, weighted by multiplicities.
+func countMatches(eval Evaluator, inputNames []string, inputs []*DocumentZSet, doc Document) (int, error) {
+	currentDocs := make([]Document, len(inputNames))
+	currentDocs[0] = doc
+	return countMatchesRec(eval, inputNames, inputs, 1, currentDocs, 1)
+}
+
+func countMatchesRec(eval Evaluator, inputNames []string, inputs []*DocumentZSet, inputIndex int, currentDocs []Document, currentMult int) (int, error) {
+	if inputIndex == len(inputNames) {
+		joinInput := make(Document)
+		for i, doc := range currentDocs {
+			joinInput[inputNames[i]] = doc
+		}
+
+		joinedDocs, err := eval.Evaluate(joinInput)
+		if err != nil {
+			return 0, err
+		}
+		if len(joinedDocs) == 0 {
+			return 0, nil
+		}
+		return currentMult, nil
+	}
+
+	total := 0
+	input := inputs[inputIndex]
+	for key, mult := range input.counts {
+		currentDocs[inputIndex] = input.docs[key]
+		c, err := countMatchesRec(eval, inputNames, inputs, inputIndex+1, currentDocs, currentMult*mult)
+		if err != nil {
+			return 0, err
+		}
+		total += c
+	}
+
+	return total, nil
+}
+
+// unmatchedMult returns the multiplicity of a document in the output of an anti-join given its
+// multiplicity in the first input and its match count.
+func unmatchedMult(mult, matches int) int {
+	if mult > 0 && matches <= 0 {
+		return mult
+	}
+	return 0
+}
@@ -2,6 +2,7 @@ package dbsp
 
 import (
 	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -1366,3 +1367,681 @@ var _ = Describe("ComplexChainEval", func() {
 		Expect(doc.Multiplicity).To(Equal(-1))
 	})
 })
+
+// FieldsAggregateTransformer writes the named aggregates into the fields of the same name.
+type FieldsAggregateTransformer struct{}
+
+func (t *FieldsAggregateTransformer) Transform(doc Document, value any) (Document, error) {
+	for name, v := range value.(map[string]any) {
+		doc[name] = v
+	}
+	return doc, nil
+}
+
+func (t *FieldsAggregateTransformer) String() string { return "fields" }
+
+func stripFields(zset *DocumentZSet, fields ...string) ([]DocumentEntry, error) {
+	entries, err := zset.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		for _, field := range fields {
+			delete(entry.Document, field)
+		}
+	}
+	return entries, nil
+}
+
+var _ = Describe("Aggregate Operations", func() {
+	var (
+		aggregates []Aggregate
+		docs       []Document
+	)
+
+	BeforeEach(func() {
+		aggregates = []Aggregate{
+			{Name: "count", Extractor: NewFieldExtractor("amount"), Func: NewCount()},
+			{Name: "total", Extractor: NewFieldExtractor("amount"), Func: NewSum()},
+			{Name: "max", Extractor: NewFieldExtractor("amount"), Func: NewMax()},
+			{Name: "first", Extractor: NewFieldExtractor("rep"), Func: NewFirst()},
+		}
+
+		docs = []Document{}
+		for _, pairs := range [][]any{
+			{"dept", "Engineering", "amount", int64(1000), "rep", "Alice"},
+			{"dept", "Engineering", "amount", int64(1500), "rep", "Bob"},
+			{"dept", "Marketing", "amount", int64(800), "rep", "Charlie"},
+			{"dept", "Marketing", "amount", int64(1200), "rep", "Diana"},
+		} {
+			doc, err := newDocumentFromPairs(pairs...)
+			Expect(err).NotTo(HaveOccurred())
+			docs = append(docs, doc)
+		}
+	})
+
+	It("should compute named aggregates per group", func() {
+		op := NewAggregate(NewFieldExtractor("dept"), aggregates, &FieldsAggregateTransformer{})
+		input := NewDocumentZSet()
+		for _, doc := range docs {
+			Expect(input.AddDocumentMutate(doc, 1)).To(Succeed())
+		}
+
+		result, err := op.Process(input)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.UniqueCount()).To(Equal(2))
+		res, err := result.GetUniqueDocuments()
+		Expect(err).NotTo(HaveOccurred())
+		for _, doc := range res {
+			switch doc["dept"] {
+			case "Engineering":
+				Expect(doc["count"]).To(Equal(int64(2)))
+				Expect(doc["total"]).To(Equal(int64(2500)))
+				Expect(doc["max"]).To(Equal(int64(1500)))
+			case "Marketing":
+				Expect(doc["count"]).To(Equal(int64(2)))
+				Expect(doc["total"]).To(Equal(int64(2000)))
+				Expect(doc["max"]).To(Equal(int64(1200)))
+			default:
+				Fail("unexpected group")
+			}
+		}
+	})
+
+	It("should update only the affected groups incrementally", func() {
+		op := NewIncrementalAggregate(NewFieldExtractor("dept"), aggregates, &FieldsAggregateTransformer{})
+		process := func(doc Document, mult int) *DocumentZSet {
+			delta, err := NewDocumentZSet().AddDocument(doc, mult)
+			Expect(err).NotTo(HaveOccurred())
+			result, err := op.Process(delta)
+			Expect(err).NotTo(HaveOccurred())
+			return result
+		}
+
+		result := process(docs[0], 1)
+		Expect(result.Size()).To(Equal(1))
+
+		// the old group result is retracted and the new one is added
+		result = process(docs[1], 1)
+		Expect(result.TotalSize()).To(Equal(2))
+		entries, err := result.List()
+		Expect(err).NotTo(HaveOccurred())
+		for _, entry := range entries {
+			if entry.Multiplicity > 0 {
+				Expect(entry.Document["total"]).To(Equal(int64(2500)))
+				Expect(entry.Document["first"]).To(Equal("Alice"))
+			} else {
+				Expect(entry.Document["total"]).To(Equal(int64(1000)))
+			}
+		}
+
+		result = process(docs[2], 1)
+		Expect(result.Size()).To(Equal(1))
+
+		// removing the first document of a group
+		result = process(docs[0], -1)
+		entries, err = result.List()
+		Expect(err).NotTo(HaveOccurred())
+		for _, entry := range entries {
+			if entry.Multiplicity > 0 {
+				Expect(entry.Document["first"]).To(Equal("Bob"))
+				Expect(entry.Document["count"]).To(Equal(int64(1)))
+			}
+		}
+
+		// removing the last document of a group
+		result = process(docs[2], -1)
+		Expect(result.TotalSize()).To(Equal(1))
+		entries, err = result.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries[0].Multiplicity).To(Equal(-1))
+		Expect(entries[0].Document["dept"]).To(Equal("Marketing"))
+
+		op.Reset()
+		result = process(docs[1], 1)
+		entries, err = result.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Document["count"]).To(Equal(int64(1)))
+	})
+
+	It("should be consistent with the snapshot aggregation", func() {
+		snapshotOp := NewAggregate(NewFieldExtractor("dept"), aggregates, &FieldsAggregateTransformer{})
+		incOp, ok := IncrementalizeOp(snapshotOp)
+		Expect(ok).To(BeTrue())
+
+		snapshot := NewDocumentZSet()
+		integrated := NewDocumentZSet()
+		for _, step := range []struct {
+			doc   Document
+			count int
+		}{{docs[0], 1}, {docs[2], 2}, {docs[1], 1}, {docs[3], 1}, {docs[2], -1}, {docs[0], -1}, {docs[2], -1}} {
+			delta, err := NewDocumentZSet().AddDocument(step.doc, step.count)
+			Expect(err).NotTo(HaveOccurred())
+
+			out, err := incOp.Process(delta)
+			Expect(err).NotTo(HaveOccurred())
+			integrated, err = integrated.Add(out)
+			Expect(err).NotTo(HaveOccurred())
+
+			snapshot, err = snapshot.Add(delta)
+			Expect(err).NotTo(HaveOccurred())
+			expected, err := snapshotOp.Process(snapshot)
+			Expect(err).NotTo(HaveOccurred())
+
+			// the snapshot may choose a different representative document, compare the aggregates
+			a, err := stripFields(integrated, "amount", "rep", "first")
+			Expect(err).NotTo(HaveOccurred())
+			b, err := stripFields(expected, "amount", "rep", "first")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(a).To(ConsistOf(b))
+		}
+	})
+})
+
+// WindowFieldsTransformer writes the named aggregates and the start of the window into the fields
+// of the same name.
+type WindowFieldsTransformer struct{}
+
+func (t *WindowFieldsTransformer) Transform(doc Document, value any) (Document, error) {
+	res := value.(*WindowResult)
+	for name, v := range res.Aggregates {
+		doc[name] = v
+	}
+	doc["start"] = res.Start.Unix()
+	return doc, nil
+}
+
+func (t *WindowFieldsTransformer) String() string { return "window-fields" }
+
+var _ = Describe("Windowed Aggregate Operations", func() {
+	var (
+		now        time.Time
+		clock      func() time.Time
+		aggregates []Aggregate
+	)
+
+	newDoc := func(key string, ts int64) Document {
+		doc, err := newDocumentFromPairs("key", key, "ts", ts)
+		Expect(err).NotTo(HaveOccurred())
+		return doc
+	}
+
+	BeforeEach(func() {
+		now = time.Unix(25, 0)
+		clock = func() time.Time { return now }
+		aggregates = []Aggregate{{Name: "count", Extractor: NewFieldExtractor("ts"), Func: NewCount()}}
+	})
+
+	It("should assign timestamps to windows", func() {
+		tumbling := NewTumblingWindow(10 * time.Second)
+		Expect(tumbling.Validate()).To(Succeed())
+		Expect(tumbling.Starts(time.Unix(15, 0))).To(Equal([]time.Time{time.Unix(10, 0)}))
+		start, end := tumbling.Current(time.Unix(25, 0))
+		Expect(start).To(Equal(time.Unix(10, 0)))
+		Expect(end).To(Equal(time.Unix(20, 0)))
+
+		sliding := NewSlidingWindow(10*time.Second, 5*time.Second)
+		Expect(sliding.Starts(time.Unix(15, 0))).To(Equal([]time.Time{time.Unix(15, 0), time.Unix(10, 0)}))
+		start, end = sliding.Current(time.Unix(27, 0))
+		Expect(start).To(Equal(time.Unix(15, 0)))
+		Expect(end).To(Equal(time.Unix(25, 0)))
+
+		Expect(NewSlidingWindow(0, time.Second).Validate()).NotTo(Succeed())
+		Expect(NewSlidingWindow(10*time.Second, 3*time.Second).Validate()).NotTo(Succeed())
+	})
+
+	It("should aggregate the last closed window and advance with the clock", func() {
+		op := NewIncrementalWindowedAggregate(NewFieldExtractor("key"), NewFieldExtractor("ts"),
+			NewTumblingWindow(10*time.Second), aggregates, &WindowFieldsTransformer{}, clock)
+		process := func(docs ...Document) []DocumentEntry {
+			delta := NewDocumentZSet()
+			for _, doc := range docs {
+				Expect(delta.AddDocumentMutate(doc, 1)).To(Succeed())
+			}
+			result, err := op.Process(delta)
+			Expect(err).NotTo(HaveOccurred())
+			entries, err := result.List()
+			Expect(err).NotTo(HaveOccurred())
+			return entries
+		}
+
+		entries := process(newDoc("a", 12), newDoc("a", 15), newDoc("b", 5))
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Multiplicity).To(Equal(1))
+		Expect(entries[0].Document["count"]).To(Equal(int64(2)))
+		Expect(entries[0].Document["start"]).To(Equal(int64(10)))
+
+		// documents in the open window do not change the output
+		Expect(process(newDoc("a", 22))).To(BeEmpty())
+
+		// closing the window retracts the output of the previous window
+		now = time.Unix(31, 0)
+		entries = process()
+		Expect(entries).To(HaveLen(2))
+		for _, entry := range entries {
+			if entry.Multiplicity > 0 {
+				Expect(entry.Document["count"]).To(Equal(int64(1)))
+				Expect(entry.Document["start"]).To(Equal(int64(20)))
+			} else {
+				Expect(entry.Document["start"]).To(Equal(int64(10)))
+			}
+		}
+
+		// late documents are ignored
+		Expect(process(newDoc("a", 15))).To(BeEmpty())
+
+		now = time.Unix(45, 0)
+		entries = process()
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Multiplicity).To(Equal(-1))
+
+		op.Reset()
+		Expect(process(newDoc("a", 32))).To(HaveLen(1))
+	})
+
+	It("should be consistent with the snapshot windowed aggregation", func() {
+		snapshotOp := NewWindowedAggregate(NewFieldExtractor("key"), NewFieldExtractor("ts"),
+			NewSlidingWindow(10*time.Second, 5*time.Second), aggregates, &WindowFieldsTransformer{}, clock)
+		incOp, ok := IncrementalizeOp(snapshotOp)
+		Expect(ok).To(BeTrue())
+		Expect(incOp.IsTimeInvariant()).To(BeFalse())
+
+		snapshot := NewDocumentZSet()
+		integrated := NewDocumentZSet()
+		for step := 0; step < 30; step++ {
+			now = time.Unix(int64(20+step*2), 0)
+			delta := NewDocumentZSet()
+			doc := newDoc(fmt.Sprintf("k%d", step%3), int64(18+step*2))
+			mult := 1
+			if step%4 == 3 {
+				// remove a previous document
+				doc, mult = newDoc(fmt.Sprintf("k%d", (step-2)%3), int64(18+(step-2)*2)), -1
+			}
+			Expect(delta.AddDocumentMutate(doc, mult)).To(Succeed())
+
+			out, err := incOp.Process(delta)
+			Expect(err).NotTo(HaveOccurred())
+			integrated, err = integrated.Add(out)
+			Expect(err).NotTo(HaveOccurred())
+
+			snapshot, err = snapshot.Add(delta)
+			Expect(err).NotTo(HaveOccurred())
+			expected, err := snapshotOp.Process(snapshot)
+			Expect(err).NotTo(HaveOccurred())
+
+			a, err := stripFields(integrated, "ts")
+			Expect(err).NotTo(HaveOccurred())
+			b, err := stripFields(expected, "ts")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(a).To(ConsistOf(b), fmt.Sprintf("step %d", step))
+		}
+	})
+})
+
+var _ = Describe("Distinct Operations", func() {
+	var doc1, doc2 Document
+
+	BeforeEach(func() {
+		var err error
+		doc1, err = newDocumentFromPairs("name", "alice")
+		Expect(err).NotTo(HaveOccurred())
+		doc2, err = newDocumentFromPairs("name", "bob")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("Incremental Distinct Functionality", func() {
+		var distinctOp *IncrementalDistinctOp
+
+		BeforeEach(func() {
+			distinctOp = NewIncrementalDistinct()
+		})
+
+		process := func(doc Document, count int) *DocumentZSet {
+			delta, err := NewDocumentZSet().AddDocument(doc, count)
+			Expect(err).NotTo(HaveOccurred())
+			result, err := distinctOp.Process(delta)
+			Expect(err).NotTo(HaveOccurred())
+			return result
+		}
+
+		It("should emit a document only when it enters the set", func() {
+			result := process(doc1, 2)
+			Expect(result.Size()).To(Equal(1))
+			m, err := result.GetMultiplicity(doc1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(1))
+
+			// already in the set
+			result = process(doc1, 1)
+			Expect(result.IsZero()).To(BeTrue())
+		})
+
+		It("should remove a document only when its last copy is removed", func() {
+			process(doc1, 1)
+			process(doc1, 1)
+			process(doc2, 1)
+
+			result := process(doc1, -1)
+			Expect(result.IsZero()).To(BeTrue())
+
+			result = process(doc1, -1)
+			Expect(result.TotalSize()).To(Equal(1))
+			m, err := result.GetMultiplicity(doc1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(-1))
+
+			// re-adding the document brings it back
+			result = process(doc1, 1)
+			m, err = result.GetMultiplicity(doc1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(1))
+		})
+
+		It("should be consistent with the snapshot distinct", func() {
+			snapshot := NewDocumentZSet()
+			integrated := NewDocumentZSet()
+			for _, step := range []struct {
+				doc   Document
+				count int
+			}{{doc1, 1}, {doc2, 2}, {doc1, 1}, {doc2, -2}, {doc1, -1}, {doc2, 1}, {doc1, -1}} {
+				delta, err := NewDocumentZSet().AddDocument(step.doc, step.count)
+				Expect(err).NotTo(HaveOccurred())
+
+				out, err := distinctOp.Process(delta)
+				Expect(err).NotTo(HaveOccurred())
+				integrated, err = integrated.Add(out)
+				Expect(err).NotTo(HaveOccurred())
+
+				snapshot, err = snapshot.Add(delta)
+				Expect(err).NotTo(HaveOccurred())
+				expected, err := snapshot.Distinct()
+				Expect(err).NotTo(HaveOccurred())
+
+				diff, err := integrated.Subtract(expected)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(diff.IsZero()).To(BeTrue())
+			}
+		})
+
+		It("should reset state correctly", func() {
+			process(doc1, 1)
+			distinctOp.Reset()
+			result := process(doc1, 1)
+			Expect(result.Size()).To(Equal(1))
+		})
+	})
+})
+
+// countingEvaluator counts the evaluations of the wrapped evaluator.
+type countingEvaluator struct {
+	Evaluator
+	calls int
+}
+
+func (e *countingEvaluator) Evaluate(doc Document) ([]Document, error) {
+	e.calls++
+	return e.Evaluator.Evaluate(doc)
+}
+
+var _ = Describe("Outer Join Operations", func() {
+	var (
+		inputs             []string
+		evaluator          *FlexibleJoinEvaluator
+		user1, user2       Document
+		project1, project2 Document
+	)
+
+	zset := func(docs ...any) *DocumentZSet {
+		z := NewDocumentZSet()
+		for i := 0; i < len(docs); i += 2 {
+			Expect(z.AddDocumentMutate(docs[i].(Document), docs[i+1].(int))).To(Succeed())
+		}
+		return z
+	}
+
+	unmatched := func(doc Document) Document { return Document{"users": doc} }
+
+	BeforeEach(func() {
+		inputs = []string{"users", "projects"}
+		evaluator = NewFlexibleJoin("id", inputs)
+		user1 = Document{"id": int64(1), "name": "alice"}
+		user2 = Document{"id": int64(2), "name": "bob"}
+		project1 = Document{"id": int64(1), "title": "p1"}
+		project2 = Document{"id": int64(1), "title": "p2"}
+	})
+
+	Context("Snapshot Outer Joins", func() {
+		It("should emit unmatched documents of the first input", func() {
+			users := zset(user1, 1, user2, 1)
+			projects := zset(project1, 1)
+
+			result, err := NewAntiJoin(evaluator, inputs).Process(users, projects)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.UniqueCount()).To(Equal(1))
+			m, err := result.GetMultiplicity(unmatched(user2))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(1))
+
+			result, err = NewLeftJoin(evaluator, inputs).Process(users, projects)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.UniqueCount()).To(Equal(2))
+			m, err = result.GetMultiplicity(unmatched(user2))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(1))
+			contains, err := result.Contains(unmatched(user1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contains).To(BeFalse())
+		})
+
+		It("should preserve the multiplicities of the first input", func() {
+			result, err := NewAntiJoin(evaluator, inputs).Process(zset(user2, 2), NewDocumentZSet())
+			Expect(err).NotTo(HaveOccurred())
+			m, err := result.GetMultiplicity(unmatched(user2))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(2))
+		})
+	})
+
+	Context("Incremental Outer Joins", func() {
+		It("should retract an unmatched document when the first match arrives", func() {
+			antiJoin := NewIncrementalAntiJoin(evaluator, inputs)
+
+			result, err := antiJoin.Process(zset(user1, 1), NewDocumentZSet())
+			Expect(err).NotTo(HaveOccurred())
+			m, err := result.GetMultiplicity(unmatched(user1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(1))
+
+			result, err = antiJoin.Process(NewDocumentZSet(), zset(project1, 1))
+			Expect(err).NotTo(HaveOccurred())
+			m, err = result.GetMultiplicity(unmatched(user1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(-1))
+
+			// a second match does not change the output
+			result, err = antiJoin.Process(NewDocumentZSet(), zset(project2, 1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.IsZero()).To(BeTrue())
+
+			result, err = antiJoin.Process(NewDocumentZSet(), zset(project1, -1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.IsZero()).To(BeTrue())
+
+			// the last match is removed
+			result, err = antiJoin.Process(NewDocumentZSet(), zset(project2, -1))
+			Expect(err).NotTo(HaveOccurred())
+			m, err = result.GetMultiplicity(unmatched(user1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(1))
+
+			// the unmatched document is removed
+			result, err = antiJoin.Process(zset(user1, -1), NewDocumentZSet())
+			Expect(err).NotTo(HaveOccurred())
+			m, err = result.GetMultiplicity(unmatched(user1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(-1))
+		})
+
+		It("should switch between matched and unmatched rows in a left join", func() {
+			leftJoin := NewIncrementalLeftJoin(evaluator, inputs)
+
+			result, err := leftJoin.Process(zset(user1, 1), NewDocumentZSet())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.UniqueCount()).To(Equal(1))
+
+			result, err = leftJoin.Process(NewDocumentZSet(), zset(project1, 1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.UniqueCount()).To(Equal(1)) // the joined row
+			Expect(result.TotalSize()).To(Equal(2))   // the joined row and the retraction
+			m, err := result.GetMultiplicity(unmatched(user1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(-1))
+		})
+
+		It("should be consistent with the snapshot outer joins", func() {
+			leftJoin := NewIncrementalLeftJoin(evaluator, inputs)
+			antiJoin := NewIncrementalAntiJoin(evaluator, inputs)
+			users, projects := NewDocumentZSet(), NewDocumentZSet()
+			leftOut, antiOut := NewDocumentZSet(), NewDocumentZSet()
+
+			for i, step := range []struct {
+				users, projects *DocumentZSet
+			}{
+				{zset(user1, 1), NewDocumentZSet()},
+				{zset(user2, 1), zset(project1, 1)},
+				{NewDocumentZSet(), zset(project2, 1)},
+				{zset(user1, 1), zset(project1, -1)},
+				{zset(user1, -1), zset(project2, -1)},
+				{zset(user2, -1), zset(project1, 1, project2, 1)},
+				{zset(user1, -1), NewDocumentZSet()},
+			} {
+				var err error
+				users, err = users.Add(step.users)
+				Expect(err).NotTo(HaveOccurred())
+				projects, err = projects.Add(step.projects)
+				Expect(err).NotTo(HaveOccurred())
+
+				for _, tc := range []struct {
+					incremental Operator
+					snapshot    Operator
+					out         **DocumentZSet
+				}{
+					{leftJoin, NewLeftJoin(evaluator, inputs), &leftOut},
+					{antiJoin, NewAntiJoin(evaluator, inputs), &antiOut},
+				} {
+					delta, err := tc.incremental.Process(step.users, step.projects)
+					Expect(err).NotTo(HaveOccurred())
+					*tc.out, err = (*tc.out).Add(delta)
+					Expect(err).NotTo(HaveOccurred())
+
+					expected, err := tc.snapshot.Process(users, projects)
+					Expect(err).NotTo(HaveOccurred())
+					diff, err := (*tc.out).Subtract(expected)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(diff.IsZero()).To(BeTrue(), fmt.Sprintf("step %d: %s", i, diff.String()))
+				}
+			}
+		})
+
+		It("should evaluate the join condition only on new documents", func() {
+			counter := &countingEvaluator{Evaluator: evaluator}
+			antiJoin := NewIncrementalAntiJoin(counter, inputs)
+
+			users := NewDocumentZSet()
+			for i := 0; i < 10; i++ {
+				Expect(users.AddDocumentMutate(Document{"id": int64(i)}, 1)).To(Succeed())
+			}
+			_, err := antiJoin.Process(users, NewDocumentZSet())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counter.calls).To(Equal(0))
+
+			// a new project is matched against all the users
+			result, err := antiJoin.Process(NewDocumentZSet(), zset(project1, 1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counter.calls).To(Equal(10))
+			m, err := result.GetMultiplicity(unmatched(Document{"id": int64(1)}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(-1))
+
+			// changing the multiplicity of and removing the project needs no evaluation
+			result, err = antiJoin.Process(NewDocumentZSet(), zset(project1, 1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.IsZero()).To(BeTrue())
+			result, err = antiJoin.Process(NewDocumentZSet(), zset(project1, -2))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counter.calls).To(Equal(10))
+			m, err = result.GetMultiplicity(unmatched(Document{"id": int64(1)}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(1))
+
+			// removing a user needs no evaluation either
+			_, err = antiJoin.Process(zset(Document{"id": int64(1)}, -1), NewDocumentZSet())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counter.calls).To(Equal(10))
+		})
+
+		It("should be consistent with the snapshot anti-join on three inputs", func() {
+			inputs := []string{"users", "projects", "teams"}
+			evaluator := NewFlexibleJoin("id", inputs)
+			antiJoin := NewIncrementalAntiJoin(evaluator, inputs)
+			team1, team2 := Document{"team": "a"}, Document{"team": "b"}
+			users, projects, teams := NewDocumentZSet(), NewDocumentZSet(), NewDocumentZSet()
+			out := NewDocumentZSet()
+
+			for i, step := range []struct {
+				users, projects, teams *DocumentZSet
+			}{
+				{zset(user1, 1, user2, 1), zset(project1, 1), NewDocumentZSet()},
+				{NewDocumentZSet(), NewDocumentZSet(), zset(team1, 1)},
+				{NewDocumentZSet(), zset(project2, 1), zset(team2, 1)},
+				{NewDocumentZSet(), zset(project1, -1), zset(team1, -1)},
+				{zset(user1, -1), NewDocumentZSet(), zset(team2, 1)},
+				{zset(user1, 1), zset(project2, -1), NewDocumentZSet()},
+				{NewDocumentZSet(), zset(project1, 1), zset(team2, -2)},
+				{NewDocumentZSet(), NewDocumentZSet(), zset(team1, 1)},
+			} {
+				var err error
+				users, err = users.Add(step.users)
+				Expect(err).NotTo(HaveOccurred())
+				projects, err = projects.Add(step.projects)
+				Expect(err).NotTo(HaveOccurred())
+				teams, err = teams.Add(step.teams)
+				Expect(err).NotTo(HaveOccurred())
+
+				delta, err := antiJoin.Process(step.users, step.projects, step.teams)
+				Expect(err).NotTo(HaveOccurred())
+				out, err = out.Add(delta)
+				Expect(err).NotTo(HaveOccurred())
+
+				expected, err := NewAntiJoin(evaluator, inputs).Process(users, projects, teams)
+				Expect(err).NotTo(HaveOccurred())
+				diff, err := out.Subtract(expected)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(diff.IsZero()).To(BeTrue(), fmt.Sprintf("step %d: %s", i, diff.String()))
+			}
+		})
+
+		It("should reset state correctly", func() {
+			antiJoin := NewIncrementalAntiJoin(evaluator, inputs)
+			_, err := antiJoin.Process(zset(user1, 1), zset(project1, 1))
+			Expect(err).NotTo(HaveOccurred())
+
+			antiJoin.Reset()
+			result, err := antiJoin.Process(zset(user1, 1), NewDocumentZSet())
+			Expect(err).NotTo(HaveOccurred())
+			m, err := result.GetMultiplicity(unmatched(user1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(1))
+
+			leftJoin := NewIncrementalLeftJoin(evaluator, inputs)
+			_, err = leftJoin.Process(zset(user1, 1), zset(project1, 1))
+			Expect(err).NotTo(HaveOccurred())
+
+			leftJoin.Reset()
+			result, err = leftJoin.Process(NewDocumentZSet(), zset(project2, 1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.IsZero()).To(BeTrue())
+		})
+	})
+})
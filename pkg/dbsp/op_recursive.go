@@ -0,0 +1,291 @@
+package dbsp
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/go-logr/logr"
+)
+
+// DefaultMaxFixpointIterations is the default bound on the number of iterations of a fixpoint.
+const DefaultMaxFixpointIterations = 100
+
+// fixpointBody is the body F(X, R) of a recursive query, kept both in snapshot and in incremental
+// form. The body is a DAG with two inputs: the input X of the recursion and the recursive relation
+// R computed so far.
+type fixpointBody struct {
+	snapshot, incremental *DAG
+	input, self           string
+	maxIterations         int
+}
+
+func newFixpointBody(body *DAG, input, self string, maxIterations int) (*fixpointBody, error) {
+	inputs := body.Inputs()
+	if len(inputs) != 2 || !slices.Contains(inputs, input) || !slices.Contains(inputs, self) {
+		return nil, fmt.Errorf("fixpoint body must have the inputs %q and %q, got %v", input, self, inputs)
+	}
+	if err := body.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid fixpoint body: %w", err)
+	}
+
+	snapshot, err := ToSnapshotDAG(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert fixpoint body to snapshot mode: %w", err)
+	}
+
+	incremental, err := ToIncrementalDAG(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert fixpoint body to incremental mode: %w", err)
+	}
+	if err := NewLinearChainRewriteEngine().OptimizeDAG(incremental); err != nil {
+		return nil, fmt.Errorf("failed to optimize fixpoint body: %w", err)
+	}
+
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxFixpointIterations
+	}
+
+	return &fixpointBody{
+		snapshot:      snapshot,
+		incremental:   incremental,
+		input:         input,
+		self:          self,
+		maxIterations: maxIterations,
+	}, nil
+}
+
+func (b *fixpointBody) String() string {
+	return fmt.Sprintf("fix(%s)", b.snapshot.String())
+}
+
+// FixpointOp computes the least fixpoint of a recursive query R = distinct(F(X, R)), where X is
+// the input of the op and the body F is a DAG that receives X and the recursive relation R as
+// inputs. The op is evaluated by iterating the body on the output of the previous iteration,
+// passed back through a z^(-1) delay, until the output no longer changes. The body should be
+// monotone in R, e.g., made of selections, projections and joins, for the iteration to converge;
+// the op fails if no fixpoint is reached in a bounded number of iterations.
+type FixpointOp struct {
+	BaseOp
+	body     *fixpointBody
+	executor *DAGExecutor
+	feedback *DelayOp
+}
+
+// NewFixpoint creates a new snapshot fixpoint op. The input and self arguments are the names of
+// the inputs of the body that receive the input of the op and the recursive relation. If
+// maxIterations is not positive, DefaultMaxFixpointIterations is used.
+func NewFixpoint(body *DAG, input, self string, maxIterations int) (*FixpointOp, error) {
+	b, err := newFixpointBody(body, input, self, maxIterations)
+	if err != nil {
+		return nil, err
+	}
+	return newFixpointOp(b), nil
+}
+
+func newFixpointOp(body *fixpointBody) *FixpointOp {
+	return &FixpointOp{
+		BaseOp:   NewBaseOp(body.String(), 1),
+		body:     body,
+		feedback: NewDelay(),
+	}
+}
+
+func (op *FixpointOp) OpType() OperatorType              { return OpTypeNonLinear }
+func (op *FixpointOp) IsTimeInvariant() bool             { return true }
+func (op *FixpointOp) HasZeroPreservationProperty() bool { return true }
+
+// Process evaluates the op.
+func (op *FixpointOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := op.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	if op.executor == nil {
+		executor, err := NewDAGExecutor(op.body.snapshot, logr.Discard())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fixpoint executor: %w", err)
+		}
+		op.executor = executor
+	}
+
+	// The iterations run in a nested time domain: restart the feedback loop from R = 0.
+	op.feedback.Reset()
+	for i := 0; i < op.body.maxIterations; i++ {
+		self := op.feedback.Output()
+		out, err := op.executor.Process(DeltaZSet{op.body.input: inputs[0], op.body.self: self})
+		if err != nil {
+			return nil, fmt.Errorf("fixpoint iteration %d failed: %w", i, err)
+		}
+
+		next, err := out.Distinct()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := op.feedback.Process(next); err != nil {
+			return nil, err
+		}
+
+		diff, err := next.Subtract(self)
+		if err != nil {
+			return nil, err
+		}
+		if diff.IsZero() {
+			return next, nil
+		}
+	}
+
+	return nil, fmt.Errorf("fixpoint did not converge after %d iterations", op.body.maxIterations)
+}
+
+// IncrementalFixpointOp is the incremental version of the fixpoint op. Insertions into the input
+// are processed by semi-naive evaluation: the incremental body is fed only the new elements of the
+// recursive relation found in the previous iteration, until no new elements are found. Deletions,
+// and bodies that turn out not to be monotone, fall back to recomputing the fixpoint from the
+// integrated input.
+type IncrementalFixpointOp struct {
+	BaseOp
+	body     *fixpointBody
+	executor *DAGExecutor  // incremental body, its state is the integrated X and R
+	snapshot *FixpointOp   // for recomputing the fixpoint
+	input    *DocumentZSet // integrated input
+	state    *DocumentZSet // current fixpoint
+}
+
+// NewIncrementalFixpoint creates a new incremental fixpoint op.
+func NewIncrementalFixpoint(body *DAG, input, self string, maxIterations int) (*IncrementalFixpointOp, error) {
+	b, err := newFixpointBody(body, input, self, maxIterations)
+	if err != nil {
+		return nil, err
+	}
+	return newIncrementalFixpointOp(b), nil
+}
+
+func newIncrementalFixpointOp(body *fixpointBody) *IncrementalFixpointOp {
+	return &IncrementalFixpointOp{
+		BaseOp:   NewBaseOp(body.String()+"^Δ", 1),
+		body:     body,
+		snapshot: newFixpointOp(body),
+		input:    NewDocumentZSet(),
+		state:    NewDocumentZSet(),
+	}
+}
+
+func (op *IncrementalFixpointOp) OpType() OperatorType              { return OpTypeNonLinear }
+func (op *IncrementalFixpointOp) IsTimeInvariant() bool             { return true }
+func (op *IncrementalFixpointOp) HasZeroPreservationProperty() bool { return true }
+
+// Process evaluates the op.
+func (op *IncrementalFixpointOp) Process(inputs ...*DocumentZSet) (*DocumentZSet, error) {
+	if err := op.validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	if op.executor == nil {
+		executor, err := NewDAGExecutor(op.body.incremental, logr.Discard())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fixpoint executor: %w", err)
+		}
+		op.executor = executor
+	}
+
+	delta := inputs[0]
+	input, err := op.input.Add(delta)
+	if err != nil {
+		return nil, err
+	}
+	op.input = input
+
+	// Feed the input change to the body and iterate on the new elements.
+	changes, err := op.step(delta, NewDocumentZSet())
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewDocumentZSet()
+	for i := 0; ; i++ {
+		if hasNegative(delta) || hasNegative(changes) {
+			// Not monotone: recompute.
+			diff, err := op.recompute()
+			if err != nil {
+				return nil, err
+			}
+			return result.Add(diff)
+		}
+
+		frontier := NewDocumentZSet()
+		for key := range changes.counts {
+			if changes.counts[key] > 0 && op.state.counts[key] <= 0 {
+				if err := frontier.AddDocumentMutate(DeepCopyDocument(changes.docs[key]), 1); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if frontier.IsZero() {
+			return result, nil
+		}
+		if i >= op.body.maxIterations {
+			return nil, fmt.Errorf("fixpoint did not converge after %d iterations", op.body.maxIterations)
+		}
+
+		if op.state, err = op.state.Add(frontier.DeepCopy()); err != nil {
+			return nil, err
+		}
+		if result, err = result.Add(frontier); err != nil {
+			return nil, err
+		}
+
+		if changes, err = op.step(NewDocumentZSet(), frontier.DeepCopy()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// step feeds a change of the input and a change of the recursive relation to the incremental
+// body.
+func (op *IncrementalFixpointOp) step(input, self *DocumentZSet) (*DocumentZSet, error) {
+	out, err := op.executor.Process(DeltaZSet{op.body.input: input, op.body.self: self})
+	if err != nil {
+		return nil, fmt.Errorf("fixpoint iteration failed: %w", err)
+	}
+	return out, nil
+}
+
+// recompute evaluates the fixpoint on the integrated input and returns the change of the fixpoint.
+func (op *IncrementalFixpointOp) recompute() (*DocumentZSet, error) {
+	state, err := op.snapshot.Process(op.input)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := state.Subtract(op.state)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the state of the incremental body consistent with the new fixpoint.
+	if _, err := op.step(NewDocumentZSet(), diff.DeepCopy()); err != nil {
+		return nil, err
+	}
+	op.state = state.DeepCopy()
+
+	return diff, nil
+}
+
+// Reset state (useful for testing or restarting computation).
+func (op *IncrementalFixpointOp) Reset() {
+	if op.executor != nil {
+		op.executor.Reset()
+	}
+	op.input = NewDocumentZSet()
+	op.state = NewDocumentZSet()
+}
+
+// hasNegative checks whether a Z-set has an element with a negative multiplicity.
+func hasNegative(zset *DocumentZSet) bool {
+	for _, count := range zset.counts {
+		if count < 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package dbsp
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// PathJoinEvaluator extends the paths with the edges that start where the path ends.
+type PathJoinEvaluator struct{}
+
+func (e *PathJoinEvaluator) Evaluate(doc Document) ([]Document, error) {
+	path, ok := doc["paths"].(Document)
+	if !ok {
+		return nil, fmt.Errorf("missing paths")
+	}
+	edge, ok := doc["edges"].(Document)
+	if !ok {
+		return nil, fmt.Errorf("missing edges")
+	}
+	if path["to"] != edge["from"] {
+		return []Document{}, nil
+	}
+	return []Document{{"from": path["from"], "to": edge["to"]}}, nil
+}
+
+func (e *PathJoinEvaluator) String() string { return "path-join" }
+
+// CounterEvaluator increments a counter.
+type CounterEvaluator struct{}
+
+func (e *CounterEvaluator) Evaluate(doc Document) ([]Document, error) {
+	return []Document{{"n": doc["n"].(int64) + 1}}, nil
+}
+
+func (e *CounterEvaluator) String() string { return "counter" }
+
+// newClosureBody returns the body of the transitive closure of the edges:
+// paths = edges + π(paths ⋈ edges).
+func newClosureBody() *DAG {
+	dag := NewDAG()
+	Expect(dag.AddInput("edges")).To(Succeed())
+	Expect(dag.AddInput("paths")).To(Succeed())
+
+	base := NewChainGraph()
+	base.AddInput(NewInput("edges"))
+	Expect(dag.AddNode("base", base, nil)).To(Succeed())
+
+	step := NewChainGraph()
+	step.AddInput(NewInput("paths"))
+	step.AddInput(NewInput("edges"))
+	step.SetJoin(NewJoin(&PathJoinEvaluator{}, []string{"paths", "edges"}))
+	Expect(dag.AddNode("step", step, nil)).To(Succeed())
+
+	output := NewChainGraph()
+	output.AddInput(NewInput("base"))
+	output.AddInput(NewInput("step"))
+	output.SetJoin(NewNaryAdd(2))
+	Expect(dag.AddNode("output", output, nil)).To(Succeed())
+
+	return dag
+}
+
+func edge(from, to string) Document { return Document{"from": from, "to": to} }
+
+var _ = Describe("Fixpoint Operations", func() {
+	It("should compute the transitive closure", func() {
+		op, err := NewFixpoint(newClosureBody(), "edges", "paths", 0)
+		Expect(err).NotTo(HaveOccurred())
+
+		edges, err := FromDocuments([]Document{edge("a", "b"), edge("b", "c"), edge("c", "d")})
+		Expect(err).NotTo(HaveOccurred())
+		res, err := op.Process(edges)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Size()).To(Equal(6))
+		for _, path := range []Document{edge("a", "d"), edge("b", "d"), edge("a", "c")} {
+			m, err := res.GetMultiplicity(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(1))
+		}
+
+		// cycles converge
+		Expect(edges.AddDocumentMutate(edge("d", "a"), 1)).To(Succeed())
+		res, err = op.Process(edges)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Size()).To(Equal(16))
+	})
+
+	It("should bound the number of iterations", func() {
+		dag := NewDAG()
+		Expect(dag.AddInput("input")).To(Succeed())
+		Expect(dag.AddInput("self")).To(Succeed())
+		base := NewChainGraph()
+		base.AddInput(NewInput("input"))
+		Expect(dag.AddNode("base", base, nil)).To(Succeed())
+		step := NewChainGraph()
+		step.AddInput(NewInput("self"))
+		step.AddToChain(NewProjection(&CounterEvaluator{}))
+		Expect(dag.AddNode("step", step, nil)).To(Succeed())
+		output := NewChainGraph()
+		output.AddInput(NewInput("base"))
+		output.AddInput(NewInput("step"))
+		output.SetJoin(NewNaryAdd(2))
+		Expect(dag.AddNode("output", output, nil)).To(Succeed())
+
+		input, err := SingletonZSet(Document{"n": int64(0)})
+		Expect(err).NotTo(HaveOccurred())
+
+		op, err := NewFixpoint(dag, "input", "self", 5)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = op.Process(input)
+		Expect(err).To(MatchError(ContainSubstring("did not converge after 5 iterations")))
+
+		incOp, err := NewIncrementalFixpoint(dag, "input", "self", 5)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = incOp.Process(input)
+		Expect(err).To(MatchError(ContainSubstring("did not converge after 5 iterations")))
+	})
+
+	It("should reject invalid bodies", func() {
+		_, err := NewFixpoint(newClosureBody(), "edges", "self", 0)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should evaluate incrementally consistently with the snapshot fixpoint", func() {
+		snapshotOp, err := NewFixpoint(newClosureBody(), "edges", "paths", 0)
+		Expect(err).NotTo(HaveOccurred())
+		incOp, ok := IncrementalizeOp(snapshotOp)
+		Expect(ok).To(BeTrue())
+
+		steps := []struct {
+			edge Document
+			mult int
+		}{
+			{edge("a", "b"), 1},
+			{edge("c", "d"), 1},
+			{edge("b", "c"), 1},
+			{edge("d", "a"), 1},
+			{edge("b", "c"), -1},
+			{edge("x", "y"), 1},
+			{edge("d", "a"), -1},
+			{edge("b", "c"), 1},
+			{edge("a", "b"), -1},
+		}
+
+		edges := NewDocumentZSet()
+		output := NewDocumentZSet()
+		for i, step := range steps {
+			delta, err := SingletonZSet(step.edge)
+			Expect(err).NotTo(HaveOccurred())
+			if step.mult < 0 {
+				delta, err = NewDocumentZSet().Subtract(delta)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			edges, err = edges.Add(delta)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := incOp.Process(delta)
+			Expect(err).NotTo(HaveOccurred())
+			output, err = output.Add(res)
+			Expect(err).NotTo(HaveOccurred())
+
+			expected, err := snapshotOp.Process(edges)
+			Expect(err).NotTo(HaveOccurred())
+			diff, err := output.Subtract(expected)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff.IsZero()).To(BeTrue(), fmt.Sprintf("step %d: %s", i, diff.String()))
+		}
+	})
+
+	It("should convert between snapshot and incremental graphs", func() {
+		op, err := NewIncrementalFixpoint(newClosureBody(), "edges", "paths", 0)
+		Expect(err).NotTo(HaveOccurred())
+		graph := NewChainGraph()
+		graph.AddInput(NewInput("edges"))
+		graph.AddToChain(op)
+
+		snapshot, err := ToSnapshotGraph(graph)
+		Expect(err).NotTo(HaveOccurred())
+		_, ok := snapshot.GetChain()[0].(*FixpointOp)
+		Expect(ok).To(BeTrue())
+
+		incremental, err := ToIncrementalGraph(snapshot)
+		Expect(err).NotTo(HaveOccurred())
+		_, ok = incremental.GetChain()[0].(*IncrementalFixpointOp)
+		Expect(ok).To(BeTrue())
+	})
+})
@@ -4,22 +4,36 @@ import (
 	"fmt"
 )
 
-// LinearChainRewriteEngine rewrites a LinearChainGraph, applying the DBSP rewriter rules.
+// DefaultMaxRewriteIterations is the default bound on the number of rewrites applied to a graph.
+const DefaultMaxRewriteIterations = 100
+
+// LinearChainRewriteEngine rewrites a LinearChainGraph, applying the DBSP rewriter rules. Rules
+// that produce alternative plans, see CostBasedRule, are applied only if the cost model estimates
+// the rewritten graph to be cheaper, based on the cardinality estimates set in the statistics.
 type LinearChainRewriteEngine struct {
-	rules []LinearChainRule
+	rules         []LinearChainRule
+	costModel     CostModel
+	stats         Statistics
+	maxIterations int
 }
 
 // NewLinearChainRewriteEngine creates a new LinearChainRewriteEngine.
 func NewLinearChainRewriteEngine() *LinearChainRewriteEngine {
 	re := &LinearChainRewriteEngine{
-		rules: make([]LinearChainRule, 0),
+		rules:         make([]LinearChainRule, 0),
+		costModel:     NewDefaultCostModel(),
+		stats:         Statistics{},
+		maxIterations: DefaultMaxRewriteIterations,
 	}
 
 	// Add rules in order of application priority.
+	re.AddRule(&HashJoinRule{})
 	re.AddRule(&JoinIncrementalizationRule{})
 	re.AddRule(&LinearChainIncrementalizationRule{})
 	re.AddRule(&IntegrationDifferentiationEliminationRule{})
 	re.AddRule(&DistinctOptimizationRule{})
+	re.AddRule(&SelectionPushdownRule{})
+	re.AddRule(&ProjectionPushdownRule{})
 	re.AddRule(&LinearOperatorFusionRule{})
 
 	return re
@@ -32,12 +46,38 @@ type LinearChainRule interface {
 	Apply(graph *ChainGraph) error
 }
 
-// AddRule adds a rule to the rewrite engine.
+// CostBasedRule is an optional interface for rules that rewrite a graph into an equivalent plan
+// that is not necessarily cheaper. Such rules are applied only if the cost model estimates the
+// rewritten graph to be cheaper than the original one.
+type CostBasedRule interface {
+	LinearChainRule
+	// IsCostBased returns true if the rewrite is subject to the cost model.
+	IsCostBased() bool
+}
+
+// AddRule adds a rule to the rewrite engine. Rules are tried in the order they were added.
 func (re *LinearChainRewriteEngine) AddRule(rule LinearChainRule) {
 	re.rules = append(re.rules, rule)
 }
 
-// Optimize applies all rules until reaching a fixpoint.
+// SetCostModel sets the cost model. A nil cost model applies all rules unconditionally.
+func (re *LinearChainRewriteEngine) SetCostModel(costModel CostModel) {
+	re.costModel = costModel
+}
+
+// SetStatistics sets the cardinality estimates of the inputs for the cost model, e.g., as
+// obtained from the Statistics of an executor running the graph.
+func (re *LinearChainRewriteEngine) SetStatistics(stats Statistics) {
+	re.stats = stats
+}
+
+// SetMaxIterations sets the maximum number of rewrites applied to a graph.
+func (re *LinearChainRewriteEngine) SetMaxIterations(maxIterations int) {
+	re.maxIterations = maxIterations
+}
+
+// Optimize applies all rules until reaching a fixpoint. The applied rules and the estimated cost
+// of the resultant plan are recorded in the graph, see Executor.GetExecutionPlan.
 func (re *LinearChainRewriteEngine) Optimize(graph *ChainGraph) error {
 	if err := graph.Validate(); err != nil {
 		return fmt.Errorf("invalid graph: %w", err)
@@ -45,33 +85,140 @@ func (re *LinearChainRewriteEngine) Optimize(graph *ChainGraph) error {
 
 	changed := true
 	iterations := 0
-	maxIterations := 20
 
-	for changed && iterations < maxIterations {
+	for changed && iterations < re.maxIterations {
 		changed = false
 		iterations++
 
 		for _, rule := range re.rules {
-			if rule.CanApply(graph) {
-				if err := rule.Apply(graph); err != nil {
-					return fmt.Errorf("rule %s failed: %w", rule.Name(), err)
-				}
+			if !rule.CanApply(graph) {
+				continue
+			}
 
+			ok, err := re.apply(rule, graph)
+			if err != nil {
+				return fmt.Errorf("rule %s failed: %w", rule.Name(), err)
+			}
+			if ok {
 				changed = true
 				break // Apply one rule at a time
 			}
 		}
 	}
 
-	if iterations >= maxIterations {
-		return fmt.Errorf("rewrite engine did not converge after %d iterations", maxIterations)
+	if changed {
+		return fmt.Errorf("rewrite engine did not converge after %d iterations", re.maxIterations)
+	}
+
+	if re.costModel != nil {
+		graph.cost = re.costModel.Cost(graph, re.stats)
 	}
 
-	// fmt.Printf("Linear chain optimization converged after %d iterations\n", iterations)
 	return nil
 }
 
-// Rule 1: Convert N-ary join to incremental version.
+// apply applies a rule to the graph. Cost-based rules are applied on a copy of the graph first,
+// and the copy replaces the graph only if it is cheaper. Returns false if the rule was rejected.
+func (re *LinearChainRewriteEngine) apply(rule LinearChainRule, graph *ChainGraph) (bool, error) {
+	if r, ok := rule.(CostBasedRule); !ok || !r.IsCostBased() || re.costModel == nil {
+		if err := rule.Apply(graph); err != nil {
+			return false, err
+		}
+		graph.rewrites = append(graph.rewrites, rule.Name())
+		return true, nil
+	}
+
+	candidate := graph.clone()
+	if err := rule.Apply(candidate); err != nil {
+		return false, err
+	}
+	if re.costModel.Cost(candidate, re.stats) >= re.costModel.Cost(graph, re.stats) {
+		return false, nil
+	}
+
+	candidate.rewrites = append(candidate.rewrites, rule.Name())
+	*graph = *candidate
+	return true, nil
+}
+
+// OptimizeDAG optimizes the graph of each node of a DAG.
+func (re *LinearChainRewriteEngine) OptimizeDAG(dag *DAG) error {
+	for _, name := range dag.order {
+		if err := re.Optimize(dag.nodes[name].Graph); err != nil {
+			return fmt.Errorf("node %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Rule 0: Convert equi-joins to hash joins. The rule is cost-based: for very small inputs a
+// nested-loop join may be cheaper than building the hash indexes.
+type HashJoinRule struct{}
+
+func (r *HashJoinRule) Name() string {
+	return "HashJoin"
+}
+
+func (r *HashJoinRule) IsCostBased() bool {
+	return true
+}
+
+func (r *HashJoinRule) CanApply(graph *ChainGraph) bool {
+	if graph.joinNode == "" {
+		return false
+	}
+
+	_, ok := toHashJoin(graph.nodes[graph.joinNode].Op)
+	return ok
+}
+
+func (r *HashJoinRule) Apply(graph *ChainGraph) error {
+	hashJoinOp, ok := toHashJoin(graph.nodes[graph.joinNode].Op)
+	if !ok {
+		return fmt.Errorf("join op %s is not an equi-join", graph.nodes[graph.joinNode].Op.id())
+	}
+
+	graph.nodes[graph.joinNode].Op = hashJoinOp
+
+	return nil
+}
+
+// toHashJoin converts an inner join into a hash join, provided that the join evaluator implements
+// the JoinKeyEvaluator interface and the join predicate is an equi-join. Snapshot joins are
+// converted into a snapshot hash join and incremental joins into an incremental hash join.
+func toHashJoin(op Operator) (Operator, bool) {
+	var eval Evaluator
+	var inputs []string
+	incremental := false
+	switch o := op.(type) {
+	case *JoinOp:
+		eval, inputs = o.eval, o.inputs
+	case *BinaryJoinOp:
+		eval, inputs = o.eval, o.inputs
+	case *IncrementalJoinOp:
+		eval, inputs, incremental = o.eval, o.inputs, true
+	case *IncrementalBinaryJoinOp:
+		eval, inputs, incremental = o.eval, o.inputs, true
+	default:
+		return nil, false
+	}
+
+	keyEval, ok := eval.(JoinKeyEvaluator)
+	if !ok {
+		return nil, false
+	}
+	keys, ok := keyEval.JoinKeys(inputs)
+	if !ok || len(keys) != len(inputs) {
+		return nil, false
+	}
+
+	if incremental {
+		return NewIncrementalHashJoin(eval, inputs, keys), true
+	}
+	return NewHashJoin(eval, inputs, keys), true
+}
+
+// Rule 1: Convert N-ary join (inner, left or anti-join) to incremental version.
 type JoinIncrementalizationRule struct{}
 
 func (r *JoinIncrementalizationRule) Name() string {
@@ -85,7 +232,7 @@ func (r *JoinIncrementalizationRule) CanApply(graph *ChainGraph) bool {
 
 	joinOp := graph.nodes[graph.joinNode].Op
 	_, canIncrement := IncrementalizeOp(joinOp)
-	return canIncrement && joinOp.OpType() == OpTypeBilinear
+	return canIncrement
 }
 
 func (r *JoinIncrementalizationRule) Apply(graph *ChainGraph) error {
@@ -107,8 +254,11 @@ func (r *LinearChainIncrementalizationRule) Name() string {
 
 func (r *LinearChainIncrementalizationRule) CanApply(graph *ChainGraph) bool {
 	// Check if we have any non-incremental operations that can be incrementalized
-	for _, nodeID := range graph.chain {
+	for i, nodeID := range graph.chain {
 		node := graph.nodes[nodeID]
+		if isIntegratedDistinct(graph, i) {
+			continue
+		}
 		if _, canInc := IncrementalizeOp(node.Op); canInc {
 			return true
 		}
@@ -118,9 +268,13 @@ func (r *LinearChainIncrementalizationRule) CanApply(graph *ChainGraph) bool {
 
 func (r *LinearChainIncrementalizationRule) Apply(graph *ChainGraph) error {
 	// Process each operation in the chain
-	for _, nodeID := range graph.chain {
+	for i, nodeID := range graph.chain {
 		node := graph.nodes[nodeID]
 
+		if isIntegratedDistinct(graph, i) {
+			continue
+		}
+
 		if incOp, canInc := IncrementalizeOp(node.Op); canInc {
 			// Replace with incremental version
 			node.Op = incOp
@@ -131,6 +285,19 @@ func (r *LinearChainIncrementalizationRule) Apply(graph *ChainGraph) error {
 	return nil
 }
 
+// isIntegratedDistinct checks whether the chain element at index i is a snapshot distinct enclosed
+// in an I->distinct->D sequence. This is already the incremental version of distinct (D ∘ distinct ∘
+// I) so it must not be incrementalized again.
+func isIntegratedDistinct(graph *ChainGraph, i int) bool {
+	if i == 0 || i >= len(graph.chain)-1 {
+		return false
+	}
+	_, isI := graph.nodes[graph.chain[i-1]].Op.(*IntegratorOp)
+	_, isDistinct := graph.nodes[graph.chain[i]].Op.(*DistinctOp)
+	_, isD := graph.nodes[graph.chain[i+1]].Op.(*DifferentiatorOp)
+	return isI && isDistinct && isD
+}
+
 // Rule 3: Remove I->D pairs (they cancel out).
 type IntegrationDifferentiationEliminationRule struct{}
 
@@ -209,10 +376,7 @@ func (r *DistinctOptimizationRule) CanApply(graph *ChainGraph) bool {
 		op1 := graph.nodes[graph.chain[i]].Op
 		op2 := graph.nodes[graph.chain[i+1]].Op
 
-		_, isDistinct1 := op1.(*DistinctOp)
-		_, isDistinct2 := op2.(*DistinctOp)
-
-		if isDistinct1 && isDistinct2 {
+		if isDistinctPair(op1, op2) {
 			return true
 		}
 	}
@@ -228,10 +392,7 @@ func (r *DistinctOptimizationRule) Apply(graph *ChainGraph) error {
 			op1 := graph.nodes[graph.chain[i]].Op
 			op2 := graph.nodes[graph.chain[i+1]].Op
 
-			_, isDistinct1 := op1.(*DistinctOp)
-			_, isDistinct2 := op2.(*DistinctOp)
-
-			if isDistinct1 && isDistinct2 {
+			if isDistinctPair(op1, op2) {
 				// Keep only the second distinct (idempotent)
 				delete(graph.nodes, graph.chain[i])
 				newChain = append(newChain, graph.chain[i+1])
@@ -255,7 +416,244 @@ func (r *DistinctOptimizationRule) Apply(graph *ChainGraph) error {
 	return nil
 }
 
-// Rule 5: Fuse adjacent linear operations for efficiency.
+// isDistinctPair checks whether two ops are both snapshot or both incremental distinct ops. Distinct
+// is idempotent in both modes: distinct^Δ ∘ distinct^Δ = D ∘ distinct ∘ distinct ∘ I = distinct^Δ.
+func isDistinctPair(op1, op2 Operator) bool {
+	switch op1.(type) {
+	case *DistinctOp:
+		_, ok := op2.(*DistinctOp)
+		return ok
+	case *IncrementalDistinctOp:
+		_, ok := op2.(*IncrementalDistinctOp)
+		return ok
+	default:
+		return false
+	}
+}
+
+// Rule 5: Push selections that refer to a single input of an inner join below the join.
+// Selecting first reduces the number of documents the join has to process and store.
+type SelectionPushdownRule struct{}
+
+func (r *SelectionPushdownRule) Name() string {
+	return "SelectionPushdown"
+}
+
+func (r *SelectionPushdownRule) IsCostBased() bool {
+	return true
+}
+
+func (r *SelectionPushdownRule) CanApply(graph *ChainGraph) bool {
+	_, _, ok := r.match(graph)
+	return ok
+}
+
+func (r *SelectionPushdownRule) Apply(graph *ChainGraph) error {
+	sel, input, ok := r.match(graph)
+	if !ok {
+		return fmt.Errorf("no selection to push down")
+	}
+
+	// Move the selection node from the chain to the input chain.
+	nodeID := graph.chain[0]
+	graph.nodes[nodeID].Op = NewSelection(&inputPredicate{eval: sel.eval, input: input})
+	graph.chain = graph.chain[1:]
+	inputID := graph.getInputID(input)
+	graph.inputChains[inputID] = append(graph.inputChains[inputID], nodeID)
+	updateOutput(graph)
+
+	return nil
+}
+
+// match returns the selection following the join and the join input it refers to.
+func (r *SelectionPushdownRule) match(graph *ChainGraph) (*SelectionOp, string, bool) {
+	if graph.joinNode == "" || len(graph.chain) == 0 {
+		return nil, "", false
+	}
+
+	joinEval, inputs, ok := innerJoinEvaluator(graph.nodes[graph.joinNode].Op)
+	if !ok {
+		return nil, "", false
+	}
+	if _, ok := joinEval.(PredicateEvaluator); !ok {
+		return nil, "", false // the joined documents may not hold the input documents
+	}
+
+	sel, ok := graph.nodes[graph.chain[0]].Op.(*SelectionOp)
+	if !ok {
+		return nil, "", false
+	}
+	pred, ok := sel.eval.(PredicateEvaluator)
+	if !ok {
+		return nil, "", false
+	}
+	refs, ok := pred.InputRefs(inputs)
+	if !ok || len(refs) != 1 {
+		return nil, "", false
+	}
+
+	return sel, refs[0], true
+}
+
+// inputPredicate evaluates a predicate on the joined documents on the documents of a single join
+// input.
+type inputPredicate struct {
+	eval  Evaluator
+	input string
+}
+
+func (e *inputPredicate) Evaluate(doc Document) ([]Document, error) {
+	res, err := e.eval.Evaluate(Document{e.input: doc})
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return []Document{}, nil
+	}
+	return []Document{doc}, nil
+}
+
+func (e *inputPredicate) String() string {
+	return fmt.Sprintf("%s@%s", e.eval.String(), e.input)
+}
+
+// Rule 6: Push projections following an inner join into the join, so that the projection is
+// evaluated on each joined document as it is produced instead of in a separate pass.
+type ProjectionPushdownRule struct{}
+
+func (r *ProjectionPushdownRule) Name() string {
+	return "ProjectionPushdown"
+}
+
+func (r *ProjectionPushdownRule) IsCostBased() bool {
+	return true
+}
+
+func (r *ProjectionPushdownRule) CanApply(graph *ChainGraph) bool {
+	if graph.joinNode == "" || len(graph.chain) == 0 {
+		return false
+	}
+	if _, _, ok := innerJoinEvaluator(graph.nodes[graph.joinNode].Op); !ok {
+		return false
+	}
+	_, ok := graph.nodes[graph.chain[0]].Op.(*ProjectionOp)
+	return ok
+}
+
+func (r *ProjectionPushdownRule) Apply(graph *ChainGraph) error {
+	proj, ok := graph.nodes[graph.chain[0]].Op.(*ProjectionOp)
+	if !ok {
+		return fmt.Errorf("no projection to push down")
+	}
+
+	joinNode := graph.nodes[graph.joinNode]
+	joinEval, _, ok := innerJoinEvaluator(joinNode.Op)
+	if !ok {
+		return fmt.Errorf("join op %s is not an inner join", joinNode.Op.id())
+	}
+	joinOp, ok := withJoinEvaluator(joinNode.Op, &projectedJoin{join: joinEval, proj: proj.eval})
+	if !ok {
+		return fmt.Errorf("join op %s is not an inner join", joinNode.Op.id())
+	}
+	joinNode.Op = joinOp
+
+	delete(graph.nodes, graph.chain[0])
+	graph.chain = graph.chain[1:]
+	updateOutput(graph)
+
+	return nil
+}
+
+// projectedJoin applies a projection to the documents produced by a join evaluator. The join keys
+// of the join evaluator, if any, are retained.
+type projectedJoin struct {
+	join, proj Evaluator
+}
+
+func (e *projectedJoin) Evaluate(doc Document) ([]Document, error) {
+	joined, err := e.join.Evaluate(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []Document{}
+	for _, d := range joined {
+		projected, err := e.proj.Evaluate(d)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, projected...)
+	}
+
+	return ret, nil
+}
+
+func (e *projectedJoin) JoinKeys(inputs []string) ([]Extractor, bool) {
+	keyEval, ok := e.join.(JoinKeyEvaluator)
+	if !ok {
+		return nil, false
+	}
+	return keyEval.JoinKeys(inputs)
+}
+
+func (e *projectedJoin) String() string {
+	return fmt.Sprintf("%s→%s", e.join.String(), e.proj.String())
+}
+
+// innerJoinEvaluator returns the evaluator and the inputs of an inner join op.
+func innerJoinEvaluator(op Operator) (Evaluator, []string, bool) {
+	switch o := op.(type) {
+	case *JoinOp:
+		return o.eval, o.inputs, true
+	case *BinaryJoinOp:
+		return o.eval, o.inputs, true
+	case *HashJoinOp:
+		return o.eval, o.inputs, true
+	case *IncrementalJoinOp:
+		return o.eval, o.inputs, true
+	case *IncrementalBinaryJoinOp:
+		return o.eval, o.inputs, true
+	case *IncrementalHashJoinOp:
+		return o.eval, o.inputs, true
+	default:
+		return nil, nil, false
+	}
+}
+
+// withJoinEvaluator returns a new inner join op of the same kind as the given join op with the
+// evaluator replaced.
+func withJoinEvaluator(op Operator, eval Evaluator) (Operator, bool) {
+	switch o := op.(type) {
+	case *JoinOp:
+		return NewJoin(eval, o.inputs), true
+	case *BinaryJoinOp:
+		return NewBinaryJoin(eval, o.inputs), true
+	case *HashJoinOp:
+		return NewHashJoin(eval, o.inputs, o.keys), true
+	case *IncrementalJoinOp:
+		return NewIncrementalJoin(eval, o.inputs), true
+	case *IncrementalBinaryJoinOp:
+		return NewIncrementalBinaryJoin(eval, o.inputs), true
+	case *IncrementalHashJoinOp:
+		return NewIncrementalHashJoin(eval, o.inputs, o.keys), true
+	default:
+		return nil, false
+	}
+}
+
+// updateOutput updates the output after chain modifications.
+func updateOutput(graph *ChainGraph) {
+	switch {
+	case len(graph.chain) > 0:
+		graph.output = graph.chain[len(graph.chain)-1]
+	case graph.joinNode != "":
+		graph.output = graph.joinNode
+	case len(graph.inputs) > 0:
+		graph.output = graph.inputs[0]
+	}
+}
+
+// Rule 7: Fuse adjacent linear operations for efficiency.
 type LinearOperatorFusionRule struct{}
 
 func (r *LinearOperatorFusionRule) Name() string {
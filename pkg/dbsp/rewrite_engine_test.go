@@ -1,6 +1,8 @@
 package dbsp
 
 import (
+	"fmt"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -89,6 +91,50 @@ var _ = Describe("LinearChainRewriteEngine", func() {
 		})
 	})
 
+	Context("Hash Join Rule", func() {
+		It("should rewrite equi-joins into hash joins", func() {
+			inputs := []string{"users", "projects", "departments"}
+			for _, in := range inputs {
+				graph.AddInput(NewInput(in))
+			}
+			graph.SetJoin(NewIncrementalJoin(NewKeyedJoin("id", inputs), inputs))
+
+			err := rewriter.Optimize(graph)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, isHashJoin := graph.nodes[graph.joinNode].Op.(*IncrementalHashJoinOp)
+			Expect(isHashJoin).To(BeTrue())
+		})
+
+		It("should rewrite snapshot equi-joins into incremental hash joins", func() {
+			inputs := []string{"users", "projects"}
+			for _, in := range inputs {
+				graph.AddInput(NewInput(in))
+			}
+			graph.SetJoin(NewBinaryJoin(NewKeyedJoin("id", inputs), inputs))
+
+			err := rewriter.Optimize(graph)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, isHashJoin := graph.nodes[graph.joinNode].Op.(*IncrementalHashJoinOp)
+			Expect(isHashJoin).To(BeTrue())
+		})
+
+		It("should not rewrite joins without join keys", func() {
+			inputs := []string{"users", "projects", "departments"}
+			for _, in := range inputs {
+				graph.AddInput(NewInput(in))
+			}
+			graph.SetJoin(NewIncrementalJoin(NewNaryJoin("id", inputs), inputs))
+
+			err := rewriter.Optimize(graph)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, isJoin := graph.nodes[graph.joinNode].Op.(*IncrementalJoinOp)
+			Expect(isJoin).To(BeTrue())
+		})
+	})
+
 	Context("Linear Chain Incrementalization Rule", func() {
 		It("should incrementalize gather operations", func() {
 			graph.AddInput(NewInput("sales"))
@@ -255,6 +301,35 @@ var _ = Describe("LinearChainRewriteEngine", func() {
 			Expect(dist3Exists).To(BeFalse())
 			Expect(dist4Exists).To(BeTrue())
 		})
+
+		It("should incrementalize distinct operations", func() {
+			graph.AddInput(NewInput("collection"))
+
+			distID := graph.AddToChain(NewDistinct())
+
+			err := rewriter.Optimize(graph)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(graph.chain).To(HaveLen(1))
+			_, isIncremental := graph.nodes[distID].Op.(*IncrementalDistinctOp)
+			Expect(isIncremental).To(BeTrue())
+		})
+
+		It("should not incrementalize a distinct enclosed in an I->D pair", func() {
+			graph.AddInput(NewInput("collection"))
+
+			graph.AddToChain(NewIntegrator())
+			distID := graph.AddToChain(NewDistinct())
+			graph.AddToChain(NewDifferentiator())
+
+			err := rewriter.Optimize(graph)
+			Expect(err).NotTo(HaveOccurred())
+
+			// D ∘ distinct ∘ I is already incremental
+			Expect(graph.chain).To(HaveLen(3))
+			_, isSnapshot := graph.nodes[distID].Op.(*DistinctOp)
+			Expect(isSnapshot).To(BeTrue())
+		})
 	})
 
 	Context("Linear Operator Fusion Rule", func() {
@@ -515,3 +590,207 @@ var _ = Describe("LinearChainRewriteEngine", func() {
 		})
 	})
 })
+
+// PredicateJoinEvaluator joins the documents with equal values in a field and passes on the joined
+// documents unchanged.
+type PredicateJoinEvaluator struct {
+	field  string
+	inputs []string
+}
+
+func NewPredicateJoin(field string, inputs []string) *PredicateJoinEvaluator {
+	return &PredicateJoinEvaluator{field: field, inputs: inputs}
+}
+
+func (e *PredicateJoinEvaluator) Evaluate(doc Document) ([]Document, error) {
+	first := doc[e.inputs[0]].(Document)[e.field]
+	for _, input := range e.inputs[1:] {
+		if doc[input].(Document)[e.field] != first {
+			return []Document{}, nil
+		}
+	}
+	return []Document{doc}, nil
+}
+
+func (e *PredicateJoinEvaluator) InputRefs(inputs []string) ([]string, bool) { return inputs, true }
+
+func (e *PredicateJoinEvaluator) JoinKeys(inputs []string) ([]Extractor, bool) {
+	keys := make([]Extractor, len(inputs))
+	for i := range inputs {
+		keys[i] = NewFieldExtractor(e.field)
+	}
+	return keys, true
+}
+
+func (e *PredicateJoinEvaluator) String() string { return "predicate-join" }
+
+// InputFieldFilter filters the joined documents on a field of the document of an input.
+type InputFieldFilter struct {
+	input, field string
+	value        any
+}
+
+func NewInputFieldFilter(input, field string, value any) *InputFieldFilter {
+	return &InputFieldFilter{input: input, field: field, value: value}
+}
+
+func (e *InputFieldFilter) Evaluate(doc Document) ([]Document, error) {
+	if in, ok := doc[e.input].(Document); ok && in[e.field] == e.value {
+		return []Document{doc}, nil
+	}
+	return []Document{}, nil
+}
+
+func (e *InputFieldFilter) InputRefs(_ []string) ([]string, bool) { return []string{e.input}, true }
+
+func (e *InputFieldFilter) String() string {
+	return fmt.Sprintf("%s.%s==%v", e.input, e.field, e.value)
+}
+
+// AlwaysRule is a rule that never converges.
+type AlwaysRule struct{}
+
+func (r *AlwaysRule) Name() string                    { return "Always" }
+func (r *AlwaysRule) CanApply(graph *ChainGraph) bool { return true }
+func (r *AlwaysRule) Apply(graph *ChainGraph) error   { return nil }
+
+var _ = Describe("Cost-Based Optimization", func() {
+	var (
+		inputs   []string
+		rewriter *LinearChainRewriteEngine
+	)
+
+	BeforeEach(func() {
+		inputs = []string{"users", "projects"}
+		rewriter = NewLinearChainRewriteEngine()
+	})
+
+	newGraph := func() *ChainGraph {
+		graph := NewChainGraph()
+		for _, in := range inputs {
+			graph.AddInput(NewInput(in))
+		}
+		graph.SetJoin(NewJoin(NewPredicateJoin("id", inputs), inputs))
+		graph.AddToChain(NewSelection(NewInputFieldFilter("users", "role", "admin")))
+		graph.AddToChain(NewProjection(NewFieldProjection("users")))
+		return graph
+	}
+
+	It("should push selections and projections down", func() {
+		graph := newGraph()
+		Expect(rewriter.Optimize(graph)).To(Succeed())
+
+		Expect(graph.GetChain()).To(BeEmpty())
+		Expect(graph.GetInputChain("users")).To(HaveLen(1))
+		_, ok := graph.GetInputChain("users")[0].(*SelectionOp)
+		Expect(ok).To(BeTrue())
+		Expect(graph.GetInputChain("projects")).To(BeEmpty())
+		_, ok = graph.nodes[graph.joinNode].Op.(*IncrementalHashJoinOp)
+		Expect(ok).To(BeTrue())
+		Expect(graph.rewrites).To(ContainElements("HashJoin", "SelectionPushdown", "ProjectionPushdown"))
+
+		// The optimized graph is equivalent to the incremental version of the original one.
+		reference := newGraph()
+		reference.nodes[reference.joinNode].Op = NewIncrementalJoin(NewPredicateJoin("id", inputs), inputs)
+
+		optimized, err := NewExecutor(graph, logger)
+		Expect(err).NotTo(HaveOccurred())
+		expected, err := NewExecutor(reference, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		steps := []DeltaZSet{
+			{"users": zsetOf(Document{"id": int64(1), "role": "admin"}), "projects": zsetOf(Document{"id": int64(1)})},
+			{"users": zsetOf(Document{"id": int64(2), "role": "dev"}), "projects": zsetOf(Document{"id": int64(2)})},
+			{"users": NewDocumentZSet(), "projects": zsetOf(Document{"id": int64(1), "name": "p"})},
+		}
+		for _, step := range steps {
+			res, err := optimized.Process(step)
+			Expect(err).NotTo(HaveOccurred())
+			exp, err := expected.Process(step)
+			Expect(err).NotTo(HaveOccurred())
+			diff, err := res.Subtract(exp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff.IsZero()).To(BeTrue())
+		}
+
+		// Snapshot conversion retains the ops pushed below the join.
+		snapshot, err := ToSnapshotGraph(graph)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshot.GetInputChain("users")).To(HaveLen(1))
+	})
+
+	It("should not push selections below joins that transform the joined documents", func() {
+		graph := NewChainGraph()
+		for _, in := range inputs {
+			graph.AddInput(NewInput(in))
+		}
+		graph.SetJoin(NewJoin(NewFlexibleJoin("id", inputs), inputs))
+		graph.AddToChain(NewSelection(NewInputFieldFilter("users", "role", "admin")))
+		Expect(rewriter.Optimize(graph)).To(Succeed())
+
+		Expect(graph.GetInputChain("users")).To(BeEmpty())
+		Expect(graph.GetChain()).To(HaveLen(1))
+	})
+
+	It("should choose the join strategy based on input cardinalities", func() {
+		graph := newGraph()
+		Expect(rewriter.Optimize(graph)).To(Succeed())
+		_, ok := graph.nodes[graph.joinNode].Op.(*IncrementalHashJoinOp)
+		Expect(ok).To(BeTrue())
+
+		// Obtain the statistics from an executor of a nested-loop join.
+		small := NewChainGraph()
+		for _, in := range inputs {
+			small.AddInput(NewInput(in))
+		}
+		small.SetJoin(NewIncrementalJoin(NewPredicateJoin("id", inputs), inputs))
+		executor, err := NewExecutor(small, logger)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = executor.Process(DeltaZSet{
+			"users":    zsetOf(Document{"id": int64(1), "role": "admin"}),
+			"projects": zsetOf(Document{"id": int64(1)}),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		stats := executor.Statistics()
+		Expect(stats).To(Equal(Statistics{"users": 1, "projects": 1}))
+
+		// Hashing does not pay off on tiny inputs.
+		rewriter.SetStatistics(stats)
+		graph = newGraph()
+		Expect(rewriter.Optimize(graph)).To(Succeed())
+		_, ok = graph.nodes[graph.joinNode].Op.(*IncrementalJoinOp)
+		Expect(ok).To(BeTrue())
+	})
+
+	It("should expose the chosen plan", func() {
+		graph := newGraph()
+		Expect(rewriter.Optimize(graph)).To(Succeed())
+		executor, err := NewExecutor(graph, logger)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = executor.Process(DeltaZSet{
+			"users":    zsetOf(Document{"id": int64(1), "role": "admin"}),
+			"projects": zsetOf(Document{"id": int64(1)}),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		plan := executor.GetExecutionPlan()
+		Expect(plan).To(ContainSubstring("users → σ"))
+		Expect(plan).To(ContainSubstring("Rewrites: "))
+		Expect(plan).To(ContainSubstring("SelectionPushdown"))
+		Expect(plan).To(ContainSubstring("Estimated cost: "))
+		Expect(plan).To(ContainSubstring("Input cardinalities: users=1, projects=1"))
+	})
+
+	It("should bound the number of rewrites", func() {
+		rewriter.AddRule(&AlwaysRule{})
+		rewriter.SetMaxIterations(5)
+		err := rewriter.Optimize(newGraph())
+		Expect(err).To(MatchError(ContainSubstring("did not converge after 5 iterations")))
+	})
+})
+
+func zsetOf(doc Document) *DocumentZSet {
+	zset, err := SingletonZSet(doc)
+	Expect(err).NotTo(HaveOccurred())
+	return zset
+}
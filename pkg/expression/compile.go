@@ -0,0 +1,591 @@
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ohler55/ojg/jp"
+)
+
+// Type is the static type of the value an expression evaluates to.
+type Type int
+
+const (
+	// TypeAny is the type of expressions whose result type cannot be inferred statically, like
+	// JSONPath expressions.
+	TypeAny Type = iota
+	TypeNull
+	TypeBool
+	TypeInt
+	TypeFloat
+	// TypeNumber is an int or a float.
+	TypeNumber
+	TypeString
+	TypeList
+	TypeMap
+)
+
+// String stringifies a type.
+func (t Type) String() string {
+	switch t {
+	case TypeNull:
+		return "null"
+	case TypeBool:
+		return "bool"
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeNumber:
+		return "number"
+	case TypeString:
+		return "string"
+	case TypeList:
+		return "list"
+	case TypeMap:
+		return "map"
+	default:
+		return "any"
+	}
+}
+
+// Accepts checks whether a value of type u may be used where a value of type t is expected. The
+// check is conservative: it fails only if the conversion would fail for every possible value,
+// e.g., strings are accepted as numbers because numeric strings are converted at runtime.
+func (t Type) Accepts(u Type) bool {
+	if t == TypeAny || u == TypeAny {
+		return true
+	}
+
+	switch t {
+	case TypeInt:
+		return u == TypeInt || u == TypeNumber || u == TypeString
+	case TypeFloat, TypeNumber:
+		return u == TypeInt || u == TypeFloat || u == TypeNumber || u == TypeString
+	case TypeString:
+		return u == TypeString || u == TypeInt || u == TypeFloat || u == TypeNumber
+	default:
+		return t == u
+	}
+}
+
+// CompileCtx defines the context for compiling an expression.
+type CompileCtx struct {
+	// Path is the location of the expression in the enclosing spec, used in error messages.
+	Path string
+	// Variables maps the names of the variables in scope to their types.
+	Variables map[string]Type
+}
+
+// child returns a copy of the compilation context with the path extended with the given element.
+func (ctx CompileCtx) child(elem string) CompileCtx {
+	if ctx.Path == "" {
+		elem = strings.TrimPrefix(elem, ".")
+	}
+	ctx.Path += elem
+	return ctx
+}
+
+// withVariables returns a copy of the compilation context extended with the given variables.
+func (ctx CompileCtx) withVariables(vars map[string]Type) CompileCtx {
+	scope := make(map[string]Type, len(ctx.Variables)+len(vars))
+	maps.Copy(scope, ctx.Variables)
+	maps.Copy(scope, vars)
+	ctx.Variables = scope
+	return ctx
+}
+
+// argKind describes how an op takes its arguments.
+type argKind int
+
+const (
+	// unaryArg ops take a single argument of the given type.
+	unaryArg argKind = iota
+	// fixedArgs ops take a list of positional arguments, the trailing ones may be optional.
+	fixedArgs
+	// variadicArgs ops take a list of any number of arguments of the same type.
+	variadicArgs
+	// opaqueArgs ops take arguments of different shapes that are not checked statically.
+	opaqueArgs
+)
+
+// signature is the static type signature of an op.
+type signature struct {
+	kind argKind
+	// args is the type of the argument for unary ops, the types of the positional arguments
+	// for fixed-arity ops and the type of the arguments for variadic ops.
+	args []Type
+	// min is the minimum number of arguments for fixed-arity ops.
+	min int
+	// own is set for ops that evaluate their arguments themselves: these ops do not unpack
+	// nested argument lists.
+	own    bool
+	result Type
+}
+
+func unary(arg, result Type) signature {
+	return signature{kind: unaryArg, args: []Type{arg}, result: result}
+}
+
+func fixed(min int, result Type, args ...Type) signature {
+	return signature{kind: fixedArgs, args: args, min: min, result: result}
+}
+
+func variadic(arg, result Type) signature {
+	return signature{kind: variadicArgs, args: []Type{arg}, result: result}
+}
+
+func opaque(result Type) signature {
+	return signature{kind: opaqueArgs, result: result}
+}
+
+func (s signature) ownArgs() signature {
+	s.own = true
+	return s
+}
+
+// signatures contains the type signature of the ops that are not compiled specially.
+var signatures = map[string]signature{
+	"@noop": opaque(TypeNull),
+
+	// ops that evaluate their own arguments
+	"@cond":      fixed(2, TypeAny, TypeBool, TypeAny, TypeAny).ownArgs(),
+	"@definedOr": fixed(2, TypeAny, TypeAny, TypeAny).ownArgs(),
+	"@and":       variadic(TypeBool, TypeBool).ownArgs(),
+	"@or":        variadic(TypeBool, TypeBool).ownArgs(),
+	"@filter":    fixed(2, TypeList, TypeBool, TypeList).ownArgs(),
+	"@any":       fixed(2, TypeBool, TypeBool, TypeList).ownArgs(),
+	"@none":      fixed(2, TypeBool, TypeBool, TypeList).ownArgs(),
+	"@all":       fixed(2, TypeBool, TypeBool, TypeList).ownArgs(),
+	"@map":       fixed(2, TypeList, TypeAny, TypeList).ownArgs(),
+	"@sortBy":    fixed(2, TypeList, TypeAny, TypeList).ownArgs(),
+	"@sort":      unary(TypeList, TypeList).ownArgs(),
+	"@reverse":   unary(TypeList, TypeList).ownArgs(),
+	"@unique":    unary(TypeList, TypeList).ownArgs(),
+	"@flatten":   unary(TypeList, TypeList).ownArgs(),
+	"@slice":     fixed(2, TypeList, TypeList, TypeInt, TypeInt).ownArgs(),
+	"@index":     fixed(2, TypeAny, TypeList, TypeInt).ownArgs(),
+	"@zip":       variadic(TypeList, TypeList).ownArgs(),
+	"@fold":      fixed(3, TypeAny, TypeAny, TypeAny, TypeList).ownArgs(),
+	"@reduce":    fixed(3, TypeAny, TypeAny, TypeAny, TypeList).ownArgs(),
+
+	// bool ops
+	"@isnil":    unary(TypeAny, TypeBool),
+	"@exists":   unary(TypeAny, TypeBool),
+	"@has":      unary(TypeAny, TypeBool),
+	"@not":      unary(TypeBool, TypeBool),
+	"@eq":       fixed(2, TypeBool, TypeAny, TypeAny),
+	"@lt":       fixed(2, TypeBool, TypeNumber, TypeNumber),
+	"@lte":      fixed(2, TypeBool, TypeNumber, TypeNumber),
+	"@gt":       fixed(2, TypeBool, TypeNumber, TypeNumber),
+	"@gte":      fixed(2, TypeBool, TypeNumber, TypeNumber),
+	"@selector": fixed(2, TypeBool, TypeMap, TypeMap),
+
+	// arithmetic
+	"@rnd":   fixed(2, TypeInt, TypeInt, TypeInt),
+	"@abs":   unary(TypeNumber, TypeFloat),
+	"@ceil":  unary(TypeNumber, TypeFloat),
+	"@floor": unary(TypeNumber, TypeFloat),
+	"@add":   fixed(2, TypeNumber, TypeNumber, TypeNumber),
+	"@sub":   fixed(2, TypeNumber, TypeNumber, TypeNumber),
+	"@mul":   fixed(2, TypeNumber, TypeNumber, TypeNumber),
+	"@div":   fixed(2, TypeNumber, TypeNumber, TypeNumber),
+	"@mod":   fixed(2, TypeNumber, TypeNumber, TypeNumber),
+	"@sum":   variadic(TypeNumber, TypeNumber),
+	"@min":   variadic(TypeNumber, TypeNumber),
+	"@max":   variadic(TypeNumber, TypeNumber),
+
+	// collections
+	"@len":         unary(TypeList, TypeInt),
+	"@in":          fixed(2, TypeBool, TypeAny, TypeList),
+	"@keys":        unary(TypeMap, TypeList),
+	"@values":      unary(TypeMap, TypeList),
+	"@entries":     unary(TypeMap, TypeList),
+	"@fromEntries": unary(TypeList, TypeMap),
+	"@merge":       variadic(TypeMap, TypeMap),
+
+	// strings
+	"@concat":       variadic(TypeString, TypeString),
+	"@upper":        unary(TypeString, TypeString),
+	"@lower":        unary(TypeString, TypeString),
+	"@trim":         opaque(TypeString),
+	"@trimPrefix":   fixed(2, TypeString, TypeString, TypeString),
+	"@trimSuffix":   fixed(2, TypeString, TypeString, TypeString),
+	"@hasPrefix":    fixed(2, TypeBool, TypeString, TypeString),
+	"@hasSuffix":    fixed(2, TypeBool, TypeString, TypeString),
+	"@split":        fixed(2, TypeList, TypeString, TypeString),
+	"@replace":      fixed(3, TypeString, TypeString, TypeString, TypeString),
+	"@substr":       fixed(2, TypeString, TypeString, TypeInt, TypeInt),
+	"@match":        fixed(2, TypeBool, TypeString, TypeString),
+	"@regex":        fixed(2, TypeAny, TypeString, TypeString),
+	"@regexReplace": fixed(3, TypeString, TypeString, TypeString, TypeString),
+
+	// time
+	"@time":       opaque(TypeString),
+	"@timeFormat": fixed(2, TypeString, TypeAny, TypeString),
+	"@duration":   unary(TypeAny, TypeNumber),
+	"@timeAdd":    fixed(2, TypeString, TypeAny, TypeAny),
+	"@timeDiff":   fixed(2, TypeNumber, TypeAny, TypeAny),
+	"@age":        unary(TypeAny, TypeNumber),
+
+	// quantities and versions
+	"@quantity":    unary(TypeAny, TypeNumber),
+	"@quantityAdd": variadic(TypeAny, TypeString),
+	"@quantityCmp": fixed(2, TypeInt, TypeAny, TypeAny),
+	"@semver":      unary(TypeString, TypeMap),
+	"@semverCmp":   fixed(2, TypeInt, TypeString, TypeString),
+
+	// encoding
+	"@hash":         unary(TypeAny, TypeString),
+	"@sha256":       unary(TypeAny, TypeString),
+	"@base64Encode": unary(TypeString, TypeString),
+	"@base64Decode": unary(TypeString, TypeString),
+	"@json":         unary(TypeAny, TypeString),
+	"@fromJson":     unary(TypeString, TypeAny),
+	"@yaml":         unary(TypeAny, TypeString),
+	"@fromYaml":     unary(TypeString, TypeAny),
+}
+
+// Compile statically checks an expression without evaluating it. It validates op names, the
+// number and the types of op arguments as far as these can be determined without the input
+// object, the syntax of JSONPaths and regular expressions given as literals, and whether the
+// variables referenced in the expression are in scope. Returns the inferred type of the value
+// the expression evaluates to, which is TypeAny if the type cannot be inferred.
+func (e *Expression) Compile(ctx CompileCtx) (Type, error) {
+	if e == nil || len(e.Op) == 0 {
+		return TypeAny, NewCompileError(ctx.Path, errors.New("empty operator"))
+	}
+
+	switch e.Op {
+	case "@bool":
+		return e.compileLiteral(ctx, TypeBool)
+	case "@int":
+		return e.compileLiteral(ctx, TypeInt)
+	case "@float":
+		return e.compileLiteral(ctx, TypeFloat)
+	case "@string":
+		if e.Arg != nil {
+			// the result is looked up as a JSONPath
+			if _, err := e.Arg.Compile(ctx.child("." + e.Op)); err != nil {
+				return TypeAny, err
+			}
+			return TypeAny, nil
+		}
+		str, err := AsString(e.Literal)
+		if err != nil {
+			return TypeAny, NewCompileError(ctx.Path, err)
+		}
+		return compileString(ctx, str)
+	case "@list":
+		if e.Arg != nil {
+			return e.compileLiteral(ctx, TypeList)
+		}
+		es, ok := e.Literal.([]Expression)
+		if !ok {
+			return TypeAny, NewCompileError(ctx.Path, errors.New("argument must be an expression list"))
+		}
+		for i := range es {
+			if _, err := es[i].Compile(ctx.child(fmt.Sprintf("[%d]", i))); err != nil {
+				return TypeAny, err
+			}
+		}
+		return TypeList, nil
+	case "@dict":
+		if e.Arg != nil {
+			return e.compileLiteral(ctx, TypeMap)
+		}
+		em, ok := e.Literal.(map[string]Expression)
+		if !ok {
+			return TypeAny, NewCompileError(ctx.Path, errors.New("argument must be a string->expression map"))
+		}
+		for _, k := range slices.Sorted(maps.Keys(em)) {
+			exp := em[k]
+			if _, err := exp.Compile(ctx.child(dictKeyPath(k))); err != nil {
+				return TypeAny, err
+			}
+		}
+		return TypeMap, nil
+	}
+
+	// literal map
+	if e.Op[0] != '@' {
+		if e.Arg != nil {
+			if _, err := e.Arg.Compile(ctx.child("." + e.Op)); err != nil {
+				return TypeAny, err
+			}
+		}
+		return TypeMap, nil
+	}
+
+	opCtx := ctx.child("." + e.Op)
+	if e.Arg == nil {
+		return TypeAny, NewCompileError(opCtx.Path, errors.New("missing argument"))
+	}
+
+	switch e.Op {
+	case "@let":
+		return e.compileLet(opCtx)
+	case "@switch":
+		return e.compileSwitch(opCtx)
+	}
+
+	sig, ok := signatures[e.Op]
+	if !ok {
+		return TypeAny, NewCompileError(opCtx.Path, fmt.Errorf("unknown op %q", e.Op))
+	}
+
+	switch sig.kind {
+	case unaryArg:
+		t, err := e.Arg.Compile(opCtx)
+		if err != nil {
+			return TypeAny, err
+		}
+		if !sig.args[0].Accepts(t) {
+			return TypeAny, NewCompileError(opCtx.Path,
+				fmt.Errorf("expected %s argument, got %s", sig.args[0], t))
+		}
+
+	case fixedArgs, variadicArgs:
+		args, ok := e.Arg.argList(sig.own)
+		if !ok {
+			// the argument list is computed at runtime
+			t, err := e.Arg.Compile(opCtx)
+			if err != nil {
+				return TypeAny, err
+			}
+			if !TypeList.Accepts(t) {
+				return TypeAny, NewCompileError(opCtx.Path,
+					fmt.Errorf("expected a list of arguments, got %s", t))
+			}
+			break
+		}
+
+		types := make([]Type, len(args))
+		for i := range args {
+			argCtx := opCtx.child(fmt.Sprintf("[%d]", i))
+			if (e.Op == "@fold" || e.Op == "@reduce") && i == 0 {
+				// the accumulator is in scope in the fold expression
+				argCtx = argCtx.withVariables(map[string]Type{accumulatorVar: TypeAny})
+			}
+			t, err := args[i].Compile(argCtx)
+			if err != nil {
+				return TypeAny, err
+			}
+			types[i] = t
+		}
+
+		// a single list argument is unpacked at runtime by ops that do not evaluate
+		// their own arguments, so the actual number of arguments is not known
+		if !sig.own && len(types) == 1 && TypeList.Accepts(types[0]) {
+			break
+		}
+
+		if err := sig.checkArgs(types); err != nil {
+			return TypeAny, NewCompileError(opCtx.Path, err)
+		}
+
+		switch e.Op {
+		case "@match", "@regex", "@regexReplace":
+			if err := compileRegexp(&args[0]); err != nil {
+				return TypeAny, NewCompileError(opCtx.argPath(0), err)
+			}
+		}
+
+	case opaqueArgs:
+		if _, err := e.Arg.Compile(opCtx); err != nil {
+			return TypeAny, err
+		}
+	}
+
+	return sig.result, nil
+}
+
+// checkArgs checks the number and the types of the arguments of a fixed-arity or variadic op.
+func (s signature) checkArgs(types []Type) error {
+	if s.kind == fixedArgs {
+		switch {
+		case s.min == len(s.args) && len(types) != s.min:
+			return fmt.Errorf("expected %d arguments, got %d", s.min, len(types))
+		case len(types) < s.min || len(types) > len(s.args):
+			return fmt.Errorf("expected %d to %d arguments, got %d", s.min, len(s.args), len(types))
+		}
+	}
+
+	for i, t := range types {
+		want := s.args[0]
+		if s.kind == fixedArgs {
+			want = s.args[i]
+		}
+		if !want.Accepts(t) {
+			return fmt.Errorf("expected %s argument at index %d, got %s", want, i, t)
+		}
+	}
+
+	return nil
+}
+
+// compileLiteral compiles a terminal expression of the given type, with a possibly stacked
+// expression to be converted into the type.
+func (e *Expression) compileLiteral(ctx CompileCtx, t Type) (Type, error) {
+	if e.Arg == nil {
+		return t, nil
+	}
+
+	// stacked expressions appear in the spec under the op
+	opCtx := ctx.child("." + e.Op)
+	u, err := e.Arg.Compile(opCtx)
+	if err != nil {
+		return TypeAny, err
+	}
+
+	// only lists and maps are strict, the rest are converted
+	if (t == TypeList || t == TypeMap) && !t.Accepts(u) {
+		return TypeAny, NewCompileError(opCtx.Path, fmt.Errorf("expected %s argument, got %s", t, u))
+	}
+
+	return t, nil
+}
+
+// compileLet compiles a @let expression: the bindings are compiled in the enclosing scope and
+// the body in the scope extended with the bindings.
+func (e *Expression) compileLet(ctx CompileCtx) (Type, error) {
+	args, _ := e.Arg.argList(true)
+	if len(args) != 2 {
+		return TypeAny, NewCompileError(ctx.Path, fmt.Errorf("expected 2 arguments, got %d", len(args)))
+	}
+
+	defs, ok := args[0].Literal.(map[string]Expression)
+	if args[0].Op != "@dict" || !ok {
+		return TypeAny, NewCompileError(ctx.argPath(0), errors.New("expected a map of bindings"))
+	}
+
+	vars := make(map[string]Type, len(defs))
+	for _, name := range slices.Sorted(maps.Keys(defs)) {
+		bindingCtx := ctx.child("[0]").child("." + name)
+		if !IsValidVariableName(name) {
+			return TypeAny, NewCompileError(bindingCtx.Path, fmt.Errorf("invalid variable name %q", name))
+		}
+		exp := defs[name]
+		t, err := exp.Compile(bindingCtx)
+		if err != nil {
+			return TypeAny, err
+		}
+		vars[name] = t
+	}
+
+	return args[1].Compile(ctx.child("[1]").withVariables(vars))
+}
+
+// compileSwitch compiles a @switch expression made of [case, action] pairs.
+func (e *Expression) compileSwitch(ctx CompileCtx) (Type, error) {
+	pairs, _ := e.Arg.argList(true)
+	if len(pairs) == 0 {
+		return TypeAny, NewCompileError(ctx.Path, errors.New("expected at least one [case, action] pair"))
+	}
+
+	for i := range pairs {
+		pairCtx := ctx.child(fmt.Sprintf("[%d]", i))
+		elems, _ := pairs[i].argList(true)
+		if len(elems) != 2 {
+			return TypeAny, NewCompileError(pairCtx.Path,
+				fmt.Errorf("expected a [case, action] pair, got %d elements", len(elems)))
+		}
+
+		t, err := elems[0].Compile(pairCtx.child("[0]"))
+		if err != nil {
+			return TypeAny, err
+		}
+		if !TypeBool.Accepts(t) {
+			return TypeAny, NewCompileError(pairCtx.argPath(0), fmt.Errorf("expected bool case, got %s", t))
+		}
+
+		if _, err := elems[1].Compile(pairCtx.child("[1]")); err != nil {
+			return TypeAny, err
+		}
+	}
+
+	return TypeAny, nil
+}
+
+// argPath returns the path of the i-th argument of the expression at the current path.
+func (ctx CompileCtx) argPath(i int) string {
+	return ctx.child(fmt.Sprintf("[%d]", i)).Path
+}
+
+// argList returns the arguments given as a literal list. Ops that evaluate their own arguments
+// take a non-list argument as a single argument, for the rest the argument list is known only at
+// runtime.
+func (e *Expression) argList(own bool) ([]Expression, bool) {
+	if e.Op == "@list" && e.Arg == nil {
+		if es, ok := e.Literal.([]Expression); ok {
+			return es, true
+		}
+	}
+
+	if own {
+		return []Expression{*e}, true
+	}
+
+	return nil, false
+}
+
+// compileString compiles a string literal, which may be a JSONPath or a variable reference.
+func compileString(ctx CompileCtx, str string) (Type, error) {
+	if len(str) == 0 || str[0] != '$' {
+		return TypeString, nil
+	}
+
+	key := str
+	switch key {
+	case "$.":
+		key = "$"
+	case "$$.":
+		key = "$$"
+	}
+
+	if name, rest, ok := cutVariableRef(key); ok {
+		t, ok := ctx.Variables[name]
+		if !ok {
+			return TypeAny, NewCompileError(ctx.Path, fmt.Errorf("undefined variable %q", name))
+		}
+		if rest == "" || rest == "." {
+			return t, nil
+		}
+		key = "$" + rest
+	} else if strings.HasPrefix(key, "$$") {
+		key = key[1:]
+	}
+
+	if _, err := jp.ParseString(key); err != nil {
+		return TypeAny, NewCompileError(ctx.Path, fmt.Errorf("invalid JSONPath %q: %w", str, err))
+	}
+
+	return TypeAny, nil
+}
+
+// compileRegexp checks a regular expression given as a string literal.
+func compileRegexp(e *Expression) error {
+	str, ok := e.Literal.(string)
+	if e.Op != "@string" || e.Arg != nil || !ok || strings.HasPrefix(str, "$") {
+		return nil
+	}
+
+	if _, err := regexp.Compile(str); err != nil {
+		return fmt.Errorf("invalid regular expression %q: %w", str, err)
+	}
+
+	return nil
+}
+
+// dictKeyPath returns the path element for a map key: JSONPath keys are appended as is, the rest
+// are appended as a field.
+func dictKeyPath(key string) string {
+	if strings.HasPrefix(key, "$") {
+		return strings.TrimPrefix(key, "$")
+	}
+	return "." + key
+}
@@ -0,0 +1,120 @@
+package expression
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/json"
+)
+
+var _ = Describe("Compiler", func() {
+	compile := func(jsonData string, ctx CompileCtx) (Type, error) {
+		var exp Expression
+		err := json.Unmarshal([]byte(jsonData), &exp)
+		Expect(err).NotTo(HaveOccurred(), jsonData)
+		return exp.Compile(ctx)
+	}
+
+	Describe("Inferring types", func() {
+		It("should infer the type of valid expressions", func() {
+			for jsonData, expected := range map[string]Type{
+				`true`:                                 TypeBool,
+				`12`:                                   TypeInt,
+				`1.5`:                                  TypeFloat,
+				`"abc"`:                                TypeString,
+				`"$.spec.a"`:                           TypeAny,
+				`[1,2]`:                                TypeList,
+				`{"a":1}`:                              TypeMap,
+				`{"@eq":["$.a",1]}`:                    TypeBool,
+				`{"@add":[1,2]}`:                       TypeNumber,
+				`{"@len":"$.spec.list"}`:               TypeInt,
+				`{"@concat":["a","$.b"]}`:              TypeString,
+				`{"@and":[{"@eq":[1,1]},"$.b"]}`:       TypeBool,
+				`{"@map":[{"@add":["$$",1]},[1,2]]}`:   TypeList,
+				`{"@sum":"$.spec.list"}`:               TypeNumber,
+				`{"@max":[[1,2,3]]}`:                   TypeNumber,
+				`{"@let":[{"x":1},{"@add":["$x",1]}]}`: TypeNumber,
+				`{"@let":[{"x":"abc"},"$x"]}`:          TypeString,
+				`{"@fold":[{"@add":["$acc","$$"]},0,[1]]}`: TypeAny,
+				`{"@switch":[[true,1],[false,2]]}`:         TypeAny,
+				`{"@match":["^a.*","abc"]}`:                TypeBool,
+			} {
+				t, err := compile(jsonData, CompileCtx{})
+				Expect(err).NotTo(HaveOccurred(), jsonData)
+				Expect(t).To(Equal(expected), jsonData)
+			}
+		})
+
+		It("should return the type of variables", func() {
+			t, err := compile(`"$x"`, CompileCtx{Variables: map[string]Type{"x": TypeInt}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t).To(Equal(TypeInt))
+
+			t, err = compile(`"$x.spec"`, CompileCtx{Variables: map[string]Type{"x": TypeMap}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t).To(Equal(TypeAny))
+		})
+	})
+
+	Describe("Rejecting invalid expressions", func() {
+		It("should report the path of invalid expressions", func() {
+			for jsonData, path := range map[string]string{
+				`{"@unknown":1}`:                           "@unknown",
+				`{"spec":{"ports":{"@unknown":1}}}`:        "spec.ports.@unknown",
+				`{"$.spec.ports":{"@unknown":1}}`:          "spec.ports.@unknown",
+				`{"@and":[true,{"@not":1}]}`:               "@and[1].@not",
+				`{"@eq":[1,2,3]}`:                          "@eq",
+				`{"@cond":[true]}`:                         "@cond",
+				`{"@cond":[1,2,3]}`:                        "@cond",
+				`{"@filter":[{"@add":[1,2]},[1]]}`:         "@filter",
+				`{"@lt":[true,1]}`:                         "@lt",
+				`{"@len":{"a":1}}`:                         "@len",
+				`{"@not":{"@len":[1]}}`:                    "@not",
+				`{"@add":true}`:                            "@add",
+				`{"@slice":[[1,2],1.5]}`:                   "@slice",
+				`{"@switch":[[true,1],[2]]}`:               "@switch[1]",
+				`{"@switch":[[true,1],[2,3]]}`:             "@switch[1][0]",
+				`{"@let":[[1],2]}`:                         "@let[0]",
+				`{"@let":[{"invalid-name":1},2]}`:          "@let[0].invalid-name",
+				`{"@let":[{"x":1},"$y"]}`:                  "@let[1]",
+				`{"@let":[{"x":1,"y":"$x"},"$y"]}`:         "@let[0].y",
+				`{"@match":["[a-","abc"]}`:                 "@match[0]",
+				`"$.spec[?(@.a=="`:                         "",
+				`{"@map":["$acc",[1]]}`:                    "@map[0]",
+				`{"@fold":[{"@add":["$acc",1]},0,"$acc"]}`: "@fold[2]",
+				`{"@dict":{"@list":[1]}}`:                  "@dict",
+				`{"@list":{"@dict":{"a":1}}}`:              "@list",
+			} {
+				_, err := compile(jsonData, CompileCtx{})
+				Expect(err).To(HaveOccurred(), jsonData)
+				if path == "" {
+					Expect(err.Error()).To(HavePrefix("invalid expression: "), jsonData)
+				} else {
+					Expect(err.Error()).To(HavePrefix("invalid expression at "+path+": "), jsonData)
+				}
+			}
+		})
+
+		It("should prefix the path from the context", func() {
+			_, err := compile(`{"ports":{"@lower":[1,2]}}`, CompileCtx{Path: "pipeline[2].@project.spec"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("invalid expression at pipeline[2].@project.spec.ports.@lower: "))
+		})
+	})
+
+	Describe("Accepting runtime conversions", func() {
+		It("should accept arguments that may be converted at runtime", func() {
+			for _, jsonData := range []string{
+				`{"@add":["1",2]}`,
+				`{"@concat":["a",1]}`,
+				`{"@lt":[1,2.5]}`,
+				`{"@eq":"$.pair"}`,
+				`{"@add":["$.pair"]}`,
+				`{"@upper":{"@add":[1,2]}}`,
+				`{"@sum":[1,"$.b",3]}`,
+			} {
+				_, err := compile(jsonData, CompileCtx{})
+				Expect(err).NotTo(HaveOccurred(), jsonData)
+			}
+		})
+	})
+})
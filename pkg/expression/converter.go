@@ -3,8 +3,13 @@ package expression
 import (
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/version"
 
 	"github.com/l7mp/dcontroller/pkg/util"
 )
@@ -288,6 +293,113 @@ func AsMap(d any) (map[string]any, error) {
 	return ret, nil
 }
 
+// AsTime converts the argument into a time. Accepts RFC3339 timestamps and Unix timestamps in
+// seconds.
+func AsTime(d any) (time.Time, error) {
+	if d == nil {
+		return time.Time{}, errors.New("argument is nil")
+	}
+
+	if s, ok := d.(string); ok {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("argument is not an RFC3339 timestamp: %w", err)
+		}
+		return t, nil
+	}
+
+	f, err := AsFloat(d)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("argument is not a timestamp: %s", util.Stringify(d))
+	}
+
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*float64(time.Second))), nil
+}
+
+// AsDuration converts the argument into a duration. Accepts duration strings like "1h30m" and
+// numbers that are interpreted as seconds.
+func AsDuration(d any) (time.Duration, error) {
+	if d == nil {
+		return 0, errors.New("argument is nil")
+	}
+
+	if s, ok := d.(string); ok {
+		if v, err := time.ParseDuration(s); err == nil {
+			return v, nil
+		}
+	}
+
+	f, err := AsFloat(d)
+	if err != nil {
+		return 0, fmt.Errorf("argument is not a duration: %s", util.Stringify(d))
+	}
+
+	return time.Duration(f * float64(time.Second)), nil
+}
+
+// AsQuantity converts the argument into a Kubernetes resource quantity. Accepts quantity strings
+// like "500m" or "1Gi" and plain numbers.
+func AsQuantity(d any) (resource.Quantity, error) {
+	if d == nil {
+		return resource.Quantity{}, errors.New("argument is nil")
+	}
+
+	switch v := d.(type) {
+	case string:
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return resource.Quantity{}, fmt.Errorf("argument is not a quantity: %w", err)
+		}
+		return q, nil
+	case int64:
+		return *resource.NewQuantity(v, resource.DecimalSI), nil
+	}
+
+	f, err := AsFloat(d)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("argument is not a quantity: %s", util.Stringify(d))
+	}
+
+	return resource.ParseQuantity(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// AsQuantityList converts the argument into a list of Kubernetes resource quantities.
+func AsQuantityList(d any) ([]resource.Quantity, error) {
+	args, err := AsList(d)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]resource.Quantity, len(args))
+	for i := range args {
+		q, err := AsQuantity(args[i])
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = q
+	}
+
+	return ret, nil
+}
+
+// AsVersion converts the argument into a version. Semantic versions are parsed with the full
+// semver semantics (including pre-release and build metadata), other strings are parsed as generic
+// dot-separated versions like "1.25".
+func AsVersion(d any) (*version.Version, error) {
+	s, err := AsString(d)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := version.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("argument is not a version: %w", err)
+	}
+
+	return v, nil
+}
+
 // AsExpOrExpList returns an expression or an expression list.
 func AsExpOrExpList(d any) ([]Expression, error) {
 	exp, ok := d.(Expression)
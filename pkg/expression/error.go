@@ -1,9 +1,13 @@
 package expression
 
 import (
+	"errors"
 	"fmt"
 )
 
+// ErrDivisionByZero is returned by the @div and @mod operators when the divisor is zero.
+var ErrDivisionByZero = errors.New("division by zero")
+
 // ErrInvalidArguments is a custom error.
 type ErrInvalidArguments = error
 
@@ -27,3 +31,14 @@ type ErrExpression = error
 func NewExpressionError(e *Expression, err error) ErrExpression {
 	return fmt.Errorf("failed to evaluate %s expression %s: %w", e.Op, e.String(), err)
 }
+
+// ErrCompile is a custom error.
+type ErrCompile = error
+
+// NewCompileError creates a new custom error.
+func NewCompileError(path string, err error) ErrCompile {
+	if path == "" {
+		return fmt.Errorf("invalid expression: %w", err)
+	}
+	return fmt.Errorf("invalid expression at %s: %w", path, err)
+}
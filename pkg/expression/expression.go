@@ -10,11 +10,19 @@
 //   - Field access: "$.metadata.name", "$.spec.containers[0].image".
 //   - Boolean logic: @and, @or, @not, @eq, @ne, @lt, @gt.
 //   - Conditionals: @cond, @switch, @noop.
-//   - String operations: @concat, @split, @replace, @regex.
-//   - Arithmetic: @add, @sub, @mul, @div, @mod.
-//   - Collections: @len, @contains, @in, @keys, @values.
-//   - Lists: @len, @filter, @any, @none, @all, @map, @min, @max
-//   - Kubernetes: @selector for label matching.
+//   - Variables: @let binds values to names that can be referenced as "$<name>".
+//   - String operations: @concat, @split, @replace, @regex, @regexReplace, @match, @upper,
+//     @lower, @trim, @trimPrefix, @trimSuffix, @hasPrefix, @hasSuffix, @substr.
+//   - Arithmetic: @add, @sub, @mul, @div, @mod, @sum, @abs, @ceil, @floor.
+//   - Collections: @len, @contains, @in.
+//   - Maps: @keys, @values, @entries, @fromEntries, @merge.
+//   - Lists: @len, @filter, @any, @none, @all, @map, @fold, @min, @max, @sort, @sortBy, @unique,
+//     @flatten, @reverse, @slice, @index, @zip.
+//   - Time: @time, @timeFormat, @duration, @timeAdd, @timeDiff, @age.
+//   - Encoding: @hash, @sha256, @base64Encode, @base64Decode, @json, @fromJson, @yaml, @fromYaml.
+//   - Kubernetes: @selector for label matching, @quantity, @quantityAdd, @quantityCmp for
+//     resource quantities.
+//   - Versions: @semver, @semverCmp.
 //
 // Example usage:
 //
@@ -29,24 +37,69 @@
 package expression
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"maps"
 	"math"
 	"math/rand"
 	"reflect"
+	"regexp"
 	"slices"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/grokify/mogo/encoding/base36"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/yaml"
+
+	"github.com/l7mp/dcontroller/pkg/object"
 )
 
+// backrefRegexp matches \N style regex group references in replacement strings.
+var backrefRegexp = regexp.MustCompile(`\\(\d+)`)
+
+// variableNameRegexp matches valid variable names.
+var variableNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
 // EvalCtx defines the context for a running evaluation.
 type EvalCtx struct {
-	Object, Subject any
-	Log             logr.Logger
+	// Object is the object the expression is evaluated on, available as "$".
+	Object any
+	// Subject is the current element in list ops like @map or @filter, available as "$$".
+	Subject any
+	// Bindings maps variable names to values, available as "$<name>". Bindings are created by
+	// @let and @fold ("$acc"). If the value is an *Expression then the binding is a named
+	// sub-expression that is evaluated in the context where it is referenced.
+	Bindings map[string]any
+	Log      logr.Logger
+}
+
+// withSubject returns a copy of the evaluation context with the subject set to the given value.
+func (ctx EvalCtx) withSubject(subject any) EvalCtx {
+	ctx.Subject = subject
+	return ctx
+}
+
+// withBindings returns a copy of the evaluation context extended with the given bindings. The
+// bindings of the original context are left intact.
+func (ctx EvalCtx) withBindings(bindings map[string]any) EvalCtx {
+	scope := make(map[string]any, len(ctx.Bindings)+len(bindings))
+	maps.Copy(scope, ctx.Bindings)
+	maps.Copy(scope, bindings)
+	ctx.Bindings = scope
+	return ctx
+}
+
+// IsValidVariableName checks whether a string can be used as a variable name: it must start with
+// a letter or an underscore, followed by letters, digits and underscores.
+func IsValidVariableName(name string) bool {
+	return variableNameRegexp.MatchString(name)
 }
 
 // Expression defines a single expression op.
@@ -145,7 +198,7 @@ func (e *Expression) Evaluate(ctx EvalCtx) (any, error) {
 		}
 
 		if ret == "@now" {
-			ret = time.Now().UTC().Format("2006-01-02T15:04:05Z07:00")
+			ret = formatTime(time.Now())
 		}
 
 		ctx.Log.V(8).Info("eval ready", "expression", e.String(), "result", ret)
@@ -465,7 +518,7 @@ func (e *Expression) Evaluate(ctx EvalCtx) (any, error) {
 
 			vs := []any{}
 			for _, input := range list {
-				res, err := cond.Evaluate(EvalCtx{Object: ctx.Object, Subject: input, Log: ctx.Log})
+				res, err := cond.Evaluate(ctx.withSubject(input))
 				if err != nil {
 					return nil, err
 				}
@@ -514,7 +567,7 @@ func (e *Expression) Evaluate(ctx EvalCtx) (any, error) {
 
 			v := false
 			for _, input := range list {
-				res, err := exp.Evaluate(EvalCtx{Object: ctx.Object, Subject: input, Log: ctx.Log})
+				res, err := exp.Evaluate(ctx.withSubject(input))
 				if err != nil {
 					return nil, err
 				}
@@ -563,7 +616,7 @@ func (e *Expression) Evaluate(ctx EvalCtx) (any, error) {
 
 			v := false
 			for _, input := range list {
-				res, err := exp.Evaluate(EvalCtx{Object: ctx.Object, Subject: input, Log: ctx.Log})
+				res, err := exp.Evaluate(ctx.withSubject(input))
 				if err != nil {
 					return nil, err
 				}
@@ -611,7 +664,7 @@ func (e *Expression) Evaluate(ctx EvalCtx) (any, error) {
 
 			v := true
 			for _, input := range list {
-				res, err := exp.Evaluate(EvalCtx{Object: ctx.Object, Subject: input, Log: ctx.Log})
+				res, err := exp.Evaluate(ctx.withSubject(input))
 				if err != nil {
 					return nil, err
 				}
@@ -654,7 +707,7 @@ func (e *Expression) Evaluate(ctx EvalCtx) (any, error) {
 
 			vs := []any{}
 			for _, input := range list {
-				res, err := exp.Evaluate(EvalCtx{Object: ctx.Object, Subject: input, Log: ctx.Log})
+				res, err := exp.Evaluate(ctx.withSubject(input))
 				if err != nil {
 					return nil, err
 				}
@@ -666,35 +719,300 @@ func (e *Expression) Evaluate(ctx EvalCtx) (any, error) {
 
 			return vs, nil
 
-			// case "@fold":
-			// 	args, err := asExpOrList(e.Arg)
-			// 	if err != nil {
-			// 		return nil, NewExpressionError(e.Op, e.Raw, err)
-			// 	}
+		case "@sortBy": // @sortBy: [key, list]
+			args, err := AsExpOrExpList(e.Arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			if len(args) != 2 {
+				return nil, NewExpressionError(e,
+					errors.New("invalid arguments: expected 2 arguments"))
+			}
+
+			list, err := args[1].evalList(ctx)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			keys := make([]any, len(list))
+			for i, input := range list {
+				keys[i], err = args[0].Evaluate(ctx.withSubject(input))
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			idx := make([]int, len(list))
+			for i := range idx {
+				idx[i] = i
+			}
+			slices.SortStableFunc(idx, func(a, b int) int { return compareValues(keys[a], keys[b]) })
+
+			v := make([]any, len(list))
+			for i, j := range idx {
+				v[i] = list[j]
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", args, "result", v)
+			return v, nil
+
+		case "@sort":
+			list, err := e.Arg.evalList(ctx)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := slices.Clone(list)
+			slices.SortStableFunc(v, compareValues)
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", list, "result", v)
+			return v, nil
+
+		case "@reverse":
+			list, err := e.Arg.evalList(ctx)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := slices.Clone(list)
+			slices.Reverse(v)
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", list, "result", v)
+			return v, nil
+
+		case "@unique":
+			list, err := e.Arg.evalList(ctx)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			// keep the first occurrence, compare elements by their JSON form
+			v := []any{}
+			seen := map[string]bool{}
+			for _, elem := range list {
+				js, err := json.Marshal(elem)
+				if err != nil {
+					return nil, NewExpressionError(e, err)
+				}
+				if seen[string(js)] {
+					continue
+				}
+				seen[string(js)] = true
+				v = append(v, elem)
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", list, "result", v)
+			return v, nil
+
+		case "@flatten":
+			list, err := e.Arg.evalList(ctx)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			// flatten a single level
+			v := []any{}
+			for _, elem := range list {
+				if elems, ok := elem.([]any); ok {
+					v = append(v, elems...)
+				} else {
+					v = append(v, elem)
+				}
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", list, "result", v)
+			return v, nil
+
+		case "@slice": // @slice: [list, start(, end)]
+			args, err := AsExpOrExpList(e.Arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			if len(args) != 2 && len(args) != 3 {
+				return nil, NewExpressionError(e,
+					errors.New("invalid arguments: expected 2 or 3 arguments"))
+			}
+
+			list, err := args[0].evalList(ctx)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			bounds := []int64{0, int64(len(list))}
+			for i := range args[1:] {
+				b, err := args[i+1].Evaluate(ctx)
+				if err != nil {
+					return nil, err
+				}
+				bounds[i], err = AsInt(b)
+				if err != nil {
+					return nil, NewExpressionError(e, err)
+				}
+			}
+
+			i, j := clampRange(bounds[0], bounds[1], len(list))
+			v := slices.Clone(list[i:j])
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", args, "result", v)
+			return v, nil
+
+		case "@index": // @index: [list, index]
+			args, err := AsExpOrExpList(e.Arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			if len(args) != 2 {
+				return nil, NewExpressionError(e,
+					errors.New("invalid arguments: expected 2 arguments"))
+			}
+
+			list, err := args[0].evalList(ctx)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			rawIdx, err := args[1].Evaluate(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			idx, err := AsInt(rawIdx)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			// negative indices count from the end, out-of-range indices yield nil
+			if idx < 0 {
+				idx += int64(len(list))
+			}
+			var v any
+			if idx >= 0 && idx < int64(len(list)) {
+				v = list[idx]
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", args, "result", v)
+			return v, nil
+
+		case "@zip": // @zip: [list, list, ...]
+			args, err := AsExpOrExpList(e.Arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			lists := make([][]any, len(args))
+			n := -1
+			for i := range args {
+				lists[i], err = args[i].evalList(ctx)
+				if err != nil {
+					return nil, NewExpressionError(e, err)
+				}
+				if n < 0 || len(lists[i]) < n {
+					n = len(lists[i])
+				}
+			}
+
+			// the result is as long as the shortest list
+			v := []any{}
+			for i := 0; i < n; i++ {
+				tuple := make([]any, len(lists))
+				for j := range lists {
+					tuple[j] = lists[j][i]
+				}
+				v = append(v, tuple)
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", args, "result", v)
+			return v, nil
+
+		case "@let": // @let: [{name: exp, ...}, body]
+			args, err := AsExpOrExpList(e.Arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			if len(args) != 2 {
+				return nil, NewExpressionError(e,
+					errors.New("invalid arguments: expected 2 arguments"))
+			}
+
+			defs, ok := args[0].Literal.(map[string]Expression)
+			if args[0].Op != "@dict" || !ok {
+				return nil, NewExpressionError(e,
+					errors.New("invalid arguments: expected a map of bindings"))
+			}
+
+			// all bindings are evaluated in the enclosing scope
+			bindings := make(map[string]any, len(defs))
+			for name, exp := range defs {
+				if !IsValidVariableName(name) {
+					return nil, NewExpressionError(e, fmt.Errorf("invalid variable name %q", name))
+				}
+
+				v, err := exp.Evaluate(ctx)
+				if err != nil {
+					return nil, err
+				}
+				bindings[name] = v
+			}
+
+			v, err := args[1].Evaluate(ctx.withBindings(bindings))
+			if err != nil {
+				return nil, err
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "bindings", bindings, "result", v)
+			return v, nil
+
+		case "@fold", "@reduce":
+			args, err := AsExpOrExpList(e.Arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
 
-			// 	if len(args) == 2 {
-			// 		return nil, NewExpressionError(e.Op, e.Raw, errors.New("not enough arguments"))
-			// 	}
+			if len(args) != 3 {
+				return nil, NewExpressionError(e,
+					errors.New("invalid arguments: expected 3 arguments"))
+			}
+
+			// function
+			exp := args[0]
 
-			// 	var outputs []map[string]any
-			// 	for _, exp := range args {
-			// 		res, err := exp.Evaluate(eng)
-			// 		if err != nil {
-			// 			return nil, NewExpressionError(e.Op, e.Raw,
-			// 				fmt.Errorf("should evaluate to an object list: %s", err))
-			// 		}
+			// initial value of the accumulator
+			acc, err := args[1].Evaluate(ctx)
+			if err != nil {
+				return nil, NewExpressionError(e,
+					fmt.Errorf("failed to evaluate initial value: %w", err))
+			}
 
-			// 		outputs, err = asObjectList(eng.view, res)
-			// 		if err != nil {
-			// 			return nil, err
-			// 		}
+			// arguments
+			rawArg, err := args[2].Evaluate(ctx)
+			if err != nil {
+				return nil, NewExpressionError(e,
+					fmt.Errorf("failed to evaluate arguments: %w", err))
+			}
 
-			// 		eng.inputs = outputs
-			// 	}
+			list := []any{}
+			if rawArg != nil {
+				list, err = AsList(rawArg)
+				if err != nil {
+					return nil, NewExpressionError(e,
+						fmt.Errorf("invalid arguments: expected a list: %w", err))
+				}
+			}
 
-			// 	eng.log.V(4).Info("eval ready", "expression", e.String(), "result", outputs)
+			for _, input := range list {
+				foldCtx := ctx.withSubject(input).withBindings(map[string]any{accumulatorVar: acc})
+				acc, err = exp.Evaluate(foldCtx)
+				if err != nil {
+					return nil, err
+				}
+			}
 
-			// 	return outputs, nil
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", acc)
+			return acc, nil
 		}
 	}
 
@@ -899,87 +1217,551 @@ func (e *Expression) Evaluate(ctx EvalCtx) (any, error) {
 			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", f, "result", v)
 			return v, nil
 
-			// list ops
-		case "@sum":
-			is, fs, kind, err := AsIntOrFloatList(arg)
+			// binary arithmetic: int if both arguments are ints, otherwise float
+		case "@add":
+			is, fs, kind, err := AsBinaryIntOrFloatList(arg)
 			if err != nil {
 				return nil, NewExpressionError(e, err)
 			}
 
 			var v any
 			if kind == reflect.Int64 {
-				vi := int64(0)
-				for i := range is {
-					vi += is[i]
-				}
-				v = vi
+				v = is[0] + is[1]
 			} else {
-				vf := 0.0
-				for i := range fs {
-					vf += fs[i]
-				}
-				v = vf
+				v = fs[0] + fs[1]
 			}
 
 			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
 			return v, nil
 
-		case "@min":
-			is, fs, kind, err := AsIntOrFloatList(arg)
+		case "@sub":
+			is, fs, kind, err := AsBinaryIntOrFloatList(arg)
 			if err != nil {
 				return nil, NewExpressionError(e, err)
 			}
 
 			var v any
-			switch kind {
-			case reflect.Int64:
-				if len(is) != 0 {
-					v = slices.Min(is)
-				}
-			case reflect.Float64:
-				if len(fs) != 0 {
-					v = slices.Min(fs)
-				}
-			default:
+			if kind == reflect.Int64 {
+				v = is[0] - is[1]
+			} else {
+				v = fs[0] - fs[1]
 			}
 
-			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "result", v)
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
 			return v, nil
 
-		case "@max":
-			is, fs, kind, err := AsIntOrFloatList(arg)
+		case "@mul":
+			is, fs, kind, err := AsBinaryIntOrFloatList(arg)
 			if err != nil {
 				return nil, NewExpressionError(e, err)
 			}
 
 			var v any
-			switch kind {
-			case reflect.Int64:
-				if len(is) != 0 {
-					v = slices.Max(is)
-				}
-			case reflect.Float64:
-				if len(fs) != 0 {
-					v = slices.Max(fs)
-				}
-			default:
+			if kind == reflect.Int64 {
+				v = is[0] * is[1]
+			} else {
+				v = fs[0] * fs[1]
 			}
 
-			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "result", v)
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
 			return v, nil
 
-		case "@len":
-			args, err := AsList(arg)
+		case "@div":
+			is, fs, kind, err := AsBinaryIntOrFloatList(arg)
 			if err != nil {
 				return nil, NewExpressionError(e, err)
 			}
 
-			v := int64(len(args))
-			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			var v any
+			if kind == reflect.Int64 {
+				if is[1] == 0 {
+					return nil, NewExpressionError(e, ErrDivisionByZero)
+				}
+				v = is[0] / is[1]
+			} else {
+				if fs[1] == 0.0 {
+					return nil, NewExpressionError(e, ErrDivisionByZero)
+				}
+				v = fs[0] / fs[1]
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
 			return v, nil
 
-		case "@in": // @in: [elem, list]
-			args, err := AsList(arg)
+		case "@mod":
+			is, fs, kind, err := AsBinaryIntOrFloatList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			var v any
+			if kind == reflect.Int64 {
+				if is[1] == 0 {
+					return nil, NewExpressionError(e, ErrDivisionByZero)
+				}
+				v = is[0] % is[1]
+			} else {
+				if fs[1] == 0.0 {
+					return nil, NewExpressionError(e, ErrDivisionByZero)
+				}
+				v = math.Mod(fs[0], fs[1])
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
+			return v, nil
+
+			// list ops
+		case "@sum":
+			is, fs, kind, err := AsIntOrFloatList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			var v any
+			if kind == reflect.Int64 {
+				vi := int64(0)
+				for i := range is {
+					vi += is[i]
+				}
+				v = vi
+			} else {
+				vf := 0.0
+				for i := range fs {
+					vf += fs[i]
+				}
+				v = vf
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
+			return v, nil
+
+		case "@min":
+			is, fs, kind, err := AsIntOrFloatList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			var v any
+			switch kind {
+			case reflect.Int64:
+				if len(is) != 0 {
+					v = slices.Min(is)
+				}
+			case reflect.Float64:
+				if len(fs) != 0 {
+					v = slices.Min(fs)
+				}
+			default:
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "result", v)
+			return v, nil
+
+		case "@max":
+			is, fs, kind, err := AsIntOrFloatList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			var v any
+			switch kind {
+			case reflect.Int64:
+				if len(is) != 0 {
+					v = slices.Max(is)
+				}
+			case reflect.Float64:
+				if len(fs) != 0 {
+					v = slices.Max(fs)
+				}
+			default:
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "result", v)
+			return v, nil
+
+		case "@len":
+			args, err := AsList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := int64(len(args))
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+		case "@in": // @in: [elem, list]
+			args, err := AsList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			if len(args) != 2 {
+				return nil, NewExpressionError(e, errors.New("expected 2 arguments"))
+			}
+
+			elem := args[0]
+			list, err := AsList(args[1])
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := false
+			for i := range list {
+				if reflect.DeepEqual(list[i], elem) {
+					v = true
+					break
+				}
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+			// map ops
+		case "@keys":
+			m, err := AsMap(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := []any{}
+			for _, k := range sortedKeys(m) {
+				v = append(v, k)
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", m, "result", v)
+			return v, nil
+
+		case "@values":
+			m, err := AsMap(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := []any{}
+			for _, k := range sortedKeys(m) {
+				v = append(v, m[k])
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", m, "result", v)
+			return v, nil
+
+		case "@entries":
+			m, err := AsMap(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := []any{}
+			for _, k := range sortedKeys(m) {
+				v = append(v, map[string]any{"key": k, "value": m[k]})
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", m, "result", v)
+			return v, nil
+
+		case "@fromEntries":
+			list, err := AsList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := map[string]any{}
+			for _, elem := range list {
+				entry, err := AsMap(elem)
+				if err != nil {
+					return nil, NewExpressionError(e, fmt.Errorf("invalid entry: %w", err))
+				}
+
+				k, err := AsString(entry["key"])
+				if err != nil {
+					return nil, NewExpressionError(e, fmt.Errorf("invalid entry key: %w", err))
+				}
+
+				v[k] = entry["value"]
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", list, "result", v)
+			return v, nil
+
+		case "@merge":
+			list, err := AsList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			var res any = map[string]any{}
+			for _, elem := range list {
+				// missing maps are skipped
+				if elem == nil {
+					continue
+				}
+
+				m, err := AsMap(elem)
+				if err != nil {
+					return nil, NewExpressionError(e, err)
+				}
+
+				res, err = object.MergeAny(res, object.DeepCopyAny(m))
+				if err != nil {
+					return nil, NewExpressionError(e, err)
+				}
+			}
+
+			v, err := AsMap(res)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", list, "result", v)
+			return v, nil
+
+		case "@concat":
+			args, err := AsStringList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := ""
+			for i := range args {
+				v += args[i]
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+
+			return v, nil
+
+			// string ops
+		case "@upper":
+			str, err := AsString(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := strings.ToUpper(str)
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", str, "result", v)
+			return v, nil
+
+		case "@lower":
+			str, err := AsString(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := strings.ToLower(str)
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", str, "result", v)
+			return v, nil
+
+		case "@trim": // @trim: str or [str, cutset]
+			var v string
+			if IsList(arg) {
+				args, err := AsBinaryStringList(arg)
+				if err != nil {
+					return nil, NewExpressionError(e, err)
+				}
+				v = strings.Trim(args[0], args[1])
+			} else {
+				str, err := AsString(arg)
+				if err != nil {
+					return nil, NewExpressionError(e, err)
+				}
+				v = strings.TrimSpace(str)
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
+			return v, nil
+
+		case "@trimPrefix": // @trimPrefix: [str, prefix]
+			args, err := AsBinaryStringList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := strings.TrimPrefix(args[0], args[1])
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+		case "@trimSuffix": // @trimSuffix: [str, suffix]
+			args, err := AsBinaryStringList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := strings.TrimSuffix(args[0], args[1])
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+		case "@hasPrefix": // @hasPrefix: [str, prefix]
+			args, err := AsBinaryStringList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := strings.HasPrefix(args[0], args[1])
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+		case "@hasSuffix": // @hasSuffix: [str, suffix]
+			args, err := AsBinaryStringList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := strings.HasSuffix(args[0], args[1])
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+		case "@split": // @split: [str, separator]
+			args, err := AsBinaryStringList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := []any{}
+			for _, s := range strings.Split(args[0], args[1]) {
+				v = append(v, s)
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+		case "@replace": // @replace: [str, old, new]
+			args, err := AsStringList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			if len(args) != 3 {
+				return nil, NewExpressionError(e, errors.New("expected 3 arguments"))
+			}
+
+			v := strings.ReplaceAll(args[0], args[1], args[2])
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+		case "@substr": // @substr: [str, start] or [str, start, end]
+			args, err := AsList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			if len(args) != 2 && len(args) != 3 {
+				return nil, NewExpressionError(e,
+					errors.New("invalid arguments: expected 2 (str/start) or 3 (str/start/end) arguments"))
+			}
+
+			str, err := AsString(args[0])
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			runes := []rune(str)
+			start, err := AsInt(args[1])
+			if err != nil {
+				return nil, NewExpressionError(e, fmt.Errorf("invalid start index: %w", err))
+			}
+			end := int64(len(runes))
+			if len(args) == 3 {
+				end, err = AsInt(args[2])
+				if err != nil {
+					return nil, NewExpressionError(e, fmt.Errorf("invalid end index: %w", err))
+				}
+			}
+
+			i, j := clampRange(start, end, len(runes))
+			v := string(runes[i:j])
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+		case "@match": // @match: [regex, str]
+			args, err := AsBinaryStringList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			re, err := regexp.Compile(args[0])
+			if err != nil {
+				return nil, NewExpressionError(e, fmt.Errorf("invalid regular expression %q: %w",
+					args[0], err))
+			}
+
+			v := re.MatchString(args[1])
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+		case "@regex": // @regex: [regex, str]
+			args, err := AsBinaryStringList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			re, err := regexp.Compile(args[0])
+			if err != nil {
+				return nil, NewExpressionError(e, fmt.Errorf("invalid regular expression %q: %w",
+					args[0], err))
+			}
+
+			// return the full match followed by the capture groups, or nil if there is no match
+			var v any
+			if ms := re.FindStringSubmatch(args[1]); ms != nil {
+				vs := make([]any, len(ms))
+				for i := range ms {
+					vs[i] = ms[i]
+				}
+				v = vs
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+		case "@regexReplace": // @regexReplace: [regex, str, replacement]
+			args, err := AsStringList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			if len(args) != 3 {
+				return nil, NewExpressionError(e, errors.New("expected 3 arguments"))
+			}
+
+			re, err := regexp.Compile(args[0])
+			if err != nil {
+				return nil, NewExpressionError(e, fmt.Errorf("invalid regular expression %q: %w",
+					args[0], err))
+			}
+
+			// strings starting with "$" are JSONPaths so allow \N style group references
+			repl := backrefRegexp.ReplaceAllString(args[2], "$${$1}")
+			v := re.ReplaceAllString(args[1], repl)
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+			// time ops: times are RFC3339 strings, durations are seconds
+		case "@time": // @time: time or [time, layout]
+			var t time.Time
+			if IsList(arg) {
+				args, err := AsBinaryStringList(arg)
+				if err != nil {
+					return nil, NewExpressionError(e, err)
+				}
+
+				t, err = time.Parse(args[1], args[0])
+				if err != nil {
+					return nil, NewExpressionError(e, fmt.Errorf("invalid time %q for layout %q: %w",
+						args[0], args[1], err))
+				}
+			} else {
+				t, err = AsTime(arg)
+				if err != nil {
+					return nil, NewExpressionError(e, err)
+				}
+			}
+
+			v := formatTime(t)
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
+			return v, nil
+
+		case "@timeFormat": // @timeFormat: [time, layout]
+			args, err := AsList(arg)
 			if err != nil {
 				return nil, NewExpressionError(e, err)
 			}
@@ -988,36 +1770,181 @@ func (e *Expression) Evaluate(ctx EvalCtx) (any, error) {
 				return nil, NewExpressionError(e, errors.New("expected 2 arguments"))
 			}
 
-			elem := args[0]
-			list, err := AsList(args[1])
+			t, err := AsTime(args[0])
 			if err != nil {
 				return nil, NewExpressionError(e, err)
 			}
 
-			v := false
-			for i := range list {
-				if reflect.DeepEqual(list[i], elem) {
-					v = true
-					break
-				}
+			layout, err := AsString(args[1])
+			if err != nil {
+				return nil, NewExpressionError(e, err)
 			}
 
+			v := t.UTC().Format(layout)
 			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
 			return v, nil
 
-		case "@concat":
-			args, err := AsStringList(arg)
+		case "@duration":
+			d, err := AsDuration(arg)
 			if err != nil {
 				return nil, NewExpressionError(e, err)
 			}
 
-			v := ""
-			for i := range args {
-				v += args[i]
+			v := durationSeconds(d)
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
+			return v, nil
+
+		case "@timeAdd": // @timeAdd: [time, duration]
+			args, err := AsList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			if len(args) != 2 {
+				return nil, NewExpressionError(e, errors.New("expected 2 arguments"))
+			}
+
+			t, err := AsTime(args[0])
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			d, err := AsDuration(args[1])
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := formatTime(t.Add(d))
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+		case "@timeDiff": // @timeDiff: [time1, time2]
+			args, err := AsList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			if len(args) != 2 {
+				return nil, NewExpressionError(e, errors.New("expected 2 arguments"))
+			}
+
+			t1, err := AsTime(args[0])
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			t2, err := AsTime(args[1])
+			if err != nil {
+				return nil, NewExpressionError(e, err)
 			}
 
+			v := durationSeconds(t1.Sub(t2))
 			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
+			return v, nil
+
+		case "@age":
+			t, err := AsTime(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := durationSeconds(time.Since(t).Truncate(time.Second))
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
+			return v, nil
+
+			// Kubernetes resource quantity ops
+		case "@quantity":
+			q, err := AsQuantity(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			var v any
+			if i, ok := q.AsInt64(); ok {
+				v = i
+			} else {
+				v = q.AsApproximateFloat64()
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
+			return v, nil
+
+		case "@quantityAdd": // @quantityAdd: [quantity, quantity, ...]
+			qs, err := AsQuantityList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			sum := resource.Quantity{}
+			for i := range qs {
+				sum.Add(qs[i])
+			}
+
+			v := sum.String()
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
+			return v, nil
+
+		case "@quantityCmp": // @quantityCmp: [quantity, quantity]
+			qs, err := AsQuantityList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			if len(qs) != 2 {
+				return nil, NewExpressionError(e, errors.New("expected 2 arguments"))
+			}
+
+			v := int64(qs[0].Cmp(qs[1]))
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
+			return v, nil
+
+			// version ops
+		case "@semver":
+			ver, err := AsVersion(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := map[string]any{
+				"major":         int64(ver.Major()),
+				"minor":         int64(ver.Minor()),
+				"patch":         int64(ver.Patch()),
+				"preRelease":    ver.PreRelease(),
+				"buildMetadata": ver.BuildMetadata(),
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", arg, "result", v)
+			return v, nil
 
+		case "@semverCmp": // @semverCmp: [version, version]
+			args, err := AsList(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			if len(args) != 2 {
+				return nil, NewExpressionError(e, errors.New("expected 2 arguments"))
+			}
+
+			v1, err := AsVersion(args[0])
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v2, err := AsVersion(args[1])
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := int64(0)
+			switch {
+			case v1.LessThan(v2):
+				v = -1
+			case v1.GreaterThan(v2):
+				v = 1
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "arg", args, "result", v)
 			return v, nil
 
 		case "@hash":
@@ -1037,6 +1964,102 @@ func (e *Expression) Evaluate(ctx EvalCtx) (any, error) {
 
 			return v, nil
 
+			// encoding ops
+		case "@sha256":
+			// strings are hashed as is, other values are hashed in their JSON form
+			data, ok := arg.(string)
+			if !ok {
+				js, err := json.Marshal(arg)
+				if err != nil {
+					return nil, NewExpressionError(e, fmt.Errorf("failed to marshal value to JSON: %w", err))
+				}
+				data = string(js)
+			}
+
+			sum := sha256.Sum256([]byte(data))
+			v := hex.EncodeToString(sum[:])
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", arg, "result", v)
+			return v, nil
+
+		case "@base64Encode":
+			str, err := AsString(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			v := base64.StdEncoding.EncodeToString([]byte(str))
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", arg, "result", v)
+			return v, nil
+
+		case "@base64Decode":
+			str, err := AsString(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			data, err := base64.StdEncoding.DecodeString(str)
+			if err != nil {
+				return nil, NewExpressionError(e, fmt.Errorf("invalid base64 string: %w", err))
+			}
+
+			v := string(data)
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", arg, "result", v)
+			return v, nil
+
+		case "@json":
+			js, err := json.Marshal(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, fmt.Errorf("failed to marshal value to JSON: %w", err))
+			}
+
+			v := string(js)
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", arg, "result", v)
+			return v, nil
+
+		case "@fromJson":
+			str, err := AsString(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			var v any
+			if err := json.Unmarshal([]byte(str), &v); err != nil {
+				return nil, NewExpressionError(e, fmt.Errorf("invalid JSON: %w", err))
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", arg, "result", v)
+			return v, nil
+
+		case "@yaml":
+			y, err := yaml.Marshal(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, fmt.Errorf("failed to marshal value to YAML: %w", err))
+			}
+
+			v := string(y)
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", arg, "result", v)
+			return v, nil
+
+		case "@fromYaml":
+			str, err := AsString(arg)
+			if err != nil {
+				return nil, NewExpressionError(e, err)
+			}
+
+			// convert to JSON first so that numbers are unmarshaled the same way as in @fromJson
+			js, err := yaml.YAMLToJSON([]byte(str))
+			if err != nil {
+				return nil, NewExpressionError(e, fmt.Errorf("invalid YAML: %w", err))
+			}
+
+			var v any
+			if err := json.Unmarshal(js, &v); err != nil {
+				return nil, NewExpressionError(e, fmt.Errorf("invalid YAML: %w", err))
+			}
+
+			ctx.Log.V(8).Info("eval ready", "expression", e.String(), "args", arg, "result", v)
+			return v, nil
+
 		default:
 			return nil, NewExpressionError(e, fmt.Errorf("unknown op %q", e.Op))
 		}
@@ -1045,3 +2068,145 @@ func (e *Expression) Evaluate(ctx EvalCtx) (any, error) {
 	// literal map
 	return map[string]any{e.Op: arg}, nil
 }
+
+// clampRange normalizes a [start, end) index range over a sequence of the given length. Negative
+// indices count from the end of the sequence and out-of-range indices are clamped to the bounds.
+func clampRange(start, end int64, length int) (int, int) {
+	l := int64(length)
+	if start < 0 {
+		start += l
+	}
+	if end < 0 {
+		end += l
+	}
+	start = max(0, min(start, l))
+	end = max(start, min(end, l))
+	return int(start), int(end)
+}
+
+// sortedKeys returns the keys of a map in lexicographic order so that map iteration yields a
+// deterministic result.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// formatTime returns the RFC3339 representation of a time in UTC, the same format Kubernetes uses
+// for timestamps.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// durationSeconds returns a duration in seconds, as an integer if the duration is a whole number
+// of seconds and as a float otherwise.
+func durationSeconds(d time.Duration) any {
+	if d%time.Second == 0 {
+		return int64(d / time.Second)
+	}
+	return d.Seconds()
+}
+
+// evalList evaluates an expression into a list. Unlike the @list op, literal lists are evaluated
+// elementwise so that nested lists are retained. A nil result is converted into an empty list.
+func (e *Expression) evalList(ctx EvalCtx) ([]any, error) {
+	if e == nil {
+		return nil, errors.New("empty argument list")
+	}
+
+	if exps, ok := e.Literal.([]Expression); ok && e.Op == "@list" && e.Arg == nil {
+		ret := make([]any, len(exps))
+		for i := range exps {
+			v, err := exps[i].Evaluate(ctx)
+			if err != nil {
+				return nil, err
+			}
+			ret[i] = v
+		}
+		return ret, nil
+	}
+
+	v, err := e.Evaluate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return []any{}, nil
+	}
+
+	return AsList(v)
+}
+
+// compareValues implements a total order over arbitrary values. Values of different types are
+// ordered as nil < bool < number < string < list < map. Ints and floats are compared numerically,
+// lists are compared elementwise and maps are compared by their JSON form.
+func compareValues(a, b any) int {
+	ra, rb := valueRank(a), valueRank(b)
+	if ra != rb {
+		return cmp.Compare(ra, rb)
+	}
+
+	switch va := a.(type) {
+	case bool:
+		vb := b.(bool)
+		switch {
+		case va == vb:
+			return 0
+		case !va:
+			return -1
+		default:
+			return 1
+		}
+	case string:
+		return strings.Compare(va, b.(string))
+	case []any:
+		vb := b.([]any)
+		for i := 0; i < len(va) && i < len(vb); i++ {
+			if c := compareValues(va[i], vb[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(va), len(vb))
+	case map[string]any:
+		ja, _ := json.Marshal(va)
+		jb, _ := json.Marshal(b)
+		return strings.Compare(string(ja), string(jb))
+	}
+
+	// numbers
+	if ia, ok := a.(int64); ok {
+		if ib, ok := b.(int64); ok {
+			return cmp.Compare(ia, ib)
+		}
+	}
+	fa, _ := AsFloat(a)
+	fb, _ := AsFloat(b)
+	return cmp.Compare(fa, fb)
+}
+
+// valueRank returns the rank of the type of a value in the order implemented by compareValues.
+func valueRank(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case string:
+		return 3
+	case []any:
+		return 4
+	case map[string]any:
+		return 5
+	}
+
+	if _, err := AsFloat(v); err == nil {
+		return 2
+	}
+
+	// unknown types are ordered last
+	return 6
+}
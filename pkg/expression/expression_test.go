@@ -1,6 +1,7 @@
 package expression
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -1377,6 +1378,288 @@ var _ = Describe("Expressions", func() {
 		// })
 	})
 
+	Describe("Evaluating @fold expressions", func() {
+		var pod map[string]any
+
+		BeforeEach(func() {
+			pod = map[string]any{
+				"spec": map[string]any{
+					"containers": []any{
+						map[string]any{
+							"name":      "nginx",
+							"resources": map[string]any{"requests": map[string]any{"cpu": int64(100)}},
+							"ports": []any{
+								map[string]any{"name": "http", "containerPort": int64(80)},
+								map[string]any{"name": "https", "containerPort": int64(443)},
+							},
+						},
+						map[string]any{
+							"name":      "sidecar",
+							"resources": map[string]any{"requests": map[string]any{"cpu": int64(50)}},
+						},
+					},
+				},
+			}
+		})
+
+		It("should sum a field across a list", func() {
+			jsonData := `{"@fold":[{"@add":["$acc","$$.resources.requests.cpu"]},0,"$.spec.containers"]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: pod, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(int64(150)))
+		})
+
+		It("should concatenate strings with the @reduce alias", func() {
+			jsonData := `{"@reduce":[{"@concat":["$acc",",","$$.name"]},"ports","$.spec.containers[0].ports"]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: pod, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal("ports,http,https"))
+		})
+
+		It("should access fields of a map accumulator", func() {
+			jsonData := `{"@fold":[{"count":{"@add":["$acc.count",1]},"max":{"@max":["$acc.max","$$"]}},{"count":0,"max":0},[3,7,5]]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: pod, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(map[string]any{"count": int64(3), "max": int64(7)}))
+		})
+
+		It("should make the accumulator available in nested list ops", func() {
+			jsonData := `{"@fold":[{"@add":["$acc",{"@len":{"@filter":[{"@gt":["$$.containerPort","$acc"]},"$.spec.containers[0].ports"]}}]},0,"$.spec.containers"]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: pod, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(int64(4)))
+		})
+
+		It("should return the initial value for an empty or missing list", func() {
+			for _, jsonData := range []string{
+				`{"@fold":[{"@add":["$acc","$$"]},42,[]]}`,
+				`{"@fold":[{"@add":["$acc","$$"]},42,"$.spec.nonexistent"]}`,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				res, err := exp.Evaluate(EvalCtx{Object: pod, Log: logger})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(int64(42)), jsonData)
+			}
+		})
+
+		It("should reject invalid arguments", func() {
+			for _, jsonData := range []string{
+				`{"@fold":[{"@add":["$acc","$$"]},[1,2]]}`,
+				`{"@fold":[{"@add":["$acc","$$"]},0,"x"]}`,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = exp.Evaluate(EvalCtx{Object: pod, Log: logger})
+				Expect(err).To(HaveOccurred(), jsonData)
+			}
+		})
+	})
+
+	Describe("Evaluating @let expressions", func() {
+		It("should bind variables", func() {
+			jsonData := `{"@let":[{"a":"$.spec.a","b":{"@add":["$.spec.b.c",1]}},{"sum":{"@add":["$a","$b"]},"a":"$a"}]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj1.UnstructuredContent(), Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(map[string]any{"sum": int64(4), "a": int64(1)}))
+		})
+
+		It("should allow JSONPaths rooted at variables", func() {
+			jsonData := `{"@let":[{"spec":"$.spec"},{"@concat":["$spec.b.c","-",{"@string":"$spec.x[2]"}]}]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj1.UnstructuredContent(), Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal("2-3"))
+		})
+
+		It("should make variables available in nested list ops", func() {
+			jsonData := `{"@let":[{"min":{"@add":["$.spec.a",1]}},{"@filter":[{"@gt":["$$","$min"]},"$.spec.x"]}]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj1.UnstructuredContent(), Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal([]any{int64(3), int64(4), int64(5)}))
+		})
+
+		It("should shadow outer variables in nested scopes", func() {
+			jsonData := `{"@let":[{"x":1,"y":2},[{"@let":[{"x":10},{"@add":["$x","$y"]}]},"$x"]]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj1.UnstructuredContent(), Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal([]any{int64(12), int64(1)}))
+		})
+
+		It("should evaluate named sub-expressions in the referencing context", func() {
+			exp := Expression{Op: "@string", Literal: "$double"}
+			double := &Expression{Op: "@mul", Arg: &Expression{Op: "@list", Literal: []Expression{
+				{Op: "@string", Literal: "$.spec.a"},
+				{Op: "@int", Literal: int64(2)},
+			}}}
+
+			res, err := exp.Evaluate(EvalCtx{
+				Object:   obj1.UnstructuredContent(),
+				Bindings: map[string]any{"double": double},
+				Log:      logger,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(int64(2)))
+
+			// self-reference must not recurse
+			_, err = exp.Evaluate(EvalCtx{
+				Object:   obj1.UnstructuredContent(),
+				Bindings: map[string]any{"double": &Expression{Op: "@string", Literal: "$double"}},
+				Log:      logger,
+			})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject invalid bindings and undefined variables", func() {
+			for _, jsonData := range []string{
+				`{"@let":[{"a":1}]}`,
+				`{"@let":[[1,2],"$a"]}`,
+				`{"@let":[{"a-b":1},1]}`,
+				`{"@let":[{"a":1},"$b"]}`,
+				`{"@let":[{"a":1,"b":"$a"},"$b"]}`,
+				`"$undefined.field"`,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = exp.Evaluate(EvalCtx{Object: obj1.UnstructuredContent(), Log: logger})
+				Expect(err).To(HaveOccurred(), jsonData)
+			}
+		})
+	})
+
+	Describe("Evaluating list manipulation operators", func() {
+		var obj map[string]any
+
+		BeforeEach(func() {
+			obj = map[string]any{
+				"spec": map[string]any{
+					"nums": []any{int64(3), 1.5, int64(-2), int64(3), "b", "a", nil, true},
+					"endpoints": []any{
+						map[string]any{"name": "ep-b", "addresses": []any{"10.0.0.2", "10.0.0.3"}, "port": int64(80)},
+						map[string]any{"name": "ep-a", "addresses": []any{"10.0.0.1"}, "port": int64(443)},
+						map[string]any{"name": "ep-c", "addresses": []any{"10.0.0.2"}, "port": int64(80)},
+					},
+				},
+			}
+		})
+
+		It("should evaluate list manipulation expressions", func() {
+			for jsonData, expected := range map[string]any{
+				`{"@sort":"$.spec.nums"}`:        []any{nil, true, int64(-2), 1.5, int64(3), int64(3), "a", "b"},
+				`{"@sort":[[2,1],[1,3],[1]]}`:    []any{[]any{int64(1)}, []any{int64(1), int64(3)}, []any{int64(2), int64(1)}},
+				`{"@reverse":[1,2,3]}`:           []any{int64(3), int64(2), int64(1)},
+				`{"@unique":"$.spec.nums"}`:      []any{int64(3), 1.5, int64(-2), "b", "a", nil, true},
+				`{"@flatten":[[1,2],3,[4,[5]]]}`: []any{int64(1), int64(2), int64(3), int64(4), []any{int64(5)}},
+				`{"@slice":["$.spec.nums",1,3]}`: []any{1.5, int64(-2)},
+				`{"@slice":[[1,2,3,4],-2]}`:      []any{int64(3), int64(4)},
+				`{"@slice":[[1,2,3,4],5,10]}`:    []any{},
+				`{"@index":["$.spec.nums",1]}`:   1.5,
+				`{"@index":[[1,2,3],-1]}`:        int64(3),
+				`{"@zip":[["a","b","c"],[1,2]]}`: []any{[]any{"a", int64(1)}, []any{"b", int64(2)}},
+				`{"@sort":"$.spec.nonexistent"}`: []any{},
+				`{"@sortBy":["$$.name","$.spec.endpoints"]}`: []any{
+					obj["spec"].(map[string]any)["endpoints"].([]any)[1],
+					obj["spec"].(map[string]any)["endpoints"].([]any)[0],
+					obj["spec"].(map[string]any)["endpoints"].([]any)[2],
+				},
+				`{"@sortBy":["$$.port","$.spec.endpoints"]}`: []any{
+					obj["spec"].(map[string]any)["endpoints"].([]any)[0],
+					obj["spec"].(map[string]any)["endpoints"].([]any)[2],
+					obj["spec"].(map[string]any)["endpoints"].([]any)[1],
+				},
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				res, err := exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(expected), jsonData)
+			}
+		})
+
+		It("should return nil for an out-of-range index", func() {
+			jsonData := `{"@index":[[1,2,3],3]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(BeNil())
+		})
+
+		It("should produce a deterministic address list", func() {
+			jsonData := `{"@sort":{"@unique":{"@flatten":{"@map":["$$.addresses","$.spec.endpoints"]}}}}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal([]any{"10.0.0.1", "10.0.0.2", "10.0.0.3"}))
+
+			// the input must not be modified
+			Expect(obj["spec"].(map[string]any)["endpoints"].([]any)[0].(map[string]any)["addresses"]).
+				To(Equal([]any{"10.0.0.2", "10.0.0.3"}))
+		})
+
+		It("should reject invalid arguments", func() {
+			for _, jsonData := range []string{
+				`{"@sort":"$.spec.endpoints[0].name"}`,
+				`{"@slice":[[1,2,3]]}`,
+				`{"@slice":[[1,2,3],"a"]}`,
+				`{"@index":[[1,2,3]]}`,
+				`{"@sortBy":["$$.name"]}`,
+				`{"@zip":[[1,2],"a"]}`,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+				Expect(err).To(HaveOccurred(), jsonData)
+			}
+		})
+	})
+
 	Describe("Evaluating literal @dict expressions", func() {
 		It("should deserialize and evaluate a constant literal map expression", func() {
 			jsonData := `{"a":1, "b":{"c":"x"}}`
@@ -1445,6 +1728,646 @@ var _ = Describe("Expressions", func() {
 		})
 	})
 
+	Describe("Evaluating string operators", func() {
+		It("should evaluate @upper, @lower and @trim expressions", func() {
+			for jsonData, expected := range map[string]string{
+				`{"@upper":"$.metadata.name"}`:               "NAME",
+				`{"@lower":"AbC"}`:                           "abc",
+				`{"@trim":"  abc  "}`:                        "abc",
+				`{"@trim":["--abc-","-"]}`:                   "abc",
+				`{"@trimPrefix":["api.example","api."]}`:     "example",
+				`{"@trimSuffix":["api.example",".example"]}`: "api",
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+				res, err := exp.Evaluate(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(expected), jsonData)
+			}
+		})
+
+		It("should evaluate @hasPrefix and @hasSuffix expressions", func() {
+			jsonData := `{"@and":[{"@hasPrefix":["$.metadata.name","na"]},{"@hasSuffix":["$.metadata.name","me"]}]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+			res, err := exp.Evaluate(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(BeTrue())
+		})
+
+		It("should evaluate a @split expression", func() {
+			jsonData := `{"@split":["example.com:8080",":"]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+			res, err := exp.Evaluate(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal([]any{"example.com", "8080"}))
+		})
+
+		It("should evaluate a @replace expression", func() {
+			jsonData := `{"@replace":["a.b.c",".","-"]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+			res, err := exp.Evaluate(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal("a-b-c"))
+		})
+
+		It("should err for a @replace expression with invalid arguments", func() {
+			jsonData := `{"@replace":["a.b.c","."]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+			_, err = exp.Evaluate(ctx)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should evaluate @substr expressions", func() {
+			for jsonData, expected := range map[string]string{
+				`{"@substr":["abcdef",2]}`:     "cdef",
+				`{"@substr":["abcdef",1,3]}`:   "bc",
+				`{"@substr":["abcdef",-2]}`:    "ef",
+				`{"@substr":["abcdef",0,-1]}`:  "abcde",
+				`{"@substr":["abcdef",4,100]}`: "ef",
+				`{"@substr":["abcdef",4,2]}`:   "",
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+				res, err := exp.Evaluate(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(expected), jsonData)
+			}
+		})
+
+		It("should evaluate a @match expression", func() {
+			jsonData := `{"@match":["^na.*$","$.metadata.name"]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+			res, err := exp.Evaluate(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(BeTrue())
+		})
+
+		It("should evaluate a @regex expression with capture groups", func() {
+			jsonData := `{"@regex":["^(.*):([0-9]+)$","example.com:8080"]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+			res, err := exp.Evaluate(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal([]any{"example.com:8080", "example.com", "8080"}))
+		})
+
+		It("should evaluate a non-matching @regex expression to nil", func() {
+			jsonData := `{"@regex":["^(.*):([0-9]+)$","example.com"]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+			res, err := exp.Evaluate(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(BeNil())
+		})
+
+		It("should err for an invalid regular expression", func() {
+			jsonData := `{"@regex":["(abc","abc"]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+			_, err = exp.Evaluate(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid regular expression"))
+		})
+
+		It("should evaluate a @regexReplace expression", func() {
+			jsonData := `{"@regexReplace":["^([^:]+):(.*)$","nginx:1.25","\\1-${2}"]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+			res, err := exp.Evaluate(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal("nginx-1.25"))
+		})
+	})
+
+	Describe("Evaluating arithmetic operators", func() {
+		It("should evaluate integer arithmetic expressions", func() {
+			for jsonData, expected := range map[string]int64{
+				`{"@add":["$.spec.a",{"@int":2}]}`:       3,
+				`{"@sub":["$.spec.b.c","$.spec.a"]}`:     1,
+				`{"@mul":[{"@len":"$.spec.x"},8080]}`:    40400,
+				`{"@div":[7,2]}`:                         3,
+				`{"@mod":[{"@sum":"$.spec.x"},4]}`:       3,
+				`{"@sub":[1,{"@add":["$.spec.a",1]}]}`:   -1,
+				`{"@div":[{"@mul":["$.spec.b.c",5]},3]}`: 3,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+				res, err := exp.Evaluate(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(expected), jsonData)
+			}
+		})
+
+		It("should promote mixed int/float arithmetic expressions to float", func() {
+			for jsonData, expected := range map[string]float64{
+				`{"@add":["$.spec.a",0.5]}`:    1.5,
+				`{"@sub":[2.5,"$.spec.b.c"]}`:  0.5,
+				`{"@mul":[0.25,"$.spec.b.c"]}`: 0.5,
+				`{"@div":[7,2.0]}`:             3.5,
+				`{"@div":[{"@float":7},2]}`:    3.5,
+				`{"@mod":[7.5,2]}`:             1.5,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+				res, err := exp.Evaluate(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(expected), jsonData)
+			}
+		})
+
+		It("should return an error on division by zero", func() {
+			for _, jsonData := range []string{
+				`{"@div":["$.spec.a",0]}`,
+				`{"@div":[1.5,0.0]}`,
+				`{"@mod":["$.spec.a",0]}`,
+				`{"@mod":[1.5,0.0]}`,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+				_, err = exp.Evaluate(ctx)
+				Expect(err).To(HaveOccurred(), jsonData)
+				Expect(errors.Is(err, ErrDivisionByZero)).To(BeTrue(), jsonData)
+			}
+		})
+
+		It("should reject invalid arguments", func() {
+			for _, jsonData := range []string{
+				`{"@add":[1,2,3]}`,
+				`{"@sub":[1]}`,
+				`{"@mul":[1,"a"]}`,
+				`{"@div":"$.spec.a"}`,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx := EvalCtx{Object: obj1.UnstructuredContent(), Log: logger}
+				_, err = exp.Evaluate(ctx)
+				Expect(err).To(HaveOccurred(), jsonData)
+			}
+		})
+	})
+
+	Describe("Evaluating map operators", func() {
+		var obj map[string]any
+
+		BeforeEach(func() {
+			obj = map[string]any{
+				"metadata": map[string]any{
+					"name": "test",
+					"labels": map[string]any{
+						"app":                    "nginx",
+						"example.com/tier":       "frontend",
+						"example.com/managed-by": "dcontroller",
+					},
+				},
+				"spec": map[string]any{
+					"a": map[string]any{"x": int64(1), "y": map[string]any{"z": int64(2)}},
+					"b": map[string]any{"y": map[string]any{"w": int64(3)}},
+				},
+			}
+		})
+
+		It("should evaluate @keys, @values and @entries expressions", func() {
+			for jsonData, expected := range map[string]any{
+				`{"@keys":"$.metadata.labels"}`:   []any{"app", "example.com/managed-by", "example.com/tier"},
+				`{"@values":"$.metadata.labels"}`: []any{"nginx", "dcontroller", "frontend"},
+				`{"@entries":{"a":1,"b":"x"}}`: []any{
+					map[string]any{"key": "a", "value": int64(1)},
+					map[string]any{"key": "b", "value": "x"},
+				},
+				`{"@len":{"@keys":"$.metadata.labels"}}`: int64(3),
+				`{"@keys":{}}`:                           []any{},
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				res, err := exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(expected), jsonData)
+			}
+		})
+
+		It("should copy the labels with a given prefix", func() {
+			jsonData := `{"@fromEntries":{"@filter":[{"@hasPrefix":["$$.key","example.com/"]},{"@entries":"$.metadata.labels"}]}}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(map[string]any{
+				"example.com/tier":       "frontend",
+				"example.com/managed-by": "dcontroller",
+			}))
+		})
+
+		It("should rewrite the entries of a map", func() {
+			jsonData := `{"@fromEntries":{"@map":[{"key":{"@upper":"$$.key"},"value":"$$.value"},{"@entries":{"a":1,"b":2}}]}}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(map[string]any{"A": int64(1), "B": int64(2)}))
+		})
+
+		It("should reject invalid entries", func() {
+			for _, jsonData := range []string{
+				`{"@fromEntries":["a","b"]}`,
+				`{"@fromEntries":[{"value":"a"}]}`,
+				`{"@keys":["a","b"]}`,
+				`{"@values":"x"}`,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+				Expect(err).To(HaveOccurred(), jsonData)
+			}
+		})
+
+		It("should deep-merge maps with @merge", func() {
+			jsonData := `{"@merge":["$.spec.a","$.spec.b",{"x":10}]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(map[string]any{
+				"x": int64(10),
+				"y": map[string]any{"z": int64(2), "w": int64(3)},
+			}))
+
+			// the inputs must not be modified
+			Expect(obj["spec"]).To(Equal(map[string]any{
+				"a": map[string]any{"x": int64(1), "y": map[string]any{"z": int64(2)}},
+				"b": map[string]any{"y": map[string]any{"w": int64(3)}},
+			}))
+		})
+
+		It("should skip missing maps in @merge", func() {
+			jsonData := `{"@merge":["$.metadata.annotations",{"a":"b"}]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(map[string]any{"a": "b"}))
+		})
+	})
+
+	Describe("Evaluating time operators", func() {
+		var obj map[string]any
+
+		BeforeEach(func() {
+			obj = map[string]any{
+				"metadata": map[string]any{
+					"name":              "test",
+					"creationTimestamp": "2024-03-01T12:00:00Z",
+				},
+				"status": map[string]any{
+					"lastTransitionTime": "2024-03-01T12:30:15+02:00",
+				},
+			}
+		})
+
+		It("should evaluate time and duration expressions", func() {
+			for jsonData, expected := range map[string]any{
+				`{"@time":"2024-03-01T14:00:00+02:00"}`:                                        "2024-03-01T12:00:00Z",
+				`{"@time":1709294400}`:                                                         "2024-03-01T12:00:00Z",
+				`{"@time":["01/03/2024 12:00","02/01/2006 15:04"]}`:                            "2024-03-01T12:00:00Z",
+				`{"@timeFormat":["$.metadata.creationTimestamp","2006-01-02"]}`:                "2024-03-01",
+				`{"@duration":"5m"}`:                                                           int64(300),
+				`{"@duration":"1.5s"}`:                                                         1.5,
+				`{"@duration":90}`:                                                             int64(90),
+				`{"@timeAdd":["$.metadata.creationTimestamp","1h30m"]}`:                        "2024-03-01T13:30:00Z",
+				`{"@timeAdd":["$.metadata.creationTimestamp",-60]}`:                            "2024-03-01T11:59:00Z",
+				`{"@timeDiff":["$.status.lastTransitionTime","$.metadata.creationTimestamp"]}`: int64(-5385),
+				`{"@timeDiff":["2024-03-01T12:00:00.5Z","$.metadata.creationTimestamp"]}`:      0.5,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				res, err := exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(expected), jsonData)
+			}
+		})
+
+		It("should compute the age of an object", func() {
+			obj["metadata"].(map[string]any)["creationTimestamp"] =
+				time.Now().Add(-10 * time.Minute).UTC().Format(time.RFC3339)
+
+			jsonData := `{"@age":"$.metadata.creationTimestamp"}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(BeNumerically("~", 600, 2))
+		})
+
+		It("should compare the age of an object to a duration", func() {
+			jsonData := `{"@gt":[{"@age":"$.metadata.creationTimestamp"},{"@duration":"5m"}]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(BeTrue())
+
+			obj["metadata"].(map[string]any)["creationTimestamp"] = time.Now().UTC().Format(time.RFC3339)
+			res, err = exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(BeFalse())
+		})
+
+		It("should compare a timestamp to the current time", func() {
+			jsonData := `{"@lt":[{"@timeDiff":[{"@timeAdd":["$.metadata.creationTimestamp","1h"]},"@now"]},0]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(BeTrue())
+		})
+
+		It("should reject invalid times and durations", func() {
+			for _, jsonData := range []string{
+				`{"@time":"yesterday"}`,
+				`{"@time":["2024-03-01","15:04"]}`,
+				`{"@duration":"5 minutes"}`,
+				`{"@timeAdd":["$.metadata.creationTimestamp"]}`,
+				`{"@timeDiff":["$.metadata.creationTimestamp","tomorrow"]}`,
+				`{"@age":"$.metadata.deletionTimestamp"}`,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = exp.Evaluate(EvalCtx{Object: obj, Log: logger})
+				Expect(err).To(HaveOccurred(), jsonData)
+			}
+		})
+	})
+
+	Describe("Evaluating quantity and version operators", func() {
+		var pod map[string]any
+
+		BeforeEach(func() {
+			pod = map[string]any{
+				"spec": map[string]any{
+					"containers": []any{
+						map[string]any{
+							"image": "nginx:1.25.3",
+							"resources": map[string]any{
+								"requests": map[string]any{"cpu": "500m", "memory": "1Gi"},
+							},
+						},
+						map[string]any{
+							"image": "envoy:v1.30.0-rc.1",
+							"resources": map[string]any{
+								"requests": map[string]any{"cpu": int64(1), "memory": "512Mi"},
+							},
+						},
+					},
+				},
+			}
+		})
+
+		It("should evaluate quantity expressions", func() {
+			for jsonData, expected := range map[string]any{
+				`{"@quantity":"$.spec.containers[0].resources.requests.cpu"}`:    0.5,
+				`{"@quantity":"$.spec.containers[0].resources.requests.memory"}`: int64(1073741824),
+				`{"@quantity":"2k"}`:                          int64(2000),
+				`{"@quantity":3}`:                             int64(3),
+				`{"@quantityAdd":["500m",1,"250m"]}`:          "1750m",
+				`{"@quantityAdd":["1Gi","512Mi"]}`:            "1536Mi",
+				`{"@quantityCmp":["1Gi","1024Mi"]}`:           int64(0),
+				`{"@quantityCmp":["1G","1Gi"]}`:               int64(-1),
+				`{"@quantityCmp":["1.5",{"@string":"800m"}]}`: int64(1),
+				`{"@sum":{"@map":[{"@quantity":"$$.resources.requests.cpu"},"$.spec.containers"]}}`: 1.5,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				res, err := exp.Evaluate(EvalCtx{Object: pod, Log: logger})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(expected), jsonData)
+			}
+		})
+
+		It("should sum the memory requests of the containers", func() {
+			jsonData := `{"@fold":[{"@quantityAdd":["$acc","$$.resources.requests.memory"]},0,"$.spec.containers"]}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: pod, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal("1536Mi"))
+		})
+
+		It("should evaluate @semver expressions", func() {
+			for jsonData, expected := range map[string]any{
+				`{"@semver":"v1.30.0-rc.1+build.5"}`: map[string]any{
+					"major": int64(1), "minor": int64(30), "patch": int64(0),
+					"preRelease": "rc.1", "buildMetadata": "build.5",
+				},
+				`{"@semver":"1.25"}`: map[string]any{
+					"major": int64(1), "minor": int64(25), "patch": int64(0),
+					"preRelease": "", "buildMetadata": "",
+				},
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				res, err := exp.Evaluate(EvalCtx{Object: pod, Log: logger})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(expected), jsonData)
+			}
+		})
+
+		It("should evaluate @semverCmp expressions", func() {
+			for jsonData, expected := range map[string]int64{
+				`{"@semverCmp":["1.25.3","1.25.10"]}`:                                                             -1,
+				`{"@semverCmp":["v1.30.0","1.30.0-rc.1"]}`:                                                        1,
+				`{"@semverCmp":["1.30.0-rc.2","1.30.0-rc.10"]}`:                                                   -1,
+				`{"@semverCmp":["1.25","1.25.0"]}`:                                                                0,
+				`{"@semverCmp":[{"@regexReplace":["^[^:]+:(.*)$","$.spec.containers[0].image","\\1"]},"1.25.0"]}`: 1,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				res, err := exp.Evaluate(EvalCtx{Object: pod, Log: logger})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(expected), jsonData)
+			}
+		})
+
+		It("should reject invalid quantities and versions", func() {
+			for _, jsonData := range []string{
+				`{"@quantity":"1 gigabyte"}`,
+				`{"@quantityAdd":["1Gi","many"]}`,
+				`{"@quantityCmp":["1Gi"]}`,
+				`{"@semver":"latest"}`,
+				`{"@semverCmp":["1.2.3","x.y"]}`,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = exp.Evaluate(EvalCtx{Object: pod, Log: logger})
+				Expect(err).To(HaveOccurred(), jsonData)
+			}
+		})
+	})
+
+	Describe("Evaluating encoding operators", func() {
+		var secret map[string]any
+
+		BeforeEach(func() {
+			secret = map[string]any{
+				"metadata": map[string]any{
+					"name": "config",
+					"annotations": map[string]any{
+						"example.com/config": `{"replicas":3,"tags":["a","b"]}`,
+					},
+				},
+				"data": map[string]any{
+					"config.json": "eyJwb3J0Ijo4MDgwLCJkZWJ1ZyI6dHJ1ZX0=",
+				},
+			}
+		})
+
+		It("should evaluate encoding expressions", func() {
+			for jsonData, expected := range map[string]any{
+				`{"@base64Encode":"hello"}`:                             "aGVsbG8=",
+				`{"@base64Decode":"aGVsbG8="}`:                          "hello",
+				`{"@base64Decode":{"@base64Encode":"$.metadata.name"}}`: "config",
+				`{"@sha256":"hello"}`:                                   "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+				`{"@sha256":{"b":"x","a":1}}`:                           "ecf9e98ec0641e23113ff3ce8bdc78d0ddd249886517fd4a7f68cc83d4e65667",
+				`{"@json":{"b":"x","a":1}}`:                             `{"a":1,"b":"x"}`,
+				`{"@json":[1,2]}`:                                       `[1,2]`,
+				`{"@yaml":{"b":"x","a":[1]}}`:                           "a:\n- 1\nb: x\n",
+				`{"@fromJson":"$['metadata']['annotations']['example.com/config']"}`: map[string]any{
+					"replicas": int64(3), "tags": []any{"a", "b"},
+				},
+				`{"@fromYaml":"a: 1\nb: [x, z]\n"}`: map[string]any{"a": int64(1), "b": []any{"x", "z"}},
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				res, err := exp.Evaluate(EvalCtx{Object: secret, Log: logger})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(expected), jsonData)
+			}
+		})
+
+		It("should decode and parse a Secret key", func() {
+			jsonData := `{"@fromJson":{"@base64Decode":"$['data']['config.json']"}}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: secret, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(map[string]any{"port": int64(8080), "debug": true}))
+		})
+
+		It("should compute a stable checksum of a map", func() {
+			jsonData := `{"@sha256":"$.data"}`
+			var exp Expression
+			err := json.Unmarshal([]byte(jsonData), &exp)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := exp.Evaluate(EvalCtx{Object: secret, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(HaveLen(64))
+
+			secret["data"].(map[string]any)["other"] = "eA=="
+			res2, err := exp.Evaluate(EvalCtx{Object: secret, Log: logger})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res2).NotTo(Equal(res))
+		})
+
+		It("should reject invalid input", func() {
+			for _, jsonData := range []string{
+				`{"@base64Decode":"not base64!"}`,
+				`{"@fromJson":"{invalid"}`,
+				`{"@fromYaml":"a: [1"}`,
+			} {
+				var exp Expression
+				err := json.Unmarshal([]byte(jsonData), &exp)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = exp.Evaluate(EvalCtx{Object: secret, Log: logger})
+				Expect(err).To(HaveOccurred(), jsonData)
+			}
+		})
+	})
+
 	Describe("Evaluating cornercases", func() {
 		It("should deserialize and evaluate an expression inside a literal map", func() {
 			jsonData := `{"a":1,"b":{"c":{"@eq":[1,1]}}}`
@@ -3,10 +3,15 @@ package expression
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"strings"
 
 	"github.com/ohler55/ojg/jp"
 )
 
+// accumulatorVar is the name of the variable that holds the accumulator of a @fold.
+const accumulatorVar = "acc"
+
 // GetJSONPath evaluates a string op that may or may not be a JSONPath expession.
 func GetJSONPath(ctx EvalCtx, key string) (any, error) {
 	if len(key) == 0 || key[0] != '$' {
@@ -21,6 +26,11 @@ func GetJSONPath(ctx EvalCtx, key string) (any, error) {
 		key = "$$" // $ "$$" will be stripped, plain "" is accepted as a root ref
 	}
 
+	// $<name>... is a variable
+	if name, rest, ok := cutVariableRef(key); ok {
+		return getVariable(ctx, name, rest)
+	}
+
 	// $... is object
 	subject := ctx.Object
 
@@ -39,6 +49,55 @@ func GetJSONPath(ctx EvalCtx, key string) (any, error) {
 	return ret, nil
 }
 
+// cutVariableRef splits a variable reference of the form "$<name><jsonpath>" into the variable
+// name and the remaining JSONPath. Returns false if the key is not a variable reference.
+func cutVariableRef(key string) (string, string, bool) {
+	if len(key) < 2 || key[0] != '$' {
+		return "", "", false
+	}
+
+	i := strings.IndexAny(key, ".[")
+	if i < 0 {
+		i = len(key)
+	}
+
+	name := key[1:i]
+	if !IsValidVariableName(name) {
+		return "", "", false
+	}
+
+	return name, key[i:], true
+}
+
+// getVariable returns the value bound to a variable, or the part of it selected by the JSONPath.
+func getVariable(ctx EvalCtx, name, path string) (any, error) {
+	value, ok := ctx.Bindings[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined variable %q", name)
+	}
+
+	// named sub-expressions are evaluated in the current context, with the name itself
+	// removed from the scope to prevent infinite recursion
+	if exp, ok := value.(*Expression); ok {
+		scope := maps.Clone(ctx.Bindings)
+		delete(scope, name)
+		evalCtx := ctx
+		evalCtx.Bindings = scope
+
+		v, err := exp.Evaluate(evalCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate variable %q: %w", name, err)
+		}
+		value = v
+	}
+
+	if path == "" || path == "." {
+		return value, nil
+	}
+
+	return GetJSONPathRaw("$"+path, value)
+}
+
 // SetJSONPath overwrites the data in-place with the given value at the given key. Leaves the rest
 // of the data unchanged.
 func SetJSONPath(ctx EvalCtx, key string, value, data any) error {
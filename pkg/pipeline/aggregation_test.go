@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"slices"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -1295,6 +1296,203 @@ var _ = Describe("Aggregations", func() {
 			Expect(res).To(BeEmpty())
 		})
 	})
+
+	Describe("Evaluating aggregation functions", func() {
+		It("should compute named aggregates per group", func() {
+			jsonData := `
+- '@gather':
+    - $.metadata.namespace
+    - $.spec.count:
+        '@count': $.metadata.name
+      $.spec.sum:
+        '@sum': $.spec.a
+      $.spec.avg:
+        '@avg': $.spec.b.c
+      $.spec.max:
+        '@max': $.spec.a
+      $.spec.first:
+        '@first': $.metadata.name
+      $.spec.top:
+        '@topK': [1, $.spec.b.c]
+- '@project':
+    metadata:
+      name: "gathered"
+      namespace: $.metadata.namespace
+    spec: $.spec`
+			ag, err := newAggregation(jsonData)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := ag.Evaluate(object.Delta{Type: object.Added, Object: objs[0]})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(HaveLen(1))
+			Expect(res[0].Type).To(Equal(object.Upserted))
+			spec, ok, err := unstructured.NestedMap(res[0].Object.UnstructuredContent(), "spec")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(spec).To(Equal(map[string]any{
+				"a":     int64(1),
+				"b":     map[string]any{"c": int64(2)},
+				"count": int64(1),
+				"sum":   int64(1),
+				"avg":   2.0,
+				"max":   int64(1),
+				"first": "name",
+				"top":   []any{int64(2)},
+			}))
+
+			res, err = ag.Evaluate(object.Delta{Type: object.Added, Object: objs[1]})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(HaveLen(1))
+			Expect(res[0].Type).To(Equal(object.Upserted))
+			spec, _, err = unstructured.NestedMap(res[0].Object.UnstructuredContent(), "spec")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec["count"]).To(Equal(int64(2)))
+			Expect(spec["sum"]).To(Equal(int64(3)))
+			Expect(spec["avg"]).To(Equal(2.5))
+			Expect(spec["max"]).To(Equal(int64(2)))
+			Expect(spec["first"]).To(Equal("name"))
+			Expect(spec["top"]).To(Equal([]any{int64(3)}))
+
+			res, err = ag.Evaluate(object.Delta{Type: object.Deleted, Object: objs[0]})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(HaveLen(1))
+			Expect(res[0].Type).To(Equal(object.Upserted))
+			spec, _, err = unstructured.NestedMap(res[0].Object.UnstructuredContent(), "spec")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec["count"]).To(Equal(int64(1)))
+			Expect(spec["sum"]).To(Equal(int64(2)))
+			Expect(spec["first"]).To(Equal("name2"))
+
+			res, err = ag.Evaluate(object.Delta{Type: object.Deleted, Object: objs[1]})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(HaveLen(1))
+			Expect(res[0].Type).To(Equal(object.Deleted))
+			Expect(res[0].Object.GetName()).To(Equal("gathered"))
+		})
+
+		It("should reject invalid aggregation functions", func() {
+			for jsonData, msg := range map[string]string{
+				`[{"@gather": ["$.metadata.namespace", {}]}]`:                                  "pipeline[0].@gather[1]",
+				`[{"@gather": ["$.metadata.namespace", {"$.spec.x": {"@median": "$.a"}}]}]`:    "pipeline[0].@gather[1].$.spec.x",
+				`[{"@gather": ["$.metadata.namespace", {"$.spec.x": {"@topK": [0, "$.a"]}}]}]`: "positive integer",
+				`[{"@gather": ["$.metadata.namespace", {"$.spec.x": {"@sum": {"@len": 1}}}]}]`: "pipeline[0].@gather[1].$.spec.x.@sum.@len",
+			} {
+				_, err := newAggregation(jsonData)
+				Expect(err).To(HaveOccurred(), jsonData)
+				Expect(err.Error()).To(ContainSubstring(msg), jsonData)
+			}
+		})
+	})
+
+	Describe("Evaluating windowed aggregations", func() {
+		var now time.Time
+		var ag Evaluator
+
+		BeforeEach(func() {
+			jsonData := `
+- '@window':
+    key: $.metadata.namespace
+    timestamp: $.spec.ts
+    size: 1m
+    aggregates:
+      $.spec.count:
+        '@count': $.metadata.name
+      $.spec.sum:
+        '@sum': $.spec.a
+- '@project':
+    metadata:
+      name: "windowed"
+      namespace: $.metadata.namespace
+    spec: $.spec
+    window: $.window`
+			var pipeline opv1a1.Pipeline
+			Expect(yaml.Unmarshal([]byte(jsonData), &pipeline)).To(Succeed())
+			// Sync reads the source cache of the GVK of the objects
+			var err error
+			ag, err = New("test", gvk, []schema.GroupVersionKind{target}, pipeline, logger)
+			Expect(err).NotTo(HaveOccurred())
+			now = time.Date(2025, 1, 1, 10, 1, 30, 0, time.UTC)
+			ag.(*Pipeline).clock = func() time.Time { return now }
+
+			Expect(unstructured.SetNestedField(objs[0].UnstructuredContent(), "2025-01-01T10:00:10Z", "spec", "ts")).To(Succeed())
+			Expect(unstructured.SetNestedField(objs[1].UnstructuredContent(), "2025-01-01T10:01:10Z", "spec", "ts")).To(Succeed())
+		})
+
+		It("should aggregate the objects in the last closed window", func() {
+			res, err := ag.Evaluate(object.Delta{Type: object.Added, Object: objs[0]})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(HaveLen(1))
+			Expect(res[0].Type).To(Equal(object.Upserted))
+			content := res[0].Object.UnstructuredContent()
+			Expect(content["spec"]).To(HaveKeyWithValue("count", int64(1)))
+			Expect(content["spec"]).To(HaveKeyWithValue("sum", int64(1)))
+			Expect(content["window"]).To(Equal(map[string]any{
+				"start": "2025-01-01T10:00:00Z",
+				"end":   "2025-01-01T10:01:00Z",
+			}))
+
+			// the window of the second object is not closed yet
+			res, err = ag.Evaluate(object.Delta{Type: object.Added, Object: objs[1]})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(BeEmpty())
+
+			// closing the window on the next event
+			now = now.Add(time.Minute)
+			res, err = ag.Evaluate(object.Delta{Type: object.Deleted, Object: objs[0]})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(HaveLen(1))
+			Expect(res[0].Type).To(Equal(object.Upserted))
+			content = res[0].Object.UnstructuredContent()
+			Expect(content["spec"]).To(HaveKeyWithValue("count", int64(1)))
+			Expect(content["spec"]).To(HaveKeyWithValue("sum", int64(2)))
+			Expect(content["window"]).To(HaveKeyWithValue("start", "2025-01-01T10:01:00Z"))
+		})
+
+		It("should close the windows on sync", func() {
+			_, err := ag.Evaluate(object.Delta{Type: object.Added, Object: objs[0]})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = ag.Evaluate(object.Delta{Type: object.Added, Object: objs[1]})
+			Expect(err).NotTo(HaveOccurred())
+
+			now = now.Add(time.Minute)
+			res, err := ag.Sync()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(HaveLen(1))
+			Expect(res[0].Type).To(Equal(object.Upserted))
+			content := res[0].Object.UnstructuredContent()
+			Expect(content["spec"]).To(HaveKeyWithValue("sum", int64(2)))
+			Expect(content["window"]).To(HaveKeyWithValue("start", "2025-01-01T10:01:00Z"))
+
+			// the incremental state follows the sync
+			res, err = ag.Evaluate(object.Delta{Type: object.Deleted, Object: objs[1]})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(HaveLen(1))
+			Expect(res[0].Type).To(Equal(object.Deleted))
+			Expect(res[0].Object.GetName()).To(Equal("windowed"))
+
+			now = now.Add(time.Minute)
+			res, err = ag.Sync()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(BeEmpty())
+		})
+
+		It("should reject invalid windows", func() {
+			for jsonData, msg := range map[string]string{
+				`[{"@window": ["$.metadata.namespace"]}]`:                                                                                   "expected a map",
+				`[{"@window": {"key": "$.a", "timestamp": "$.b", "aggregates": {"$.c": {"@count": "$.d"}}}}]`:                               "missing window argument \"size\"",
+				`[{"@window": {"key": "$.a", "timestamp": "$.b", "size": "1x", "aggregates": {"$.c": {"@count": "$.d"}}}}]`:                 "invalid window argument \"size\"",
+				`[{"@window": {"key": "$.a", "timestamp": "$.b", "size": "-1m", "aggregates": {"$.c": {"@count": "$.d"}}}}]`:                "must be positive",
+				`[{"@window": {"key": "$.a", "timestamp": "$.b", "size": "10m", "slide": "3m", "aggregates": {"$.c": {"@count": "$.d"}}}}]`: "multiple of the slide",
+				`[{"@window": {"key": "$.a", "timestamp": "$.b", "size": "1m", "period": "1m", "aggregates": {}}}]`:                         "unknown window argument \"period\"",
+				`[{"@window": {"key": "$.a", "timestamp": "$.b", "size": "1m", "aggregates": {}}}]`:                                         "pipeline[0].@window.aggregates",
+				`[{"@window": {"key": "$.a", "timestamp": {"@len": 1}, "size": "1m", "aggregates": {"$.c": {"@count": "$.d"}}}}]`:           "pipeline[0].@window.timestamp.@len",
+			} {
+				_, err := newAggregation(jsonData)
+				Expect(err).To(HaveOccurred(), jsonData)
+				Expect(err.Error()).To(ContainSubstring(msg), jsonData)
+			}
+		})
+	})
 })
 
 func newAggregation(data string) (Evaluator, error) {
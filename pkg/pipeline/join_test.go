@@ -1,11 +1,14 @@
 package pipeline
 
 import (
+	"encoding/json"
+
 	"github.com/bsm/gomega/types"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
+	opv1a1 "github.com/l7mp/dcontroller/pkg/api/operator/v1alpha1"
 	"github.com/l7mp/dcontroller/pkg/object"
 )
 
@@ -487,6 +490,356 @@ var _ = Describe("Joins", func() {
 			Expect(delta.Object.UnstructuredContent()["dep"]).To(Equal(dep1.UnstructuredContent()))
 		})
 	})
+	Describe("Evaluating outer joins", func() {
+		It("should evaluate a left join", func() {
+			jsonData := `
+- '@leftJoin':
+    '@eq': [$.dep.metadata.name, $.pod.spec.parent]
+- '@project':
+    metadata:
+      name: $.dep.metadata.name
+      namespace: $.dep.metadata.namespace
+    hasPod:
+      '@exists': $.pod`
+			j, err := newPipeline(jsonData, []string{"dep", "pod"})
+			Expect(err).NotTo(HaveOccurred())
+
+			// unmatched deployment
+			deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: dep2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Type).To(Equal(object.Upserted))
+			Expect(deltas[0].Object.GetName()).To(Equal("dep2"))
+			Expect(deltas[0]).To(objFieldEq(false, "hasPod"))
+
+			// the pod of the deployment arrives
+			deltas, err = j.Evaluate(object.Delta{Type: object.Upserted, Object: pod3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Type).To(Equal(object.Upserted))
+			Expect(deltas[0].Object.GetName()).To(Equal("dep2"))
+			Expect(deltas[0]).To(objFieldEq(true, "hasPod"))
+
+			// pods of other deployments do not match
+			deltas, err = j.Evaluate(object.Delta{Type: object.Upserted, Object: pod1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(BeEmpty())
+
+			// the deployment becomes unmatched again
+			deltas, err = j.Evaluate(object.Delta{Type: object.Deleted, Object: pod3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Type).To(Equal(object.Upserted))
+			Expect(deltas[0].Object.GetName()).To(Equal("dep2"))
+			Expect(deltas[0]).To(objFieldEq(false, "hasPod"))
+		})
+
+		It("should evaluate an anti-join", func() {
+			jsonData := `
+- '@antiJoin':
+    '@eq': [$.dep.metadata.name, $.pod.spec.parent]
+- '@project':
+    metadata: $.dep.metadata`
+			j, err := newPipeline(jsonData, []string{"dep", "pod"})
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, o := range []object.Object{dep1, dep2} {
+				deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: o})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(HaveLen(1))
+				Expect(deltas[0].Type).To(Equal(object.Upserted))
+				Expect(deltas[0].Object.GetName()).To(Equal(o.GetName()))
+			}
+
+			deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: pod3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Type).To(Equal(object.Deleted))
+			Expect(deltas[0].Object.GetName()).To(Equal("dep2"))
+
+			deltas, err = j.Evaluate(object.Delta{Type: object.Deleted, Object: pod3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Type).To(Equal(object.Upserted))
+			Expect(deltas[0].Object.GetName()).To(Equal("dep2"))
+
+			deltas, err = j.Evaluate(object.Delta{Type: object.Deleted, Object: dep2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Type).To(Equal(object.Deleted))
+			Expect(deltas[0].Object.GetName()).To(Equal("dep2"))
+		})
+
+		It("should require an outer join to be the first operation", func() {
+			_, err := newPipeline(`
+- '@select': true
+- '@antiJoin':
+    '@eq': [$.dep.metadata.name, $.pod.spec.parent]`, []string{"dep", "pod"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Evaluating unions", func() {
+		It("should merge sources with per-source projections", func() {
+			jsonData := `
+- '@union':
+    pod:
+      metadata: $.metadata
+      image: $.spec.image
+    dep:
+      metadata: $.metadata
+      replicas: $.spec.replicas`
+			j, err := newPipeline(jsonData, []string{"pod", "dep"})
+			Expect(err).NotTo(HaveOccurred())
+
+			deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: pod1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Type).To(Equal(object.Upserted))
+			Expect(deltas[0].Object.GetName()).To(Equal("pod1"))
+			Expect(deltas[0]).To(objFieldEq("image1", "image"))
+
+			deltas, err = j.Evaluate(object.Delta{Type: object.Upserted, Object: dep1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Type).To(Equal(object.Upserted))
+			Expect(deltas[0].Object.GetName()).To(Equal("dep1"))
+			Expect(deltas[0]).To(objFieldEq(int64(3), "replicas"))
+
+			deltas, err = j.Evaluate(object.Delta{Type: object.Deleted, Object: pod1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Type).To(Equal(object.Deleted))
+			Expect(deltas[0].Object.GetName()).To(Equal("pod1"))
+		})
+
+		It("should merge sources without projections", func() {
+			j, err := newPipeline(`
+- '@union': {}
+- '@project':
+    metadata: $.metadata
+    labels: $.metadata.labels`, []string{"pod", "dep"})
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, o := range []object.Object{pod1, dep2} {
+				deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: o})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(HaveLen(1))
+				Expect(deltas[0].Type).To(Equal(object.Upserted))
+				Expect(deltas[0].Object.GetName()).To(Equal(o.GetName()))
+				Expect(deltas[0]).To(objFieldEq(o.GetLabels()["app"], "labels", "app"))
+			}
+		})
+
+		It("should reject invalid unions", func() {
+			for pipeline, msg := range map[string]string{
+				`[{"@select": true}, {"@union": {}}]`:          "must specify @join",
+				`[{"@union": [1]}]`:                            "pipeline[0].@union",
+				`[{"@union": {"pod": {"@len": "$.spec"}}}]`:    "pipeline[0].@union.pod",
+				`[{"@union": {"rs": {"metadata": "$.spec"}}}]`: `unknown source "rs"`,
+			} {
+				_, err := newPipeline(pipeline, []string{"pod", "dep"})
+				Expect(err).To(HaveOccurred(), pipeline)
+				Expect(err.Error()).To(ContainSubstring(msg), pipeline)
+			}
+		})
+	})
+
+	Describe("Evaluating equi-joins", func() {
+		It("should use a hash join for an equality predicate", func() {
+			j, err := newPipeline(`
+- '@join':
+    '@eq': [$.dep.metadata.name, $.pod.spec.parent]
+- '@project':
+    metadata:
+      name: result
+      namespace: default
+    pod: $.pod
+    dep: $.dep`, []string{"pod", "dep"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(j.String()).To(ContainSubstring("hash_⋈"))
+
+			for _, o := range []object.Object{dep1, dep2, pod2} {
+				_, err = j.Evaluate(object.Delta{Type: object.Upserted, Object: o})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: pod3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas).To(ContainElement(objFieldEq(dep2.UnstructuredContent(), "dep")))
+			Expect(deltas).To(ContainElement(objFieldEq(pod3.UnstructuredContent(), "pod")))
+		})
+
+		It("should use a hash join for a conjunction of equalities and other conditions", func() {
+			j, err := newPipeline(`
+- '@join':
+    '@and':
+      - '@eq': [$.dep.metadata.name, $.pod.spec.parent]
+      - '@eq': [$.rs.spec.dep, $.dep.metadata.name]
+      - '@eq': [$.pod.metadata.namespace, default]
+- '@project':
+    metadata:
+      name: result
+      namespace: default
+    pod: $.pod
+    dep: $.dep
+    rs: $.rs`, []string{"pod", "dep", "rs"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(j.String()).To(ContainSubstring("hash_⋈"))
+
+			for _, o := range []object.Object{dep1, dep2, rs1, rs2, pod2} {
+				deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: o})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(BeEmpty())
+			}
+
+			deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: pod1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas).To(ContainElement(objFieldEq(dep1.UnstructuredContent(), "dep")))
+			Expect(deltas).To(ContainElement(objFieldEq(rs1.UnstructuredContent(), "rs")))
+		})
+
+		It("should fall back to a nested-loop join when the inputs are not all equated", func() {
+			for _, pred := range []string{
+				`'@lt': [$.dep.spec.replicas, 2]`,
+				`'@eq': [$.dep.metadata.name, $.dep.metadata.namespace]`,
+				`'@selector': [$.dep.spec.selector, $.pod.metadata.labels]`,
+				`'@or': [{'@eq': [$.dep.metadata.name, $.pod.spec.parent]}, true]`,
+			} {
+				j, err := newPipeline("- '@join':\n    "+pred, []string{"pod", "dep"})
+				Expect(err).NotTo(HaveOccurred(), pred)
+				Expect(j.String()).NotTo(ContainSubstring("hash_⋈"), pred)
+			}
+		})
+
+		It("should not join documents on missing keys", func() {
+			j, err := newPipeline(`
+- '@join':
+    '@eq': [$.dep.spec.missing, $.pod.spec.missing]`, []string{"pod", "dep"})
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, o := range []object.Object{dep1, pod1} {
+				deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: o})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(BeEmpty())
+			}
+		})
+	})
+
+	Describe("Evaluating pipelines with branches", func() {
+		It("should pre-process a source before the join", func() {
+			j, err := newPipeline(`
+branches:
+  - name: pod
+    ops:
+      - '@select':
+          '@eq': [$.metadata.namespace, default]
+ops:
+  - '@join':
+      '@eq': [$.dep.metadata.name, $.pod.spec.parent]
+  - '@project':
+      metadata:
+        name: $.pod.metadata.name
+        namespace: default
+      dep: $.dep.metadata.name`, []string{"pod", "dep"})
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, o := range []object.Object{dep1, dep2, pod2} {
+				deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: o})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(BeEmpty())
+			}
+
+			deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: pod1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Type).To(Equal(object.Upserted))
+			Expect(deltas[0].Object.GetName()).To(Equal("pod1"))
+			Expect(deltas).To(ContainElement(objFieldEq("dep1", "dep")))
+		})
+
+		It("should join sources in multiple steps", func() {
+			j, err := newPipeline(`
+branches:
+  - name: deprs
+    sources: [dep, rs]
+    ops:
+      - '@join':
+          '@eq': [$.dep.metadata.name, $.rs.spec.dep]
+      - '@project':
+          name: $.dep.metadata.name
+          rs: $.rs.metadata.name
+ops:
+  - '@join':
+      '@eq': [$.deprs.name, $.pod.spec.parent]
+  - '@project':
+      metadata:
+        name: $.pod.metadata.name
+        namespace: default
+      rs: $.deprs.rs`, []string{"pod", "dep", "rs"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(j.String()).To(ContainSubstring("branches[0]"))
+
+			for _, o := range []object.Object{dep1, dep2, rs1, rs2} {
+				deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: o})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(BeEmpty())
+			}
+
+			deltas, err := j.Evaluate(object.Delta{Type: object.Upserted, Object: pod1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Object.GetName()).To(Equal("pod1"))
+			Expect(deltas).To(ContainElement(objFieldEq("rs1", "rs")))
+
+			deltas, err = j.Evaluate(object.Delta{Type: object.Upserted, Object: pod3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Object.GetName()).To(Equal("pod3"))
+			Expect(deltas).To(ContainElement(objFieldEq("rs2", "rs")))
+
+			deltas, err = j.Evaluate(object.Delta{Type: object.Deleted, Object: rs1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deltas).To(HaveLen(1))
+			Expect(deltas[0].Type).To(Equal(object.Deleted))
+			Expect(deltas[0].Object.GetName()).To(Equal("pod1"))
+		})
+
+		It("should reject invalid branches", func() {
+			for pipeline, msg := range map[string]string{
+				`{"branches": [{"name": "x", "ops": [{"@select": true}]}]}`:                                        `unknown source "x"`,
+				`{"branches": [{"name": "x", "sources": ["pod", "dep"], "ops": [{"@select": true}]}]}`:             "must specify @join",
+				`{"branches": [{"name": "pod", "ops": [{"@select": true}]}, {"name": "pod", "sources": ["dep"]}]}`: "shadowed",
+				`{"branches": [{"name": "pod", "ops": [{"@select": 1}]}]}`:                                         "pipeline.branches[0].ops[0].@select",
+				`{"branches": [{"name": "pod"}], "ops": [{"@select": 1}]}`:                                         "pipeline.ops[0].@select",
+			} {
+				_, err := newPipeline(pipeline, []string{"pod", "dep"})
+				Expect(err).To(HaveOccurred(), pipeline)
+				Expect(err.Error()).To(ContainSubstring(msg), pipeline)
+			}
+		})
+
+		It("should survive a JSON marshal-unmarshal roundtrip", func() {
+			data := `{"branches":[{"name":"deprs","sources":["dep","rs"],"ops":[{"@join":true}]},` +
+				`{"name":"pod","ops":[{"@select":true}]}],"ops":[{"@join":true}]}`
+			var config opv1a1.Pipeline
+			Expect(json.Unmarshal([]byte(data), &config)).To(Succeed())
+			Expect(config.Branches).To(HaveLen(2))
+			Expect(config.Branches[0].GetSources()).To(Equal([]string{"dep", "rs"}))
+			Expect(config.Branches[1].GetSources()).To(Equal([]string{"pod"}))
+			Expect(config.Ops).To(HaveLen(1))
+			Expect(config.DeepCopy()).To(Equal(&config))
+
+			res, err := json.Marshal(config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(res)).To(Equal(data))
+
+			Expect(json.Unmarshal([]byte(`{"branches":[],"other":[]}`), &config)).NotTo(Succeed())
+			Expect(json.Unmarshal([]byte(`{"branches":[{"ops":[]}]}`), &config)).NotTo(Succeed())
+		})
+	})
 })
 
 func objFieldEq(elem any, fields ...string) types.GomegaMatcher {
@@ -3,20 +3,26 @@ package pipeline
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	opv1a1 "github.com/l7mp/dcontroller/pkg/api/operator/v1alpha1"
 	"github.com/l7mp/dcontroller/pkg/dbsp"
 	"github.com/l7mp/dcontroller/pkg/expression"
 	"github.com/l7mp/dcontroller/pkg/object"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"github.com/ohler55/ojg/jp"
 )
 
 const trimEvalLen = 30
 
 // Selection operator.
 type SelectionOp struct {
-	e   *expression.Expression
-	log logr.Logger
+	e    *expression.Expression
+	defs map[string]any
+	log  logr.Logger
 }
 
 func (eval *SelectionOp) String() string {
@@ -26,7 +32,7 @@ func (eval *SelectionOp) String() string {
 func (eval *SelectionOp) Evaluate(doc dbsp.Document) ([]dbsp.Document, error) {
 	ret := []dbsp.Document{}
 
-	res, err := eval.e.Evaluate(expression.EvalCtx{Object: doc, Log: eval.log})
+	res, err := eval.e.Evaluate(expression.EvalCtx{Object: doc, Bindings: eval.defs, Log: eval.log})
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate expression %s: %w",
 			eval.String(), err)
@@ -46,15 +52,22 @@ func (eval *SelectionOp) Evaluate(doc dbsp.Document) ([]dbsp.Document, error) {
 	return ret, nil
 }
 
+// InputRefs implements dbsp.PredicateEvaluator, so that selections that refer to a single input
+// of a join can be pushed below the join.
+func (eval *SelectionOp) InputRefs(inputs []string) ([]string, bool) {
+	return inputRefNames(eval.e, inputs)
+}
+
 func (p *Pipeline) NewSelectionOp(e *expression.Expression) dbsp.Operator {
-	eval := &SelectionOp{e: e, log: p.log.WithName("@select")}
+	eval := &SelectionOp{e: e, defs: p.defs, log: p.log.WithName("@select")}
 	return dbsp.NewSelection(eval)
 }
 
 // Projection operator.
 type ProjectionOp struct {
-	e   *expression.Expression
-	log logr.Logger
+	e    *expression.Expression
+	defs map[string]any
+	log  logr.Logger
 }
 
 func (eval *ProjectionOp) String() string {
@@ -62,7 +75,7 @@ func (eval *ProjectionOp) String() string {
 }
 
 func (eval *ProjectionOp) Evaluate(doc dbsp.Document) ([]dbsp.Document, error) {
-	res, err := eval.e.Evaluate(expression.EvalCtx{Object: doc, Log: eval.log})
+	res, err := eval.e.Evaluate(expression.EvalCtx{Object: doc, Bindings: eval.defs, Log: eval.log})
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate expression %s: %w",
 			eval.String(), err)
@@ -92,14 +105,15 @@ func (eval *ProjectionOp) Evaluate(doc dbsp.Document) ([]dbsp.Document, error) {
 }
 
 func (p *Pipeline) NewProjectionOp(e *expression.Expression) dbsp.Operator {
-	eval := &ProjectionOp{e: e, log: p.log.WithName("@project")}
+	eval := &ProjectionOp{e: e, defs: p.defs, log: p.log.WithName("@project")}
 	return dbsp.NewProjection(eval)
 }
 
 // Unwind operator.
 type UnwindOp struct {
-	e   *expression.Expression
-	log logr.Logger
+	e    *expression.Expression
+	defs map[string]any
+	log  logr.Logger
 }
 
 func (eval *UnwindOp) String() string {
@@ -117,7 +131,7 @@ func (eval *UnwindOp) Extract(doc dbsp.Document) (any, error) {
 		return nil, errors.New("valid .metadata.name required")
 	}
 
-	arg, err := eval.e.Evaluate(expression.EvalCtx{Object: doc, Log: eval.log})
+	arg, err := eval.e.Evaluate(expression.EvalCtx{Object: doc, Bindings: eval.defs, Log: eval.log})
 	if err != nil {
 		return nil, err
 	}
@@ -173,7 +187,7 @@ func (p *Pipeline) NewUnwindOp(e *expression.Expression) (dbsp.Operator, error)
 	if _, err := e.GetLiteralString(); err != nil {
 		return nil, fmt.Errorf("expected a JSONpath expression")
 	}
-	eval := &UnwindOp{e: e, log: p.log.WithName("@unwind")}
+	eval := &UnwindOp{e: e, defs: p.defs, log: p.log.WithName("@unwind")}
 	return dbsp.NewUnwind(eval, eval), nil
 }
 
@@ -189,12 +203,13 @@ func (eval *GatherOp) String() string {
 }
 
 type gatherExtractor struct {
-	e   *expression.Expression
-	log logr.Logger
+	e    *expression.Expression
+	defs map[string]any
+	log  logr.Logger
 }
 
 func (ext *gatherExtractor) Extract(doc dbsp.Document) (any, error) {
-	arg, err := ext.e.Evaluate(expression.EvalCtx{Object: doc, Log: ext.log})
+	arg, err := ext.e.Evaluate(expression.EvalCtx{Object: doc, Bindings: ext.defs, Log: ext.log})
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate pipeline stage %s: %w",
 			ext.String(), err)
@@ -230,20 +245,274 @@ func (p *Pipeline) NewGatherOp(e *expression.Expression) (dbsp.Operator, error)
 		return nil, errors.New("expected two expressions")
 	}
 
+	keyExtractor := &gatherExtractor{e: &args[0], defs: p.defs, log: p.log}
+
+	// Aggregation functions
+	if args[1].Op == "@dict" {
+		aggregates, err := p.newAggregates(&args[1])
+		if err != nil {
+			return nil, err
+		}
+		eval := &AggregateOp{aggregates: aggregates, log: p.log.WithName("@gather")}
+		return dbsp.NewIncrementalAggregate(keyExtractor, aggregates, eval), nil
+	}
+
 	eval := &GatherOp{
 		e:              &args[1],
-		keyExtractor:   &gatherExtractor{e: &args[0], log: p.log},
-		valueExtractor: &gatherExtractor{e: &args[1], log: p.log},
+		keyExtractor:   keyExtractor,
+		valueExtractor: &gatherExtractor{e: &args[1], defs: p.defs, log: p.log},
 		log:            p.log.WithName("@gather"),
 	}
 
 	return dbsp.NewIncrementalGather(eval.keyExtractor, eval.valueExtractor, eval), nil
 }
 
+// AggregateOp is the transformer of a @gather op with aggregation functions.
+type AggregateOp struct {
+	aggregates []dbsp.Aggregate
+	log        logr.Logger
+}
+
+func (eval *AggregateOp) String() string {
+	aggs := make([]string, len(eval.aggregates))
+	for i, a := range eval.aggregates {
+		aggs[i] = fmt.Sprintf("%s=%s(%s)", a.Name, a.Func.String(), a.Extractor.String())
+	}
+	return fmt.Sprintf("aggregate:%s", strings.Join(aggs, ","))
+}
+
+// Transform writes the result of the aggregates into the representative document of the group.
+func (eval *AggregateOp) Transform(doc dbsp.Document, v any) (dbsp.Document, error) {
+	results, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("expected aggregate results")
+	}
+
+	for _, a := range eval.aggregates {
+		if err := expression.SetJSONPathRaw(a.Name, results[a.Name], map[string]any(doc)); err != nil {
+			return nil, fmt.Errorf("failed to set aggregate at JSONpath %q: %w", a.Name, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// newAggregates creates the aggregation functions of a @gather op from a map of JSONPaths to
+// aggregation function calls.
+func (p *Pipeline) newAggregates(e *expression.Expression) ([]dbsp.Aggregate, error) {
+	funcs, ok := e.Literal.(map[string]expression.Expression)
+	if !ok || len(funcs) == 0 {
+		return nil, errors.New("expected a map of aggregation functions")
+	}
+
+	aggregates := make([]dbsp.Aggregate, 0, len(funcs))
+	for _, path := range slices.Sorted(maps.Keys(funcs)) {
+		f, arg, err := newAggregateFunc(funcs[path])
+		if err != nil {
+			return nil, fmt.Errorf("invalid aggregate %q: %w", path, err)
+		}
+		aggregates = append(aggregates, dbsp.Aggregate{
+			Name:      path,
+			Extractor: &gatherExtractor{e: arg, defs: p.defs, log: p.log},
+			Func:      f,
+		})
+	}
+
+	return aggregates, nil
+}
+
+// newAggregateFunc returns the aggregation function of a call like {"@sum": <expression>}, along
+// with the expression that extracts the values to be aggregated.
+func newAggregateFunc(e expression.Expression) (dbsp.AggregateFunc, *expression.Expression, error) {
+	if e.Arg == nil {
+		return nil, nil, errors.New("expected an aggregation function")
+	}
+
+	switch e.Op {
+	case "@count":
+		return dbsp.NewCount(), e.Arg, nil
+	case "@sum":
+		return dbsp.NewSum(), e.Arg, nil
+	case "@avg":
+		return dbsp.NewAvg(), e.Arg, nil
+	case "@min":
+		return dbsp.NewMin(), e.Arg, nil
+	case "@max":
+		return dbsp.NewMax(), e.Arg, nil
+	case "@first":
+		return dbsp.NewFirst(), e.Arg, nil
+	case "@last":
+		return dbsp.NewLast(), e.Arg, nil
+	case "@topK":
+		args, ok := e.Arg.Literal.([]expression.Expression)
+		if e.Arg.Op != "@list" || !ok || len(args) != 2 {
+			return nil, nil, errors.New("@topK expects a list of a limit and an expression")
+		}
+		k, ok := args[0].Literal.(int64)
+		if args[0].Op != "@int" || !ok || k <= 0 {
+			return nil, nil, errors.New("@topK expects a positive integer limit")
+		}
+		return dbsp.NewTopK(int(k)), &args[1], nil
+	default:
+		return nil, nil, fmt.Errorf("unknown aggregation function %q", e.Op)
+	}
+}
+
+// windowArgs are the arguments of a @window op.
+type windowArgs struct {
+	key, timestamp, aggregates *expression.Expression
+	window                     dbsp.Window
+}
+
+// parseWindowArgs parses the arguments of a @window op from a map with the keys "key",
+// "timestamp", "size", "slide" (optional, defaults to the size) and "aggregates".
+func parseWindowArgs(e *expression.Expression) (*windowArgs, error) {
+	args, ok := e.Literal.(map[string]expression.Expression)
+	if e.Op != "@dict" || !ok {
+		return nil, errors.New("expected a map of window arguments")
+	}
+
+	for name := range args {
+		if !slices.Contains([]string{"key", "timestamp", "size", "slide", "aggregates"}, name) {
+			return nil, fmt.Errorf("unknown window argument %q", name)
+		}
+	}
+
+	exps := make([]*expression.Expression, 3)
+	for i, name := range []string{"key", "timestamp", "aggregates"} {
+		arg, ok := args[name]
+		if !ok {
+			return nil, fmt.Errorf("missing window argument %q", name)
+		}
+		exps[i] = &arg
+	}
+	ret := &windowArgs{key: exps[0], timestamp: exps[1], aggregates: exps[2]}
+
+	size, err := parseDuration(args, "size")
+	if err != nil {
+		return nil, err
+	}
+	ret.window = dbsp.NewTumblingWindow(size)
+	if _, ok := args["slide"]; ok {
+		slide, err := parseDuration(args, "slide")
+		if err != nil {
+			return nil, err
+		}
+		ret.window = dbsp.NewSlidingWindow(size, slide)
+	}
+
+	if err := ret.window.Validate(); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func parseDuration(args map[string]expression.Expression, name string) (time.Duration, error) {
+	arg, ok := args[name]
+	if !ok {
+		return 0, fmt.Errorf("missing window argument %q", name)
+	}
+	str, ok := arg.Literal.(string)
+	if arg.Op != "@string" || !ok {
+		return 0, fmt.Errorf("window argument %q must be a duration string", name)
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window argument %q: %w", name, err)
+	}
+	return d, nil
+}
+
+// NewWindowOp creates a windowed aggregation op. Windows are closed by the pipeline clock.
+func (p *Pipeline) NewWindowOp(e *expression.Expression) (dbsp.Operator, error) {
+	args, err := parseWindowArgs(e)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregates, err := p.newAggregates(args.aggregates)
+	if err != nil {
+		return nil, err
+	}
+
+	keyExtractor := &gatherExtractor{e: args.key, defs: p.defs, log: p.log}
+	tsExtractor := &gatherExtractor{e: args.timestamp, defs: p.defs, log: p.log}
+	eval := &WindowOp{AggregateOp: AggregateOp{aggregates: aggregates, log: p.log.WithName("@window")}}
+	clock := func() time.Time { return p.clock() }
+
+	return dbsp.NewIncrementalWindowedAggregate(keyExtractor, tsExtractor, args.window, aggregates, eval, clock), nil
+}
+
+// WindowOp is the transformer of a @window op.
+type WindowOp struct {
+	AggregateOp
+}
+
+// Transform writes the result of the aggregates into the representative document of the group,
+// along with the bounds of the window into the "window" field.
+func (eval *WindowOp) Transform(doc dbsp.Document, v any) (dbsp.Document, error) {
+	res, ok := v.(*dbsp.WindowResult)
+	if !ok {
+		return nil, errors.New("expected window results")
+	}
+
+	doc, err := eval.AggregateOp.Transform(doc, res.Aggregates)
+	if err != nil {
+		return nil, err
+	}
+
+	doc["window"] = map[string]any{
+		"start": res.Start.UTC().Format(time.RFC3339),
+		"end":   res.End.UTC().Format(time.RFC3339),
+	}
+
+	return doc, nil
+}
+
+// The names under which the step of a @recurse op sees the input and the result of the recursion.
+const (
+	recurseInput = "input"
+	recurseSelf  = "self"
+)
+
+// NewRecurseOp creates a fixpoint op from a @recurse op. The body of the fixpoint is a DAG that
+// merges the output of the base ops on the input and the step ops on the result and the input.
+func (p *Pipeline) NewRecurseOp(op *opv1a1.RecurseOp) (dbsp.Operator, error) {
+	// The body has its own definitions.
+	defs := p.defs
+	defer func() { p.defs = defs }()
+
+	body := dbsp.NewDAG()
+	for _, input := range []string{recurseInput, recurseSelf} {
+		if err := body.AddInput(input); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.addChain(body, "base", op.Base, []string{recurseInput}, nil); err != nil {
+		return nil, fmt.Errorf("base: %w", err)
+	}
+	if _, err := p.addChain(body, "step", op.Step, []string{recurseSelf, recurseInput}, nil); err != nil {
+		return nil, fmt.Errorf("step: %w", err)
+	}
+
+	output := dbsp.NewChainGraph()
+	output.AddInput(dbsp.NewInput("base"))
+	output.AddInput(dbsp.NewInput("step"))
+	output.SetJoin(dbsp.NewNaryAdd(2))
+	if err := body.AddNode("output", output, nil); err != nil {
+		return nil, err
+	}
+
+	return dbsp.NewIncrementalFixpoint(body, recurseInput, recurseSelf, op.MaxIterations)
+}
+
 // Join operator.
 type JoinOp struct {
-	e   *expression.Expression
-	log logr.Logger
+	e    *expression.Expression
+	defs map[string]any
+	log  logr.Logger
 }
 
 func (eval *JoinOp) String() string {
@@ -251,7 +520,7 @@ func (eval *JoinOp) String() string {
 }
 
 func (eval *JoinOp) Evaluate(doc dbsp.Document) ([]dbsp.Document, error) {
-	res, err := eval.e.Evaluate(expression.EvalCtx{Object: doc, Log: eval.log})
+	res, err := eval.e.Evaluate(expression.EvalCtx{Object: doc, Bindings: eval.defs, Log: eval.log})
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate join expression %s: %w",
 			eval.String(), err)
@@ -270,13 +539,292 @@ func (eval *JoinOp) Evaluate(doc dbsp.Document) ([]dbsp.Document, error) {
 	return ret, nil
 }
 
-func (p *Pipeline) NewJoinOp(e *expression.Expression, sources []schema.GroupVersionKind) dbsp.Operator {
-	inputs := make([]string, len(sources))
-	for i, src := range sources {
-		inputs[i] = src.Kind
+// InputRefs implements dbsp.PredicateEvaluator: the join passes on the joined documents unchanged.
+func (eval *JoinOp) InputRefs(inputs []string) ([]string, bool) {
+	return inputRefNames(eval.e, inputs)
+}
+
+// JoinKeys implements dbsp.JoinKeyEvaluator. It returns a key extractor per input when the join
+// predicate is an @eq, or an @and of conditions including @eqs, that equate a term over each input
+// with the terms of all other inputs, e.g., `{"@eq": ["$.Pod.spec.nodeName",
+// "$.Node.metadata.name"]}`. Documents with a nil key are never joined: @eq is false on nil.
+func (eval *JoinOp) JoinKeys(inputs []string) ([]dbsp.Extractor, bool) {
+	if len(inputs) < 2 {
+		return nil, false
+	}
+
+	conjuncts := []expression.Expression{*eval.e}
+	if eval.e.Op == "@and" {
+		args, err := expression.AsExpOrExpList(eval.e.Arg)
+		if err != nil {
+			return nil, false
+		}
+		conjuncts = args
+	}
+
+	index := make(map[string]int, len(inputs))
+	for i, input := range inputs {
+		index[input] = i
+	}
+
+	// collect the terms equated by the predicate into equivalence classes
+	type term struct {
+		input int
+		e     *expression.Expression
+	}
+	terms := []term{}
+	termIndex := map[string]int{}
+	parent := []int{}
+	addTerm := func(t term) int {
+		id := fmt.Sprintf("%d/%s", t.input, t.e.String())
+		if i, ok := termIndex[id]; ok {
+			return i
+		}
+		termIndex[id] = len(terms)
+		terms = append(terms, t)
+		parent = append(parent, len(parent))
+		return len(terms) - 1
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for _, c := range conjuncts {
+		if c.Op != "@eq" || c.Arg == nil {
+			continue
+		}
+		args, err := expression.AsExpOrExpList(c.Arg)
+		if err != nil || len(args) != 2 {
+			continue
+		}
+
+		left, ok := joinInputRef(&args[0], index)
+		if !ok {
+			continue
+		}
+		right, ok := joinInputRef(&args[1], index)
+		if !ok || left == right {
+			continue
+		}
+
+		l, r := addTerm(term{input: left, e: &args[0]}), addTerm(term{input: right, e: &args[1]})
+		parent[find(l)] = find(r)
+	}
+
+	// each class that contains a term for every input yields a key component
+	keys := make([]*joinKeyExtractor, len(inputs))
+	for i, input := range inputs {
+		keys[i] = &joinKeyExtractor{input: input, defs: eval.defs, log: eval.log}
+	}
+	classes := map[int][]*expression.Expression{}
+	roots := []int{}
+	for i, t := range terms {
+		root := find(i)
+		if _, ok := classes[root]; !ok {
+			classes[root] = make([]*expression.Expression, len(inputs))
+			roots = append(roots, root)
+		}
+		if classes[root][t.input] == nil {
+			classes[root][t.input] = t.e
+		}
+	}
+	for _, root := range roots {
+		if slices.Contains(classes[root], nil) {
+			continue
+		}
+		for i, e := range classes[root] {
+			keys[i].exprs = append(keys[i].exprs, e)
+		}
+	}
+
+	if len(keys[0].exprs) == 0 {
+		return nil, false
+	}
+
+	ret := make([]dbsp.Extractor, len(inputs))
+	for i := range keys {
+		ret[i] = keys[i]
+	}
+
+	return ret, true
+}
+
+// joinInputRef returns the index of the single input an expression refers to. Expressions that
+// refer to no input, to multiple inputs, or to variables and local subjects are rejected.
+func joinInputRef(e *expression.Expression, index map[string]int) (int, bool) {
+	refs, ok := inputRefs(e, index)
+	if !ok || len(refs) != 1 {
+		return 0, false
+	}
+	return refs[0], true
+}
+
+// inputRefs returns the indices of the inputs an expression refers to. Expressions that refer to
+// the joined document as a whole, to unknown inputs, or to variables and local subjects are
+// rejected.
+func inputRefs(e *expression.Expression, index map[string]int) ([]int, bool) {
+	refs := []int{}
+	var walk func(e *expression.Expression) bool
+	walk = func(e *expression.Expression) bool {
+		if e == nil {
+			return true
+		}
+
+		switch lit := e.Literal.(type) {
+		case string:
+			if len(lit) == 0 || lit[0] != '$' {
+				return true
+			}
+			if !strings.HasPrefix(lit, "$.") && !strings.HasPrefix(lit, "$[") {
+				return false // root refs, variables and local subjects
+			}
+			path, err := jp.ParseString(lit)
+			if err != nil || len(path) < 2 {
+				return false
+			}
+			child, ok := path[1].(jp.Child)
+			if !ok {
+				return false
+			}
+			i, ok := index[string(child)]
+			if !ok {
+				return false
+			}
+			if !slices.Contains(refs, i) {
+				refs = append(refs, i)
+			}
+		case []expression.Expression:
+			for j := range lit {
+				if !walk(&lit[j]) {
+					return false
+				}
+			}
+		case map[string]expression.Expression:
+			for k, v := range lit {
+				if strings.HasPrefix(k, "$") || !walk(&v) {
+					return false
+				}
+			}
+		}
+
+		return walk(e.Arg)
+	}
+
+	if !walk(e) {
+		return nil, false
+	}
+
+	return refs, true
+}
+
+// inputRefNames returns the names of the join inputs an expression refers to.
+func inputRefNames(e *expression.Expression, inputs []string) ([]string, bool) {
+	index := make(map[string]int, len(inputs))
+	for i, input := range inputs {
+		index[input] = i
+	}
+
+	refs, ok := inputRefs(e, index)
+	if !ok {
+		return nil, false
+	}
+
+	ret := make([]string, len(refs))
+	for i, ref := range refs {
+		ret[i] = inputs[ref]
+	}
+	return ret, true
+}
+
+// joinKeyExtractor evaluates the key expressions of a hash join on the documents of an input.
+type joinKeyExtractor struct {
+	input string
+	exprs []*expression.Expression
+	defs  map[string]any
+	log   logr.Logger
+}
+
+func (ext *joinKeyExtractor) Extract(doc dbsp.Document) (any, error) {
+	obj := dbsp.Document{ext.input: doc}
+	key := make([]any, len(ext.exprs))
+	for i, e := range ext.exprs {
+		v, err := e.Evaluate(expression.EvalCtx{Object: obj, Bindings: ext.defs, Log: ext.log})
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate join key %s: %w", e.String(), err)
+		}
+		if v == nil {
+			return nil, nil
+		}
+		key[i] = v
+	}
+	return key, nil
+}
+
+func (ext *joinKeyExtractor) String() string {
+	exprs := make([]string, len(ext.exprs))
+	for i, e := range ext.exprs {
+		exprs[i] = e.String()
+	}
+	return fmt.Sprintf("%s:[%s]", ext.input, strings.Join(exprs, ","))
+}
+
+// NewJoinOp creates an inner (@join), left outer (@leftJoin) or anti-join (@antiJoin) op. Outer
+// joins preserve the first source.
+func (p *Pipeline) NewJoinOp(opType string, e *expression.Expression, inputs []string) dbsp.Operator {
+	eval := &JoinOp{e: e, defs: p.defs, log: p.log.WithName(opType)}
+	switch opType {
+	case "@leftJoin":
+		return dbsp.NewIncrementalLeftJoin(eval, inputs)
+	case "@antiJoin":
+		return dbsp.NewIncrementalAntiJoin(eval, inputs)
+	default:
+		return dbsp.NewIncrementalJoin(eval, inputs)
+	}
+}
+
+// NewUnionProjections creates the per-input projections of a @union op. The expression must be a
+// map from input names to projection expressions; inputs without a projection are merged
+// unchanged.
+func (p *Pipeline) NewUnionProjections(e *expression.Expression, inputs []string) (map[string]dbsp.Operator, error) {
+	projections, ok := e.Literal.(map[string]expression.Expression)
+	if e.Op != "@dict" || !ok {
+		return nil, errors.New("expected a map of projections")
+	}
+
+	ret := make(map[string]dbsp.Operator, len(projections))
+	for input, projection := range projections {
+		if !slices.Contains(inputs, input) {
+			return nil, fmt.Errorf("unknown source %q", input)
+		}
+		ret[input] = p.NewProjectionOp(&projection)
+	}
+
+	return ret, nil
+}
+
+// addDefinitions adds the named sub-expressions of a @define op to the scope of the subsequent ops.
+func (p *Pipeline) addDefinitions(e *expression.Expression) error {
+	defs, ok := e.Literal.(map[string]expression.Expression)
+	if e.Op != "@dict" || !ok {
+		return errors.New("expected a map of definitions")
+	}
+
+	// copy the scope so that the definitions are visible only to the subsequent ops
+	scope := make(map[string]any, len(p.defs)+len(defs))
+	maps.Copy(scope, p.defs)
+	for name, def := range defs {
+		if !expression.IsValidVariableName(name) {
+			return fmt.Errorf("invalid definition name %q", name)
+		}
+		scope[name] = &def
 	}
-	eval := &JoinOp{e: e, log: p.log.WithName("@join")}
-	return dbsp.NewIncrementalJoin(eval, inputs)
+	p.defs = scope
+
+	return nil
 }
 
 func trim(s string) string {
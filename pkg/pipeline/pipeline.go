@@ -6,11 +6,30 @@
 // operations while maintaining incremental update semantics for efficiency.
 //
 // Pipeline operations:
-//   - @join: Combine multiple resource types with boolean conditions (must be first if present).
+//   - @define: Bind named sub-expressions that the subsequent operations can refer to as "$<name>".
+//   - @join: Combine multiple resource types with boolean conditions (must be first if present,
+//     only @define operations may precede it).
+//   - @leftJoin: Like @join, but objects of the first resource type without a match are also passed
+//     on.
+//   - @antiJoin: Pass on only the objects of the first resource type that do not have a match.
+//   - @union: Merge the objects of multiple resource types without joining them, optionally
+//     projecting the objects of each resource type first.
 //   - @select: Filter objects based on boolean expressions.
 //   - @project: Transform object structure and extract fields.
 //   - @unwind: Expand array fields into multiple objects.
-//   - @gather: Collect multiple objects into aggregated results.
+//   - @gather: Collect multiple objects into aggregated results, either by collecting values into a
+//     list or by computing aggregation functions like @count, @sum or @max.
+//   - @distinct: Remove duplicate objects, i.e., switch from multiset to set semantics.
+//   - @recurse: Compute the least fixpoint of a recursive query, e.g., the transitive closure of
+//     the owner references of objects.
+//   - @window: Compute aggregation functions over tumbling or sliding time windows. Windows are
+//     closed as time passes, use a Periodic source to update the results on time.
+//
+// Pipelines may also define named branches that pre-process sources, or join some of the sources,
+// before the operations. The pipeline is compiled into a DAG of linear chains, one per branch,
+// which is then optimized by a cost-based rewrite engine, e.g., selections that refer to a single
+// source are pushed below the joins. Custom rewrite rules can be passed to New in the optional
+// Options argument.
 //
 // Example usage:
 //
@@ -26,7 +45,10 @@ package pipeline
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -35,6 +57,7 @@ import (
 	opv1a1 "github.com/l7mp/dcontroller/pkg/api/operator/v1alpha1"
 	"github.com/l7mp/dcontroller/pkg/cache"
 	"github.com/l7mp/dcontroller/pkg/dbsp"
+	"github.com/l7mp/dcontroller/pkg/expression"
 	"github.com/l7mp/dcontroller/pkg/object"
 	"github.com/l7mp/dcontroller/pkg/util"
 )
@@ -46,6 +69,8 @@ var ObjectKey = toolscache.MetaObjectToName
 // Evaluator is a query that knows how to evaluate itself on a given delta and how to print itself.
 type Evaluator interface {
 	Evaluate(object.Delta) ([]object.Delta, error)
+	// EvaluateBatch evaluates the query on a batch of deltas and returns the net change.
+	EvaluateBatch([]object.Delta) ([]object.Delta, error)
 	Sync() ([]object.Delta, error)
 	fmt.Stringer
 	// GetTargetCache returns the pipeline's internal target cache (primarily for testing).
@@ -55,67 +80,216 @@ type Evaluator interface {
 }
 
 // Pipeline is query that knows how to evaluate itself.
-// Pipeline is not reentrant: Evaluate() and Sync() must not be called concurrently.
+// Pipeline is not reentrant: Evaluate(), EvaluateBatch() and Sync() must not be called concurrently.
 type Pipeline struct {
 	operator         string
 	config           opv1a1.Pipeline
-	executor         *dbsp.Executor
-	graph            *dbsp.ChainGraph
+	executor         *dbsp.DAGExecutor
+	dag              *dbsp.DAG
+	graph            *dbsp.ChainGraph // the output node of the DAG
 	rewriter         *dbsp.LinearChainRewriteEngine
 	sources          []schema.GroupVersionKind
 	sourceCache      map[schema.GroupVersionKind]*cache.Store
 	target           schema.GroupVersionKind
 	targetCache      *cache.Store
-	snapshotGraph    *dbsp.ChainGraph
-	snapshotExecutor *dbsp.SnapshotExecutor
-	mu               sync.Mutex // Protects against concurrent Evaluate/Sync calls
+	snapshotDAG      *dbsp.DAG
+	snapshotExecutor *dbsp.DAGExecutor
+	defs             map[string]any   // named sub-expressions bound by @define
+	clock            func() time.Time // closes the windows of @window ops
+	timeVariant      bool             // whether the pipeline has time-variant ops
+	mu               sync.Mutex       // Protects against concurrent Evaluate/Sync calls
 	log              logr.Logger
 }
 
+// Options defines the pipeline configuration.
+type Options struct {
+	// RewriteRules are additional rules for the rewrite engine that optimizes the pipeline. The
+	// rules are tried after the built-in rules, rules that implement dbsp.CostBasedRule are
+	// applied only if they reduce the estimated cost of the plan.
+	RewriteRules []dbsp.LinearChainRule
+	// CostModel overrides the default cost model of the rewrite engine.
+	CostModel dbsp.CostModel
+}
+
 // New creates a new pipeline from the set of base objects and a seralized pipeline that writes
-// into a given target.
-func New(operator string, target schema.GroupVersionKind, sources []schema.GroupVersionKind, config opv1a1.Pipeline, log logr.Logger) (Evaluator, error) {
-	// Check if first operation is @join when multiple sources exist.
-	hasJoin := len(config.Ops) > 0 && config.Ops[0].OpType() == "@join"
-	if len(sources) > 1 && !hasJoin {
-		return nil, errors.New("invalid controller configuration: controllers " +
-			"defined on multiple base resources must specify @join as the first operation in the pipeline")
+// into a given target. The pipeline is configured by the first of the optional options.
+func New(operator string, target schema.GroupVersionKind, sources []schema.GroupVersionKind, config opv1a1.Pipeline, log logr.Logger, options ...Options) (Evaluator, error) {
+	if err := Validate(config); err != nil {
+		return nil, NewPipelineError(err)
+	}
+
+	opts := Options{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	rewriter := dbsp.NewLinearChainRewriteEngine()
+	for _, rule := range opts.RewriteRules {
+		rewriter.AddRule(rule)
+	}
+	if opts.CostModel != nil {
+		rewriter.SetCostModel(opts.CostModel)
 	}
 
 	p := &Pipeline{
 		operator:    operator,
 		config:      config,
-		graph:       dbsp.NewChainGraph(),
-		rewriter:    dbsp.NewLinearChainRewriteEngine(),
+		dag:         dbsp.NewDAG(),
+		rewriter:    rewriter,
 		sources:     sources,
 		sourceCache: make(map[schema.GroupVersionKind]*cache.Store),
 		target:      target,
 		targetCache: cache.NewStore(),
+		clock:       time.Now,
 		log:         log,
 	}
 
-	// Add inputs
+	// Add inputs: streams maps the names visible to the branches and the ops to the DAG nodes
+	// that produce them.
+	streams := map[string]string{}
+	names := []string{}
 	for _, src := range sources {
-		p.graph.AddInput(dbsp.NewInput(src.Kind))
+		if _, ok := streams[src.Kind]; ok {
+			continue
+		}
+		if err := p.dag.AddInput(src.Kind); err != nil {
+			return nil, NewPipelineError(err)
+		}
+		streams[src.Kind] = src.Kind
+		names = append(names, src.Kind)
 	}
 
-	// Process operations.
-	startIdx := 0
+	// Add branches.
+	consumed := map[string]bool{}
+	for i, branch := range config.Branches {
+		inputs := branch.GetSources()
+		refs := make(map[string]string, len(inputs))
+		for _, input := range inputs {
+			ref, ok := streams[input]
+			if !ok {
+				return nil, NewPipelineError(fmt.Errorf("branch %q: unknown source %q", branch.Name, input))
+			}
+			refs[input] = ref
+			consumed[ref] = true
+		}
+
+		node := fmt.Sprintf("branches[%d]", i)
+		if _, err := p.addChain(p.dag, node, branch.Ops, inputs, refs); err != nil {
+			return nil, NewPipelineError(fmt.Errorf("branch %q: %w", branch.Name, err))
+		}
+
+		if _, ok := streams[branch.Name]; !ok {
+			names = append(names, branch.Name)
+		}
+		streams[branch.Name] = node
+	}
+
+	// The ops consume the streams not consumed by any of the branches.
+	inputs := []string{}
+	refs := map[string]string{}
+	for _, name := range names {
+		if ref := streams[name]; !consumed[ref] {
+			inputs = append(inputs, name)
+			refs[name] = ref
+			consumed[ref] = true
+		}
+	}
+	for i, branch := range config.Branches {
+		if !consumed[fmt.Sprintf("branches[%d]", i)] {
+			return nil, NewPipelineError(fmt.Errorf("branch %q is shadowed before being used", branch.Name))
+		}
+	}
+
+	graph, err := p.addChain(p.dag, "pipeline", config.Ops, inputs, refs)
+	if err != nil {
+		return nil, NewPipelineError(err)
+	}
+	p.graph = graph
+
+	p.log.Info("pipeline initialization ready", "num-inputs", len(sources), "graph", p.dag.String())
+
+	// Optimize
+	if err := p.rewriter.OptimizeDAG(p.dag); err != nil {
+		return nil, NewPipelineError(fmt.Errorf("failed to optimize pipeline: %w", err))
+	}
+
+	// Create executor
+	executor, err := dbsp.NewDAGExecutor(p.dag, p.log)
+	if err != nil {
+		return nil, NewPipelineError(fmt.Errorf("failed to create chain executor: %w", err))
+	}
+	p.executor = executor
+
+	p.log.Info("pipeline optimization ready", "graph", p.dag.String())
+
+	return p, nil
+}
+
+// addChain creates a linear chain graph from a list of pipeline ops on the given inputs and adds it
+// to a DAG as a named node. The refs map the inputs to the DAG nodes that feed them. Pipelines
+// with multiple inputs must start with a join or a union, optionally preceded by @define ops.
+func (p *Pipeline) addChain(dag *dbsp.DAG, node string, ops []opv1a1.PipelineOp, inputs []string, refs map[string]string) (*dbsp.ChainGraph, error) {
+	joinIdx := slices.IndexFunc(ops, func(op opv1a1.PipelineOp) bool { return op.OpType() != "@define" })
+	hasJoin := joinIdx >= 0 && (isJoinOp(ops[joinIdx].OpType()) || ops[joinIdx].OpType() == "@union")
+	if len(inputs) > 1 && !hasJoin {
+		return nil, errors.New("invalid controller configuration: controllers " +
+			"defined on multiple base resources must specify @join, @leftJoin, @antiJoin or @union " +
+			"as the first operation in the pipeline")
+	}
+	refs = maps.Clone(refs)
 
-	// Add optional Join (if first operation is @join).
-	if hasJoin {
-		expr := config.Ops[0].GetExpression()
-		joinOp := p.NewJoinOp(expr, sources)
-		p.graph.SetJoin(joinOp)
-		startIdx = 1
+	graph := dbsp.NewChainGraph()
+	for _, input := range inputs {
+		graph.AddInput(dbsp.NewInput(input))
 	}
 
-	// Add the remaining operations to the aggregation chain.
-	for i := startIdx; i < len(config.Ops); i++ {
-		pipelineOp := config.Ops[i]
+	// Definitions are local to the chain.
+	p.defs = nil
+
+	// Process operations.
+	for i, pipelineOp := range ops {
 		expr := pipelineOp.GetExpression()
 		if expr == nil {
-			return nil, NewPipelineError(fmt.Errorf("pipeline operation %s has no expression", pipelineOp.OpType()))
+			return nil, fmt.Errorf("pipeline operation %s has no expression", pipelineOp.OpType())
+		}
+
+		// Definitions are added to the scope of the subsequent operations.
+		if pipelineOp.OpType() == "@define" {
+			if err := p.addDefinitions(expr); err != nil {
+				return nil, fmt.Errorf("invalid @define op: %w", err)
+			}
+			continue
+		}
+
+		// Add optional Join (if first operation is a join).
+		if hasJoin && i == joinIdx && pipelineOp.OpType() != "@union" {
+			graph.SetJoin(p.NewJoinOp(pipelineOp.OpType(), expr, inputs))
+			continue
+		}
+
+		// A union merges the inputs, the projections of the inputs are added as separate
+		// nodes to the DAG.
+		if hasJoin && i == joinIdx {
+			projections, err := p.NewUnionProjections(expr, inputs)
+			if err != nil {
+				return nil, fmt.Errorf("invalid @union op: %w", err)
+			}
+			for _, input := range inputs {
+				op, ok := projections[input]
+				if !ok {
+					continue
+				}
+				projection := dbsp.NewChainGraph()
+				projection.AddInput(dbsp.NewInput(input))
+				projection.AddToChain(op)
+				name := fmt.Sprintf("%s.@union.%s", node, input)
+				if err := dag.AddNode(name, projection, map[string]string{input: refs[input]}); err != nil {
+					return nil, err
+				}
+				refs[input] = name
+			}
+			graph.SetJoin(dbsp.NewNaryAdd(len(inputs)))
+			continue
 		}
 
 		var op dbsp.Operator
@@ -132,46 +306,254 @@ func New(operator string, target schema.GroupVersionKind, sources []schema.Group
 			// @demux is one to many
 			o, err := p.NewUnwindOp(expr)
 			if err != nil {
-				return nil, NewPipelineError(fmt.Errorf("failed to instantiate unwind op: %w", err))
+				return nil, fmt.Errorf("failed to instantiate unwind op: %w", err)
 			}
 			op = o
 		case "@gather", "@mux":
 			// @mux is many to one
 			o, err := p.NewGatherOp(expr)
 			if err != nil {
-				return nil, NewPipelineError(fmt.Errorf("failed to instantiate gather op: %w", err))
+				return nil, fmt.Errorf("failed to instantiate gather op: %w", err)
+			}
+			op = o
+
+		case "@distinct":
+			// @distinct is many to one
+			op = dbsp.NewDistinct()
+
+		case "@window":
+			// @window is many to one and depends on the clock
+			o, err := p.NewWindowOp(expr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to instantiate window op: %w", err)
+			}
+			op = o
+			p.timeVariant = true
+
+		case "@recurse":
+			// @recurse is many to many
+			recurseOp, ok := pipelineOp.(*opv1a1.RecurseOp)
+			if !ok {
+				return nil, fmt.Errorf("invalid @recurse op")
+			}
+			o, err := p.NewRecurseOp(recurseOp)
+			if err != nil {
+				return nil, fmt.Errorf("failed to instantiate recurse op: %w", err)
 			}
 			op = o
 
 		default:
-			return nil, NewPipelineError(fmt.Errorf("unknown pipeline op: %s", pipelineOp.OpType()))
+			return nil, fmt.Errorf("unknown pipeline op: %s", pipelineOp.OpType())
 		}
 
-		p.graph.AddToChain(op)
+		graph.AddToChain(op)
 	}
 
-	p.log.Info("pipeline initialization ready", "num-inputs", len(sources), "graph", p.graph.String())
+	if err := dag.AddNode(node, graph, refs); err != nil {
+		return nil, err
+	}
 
-	// Optimize
-	if err := p.rewriter.Optimize(p.graph); err != nil {
-		return nil, NewPipelineError(fmt.Errorf("failed to optimize pipeline: %w", err))
+	return graph, nil
+}
+
+// Validate statically checks the expressions of a pipeline without instantiating it. Errors report
+// the location of the offending expression in the pipeline, e.g., "pipeline[2].@project.spec.ports".
+func Validate(config opv1a1.Pipeline) error {
+	if len(config.Branches) == 0 {
+		return validateOps(config.Ops, "pipeline")
 	}
 
-	// Create executor
-	executor, err := dbsp.NewExecutor(p.graph, p.log)
-	if err != nil {
-		return nil, NewPipelineError(fmt.Errorf("failed to create chain executor: %w", err))
+	for i, branch := range config.Branches {
+		if err := validateOps(branch.Ops, fmt.Sprintf("pipeline.branches[%d].ops", i)); err != nil {
+			return err
+		}
 	}
-	p.executor = executor
 
-	p.log.Info("pipeline optimization ready", "graph", p.graph.String())
+	return validateOps(config.Ops, "pipeline.ops")
+}
 
-	return p, nil
+// validateOps statically checks a list of pipeline ops.
+func validateOps(ops []opv1a1.PipelineOp, path string) error {
+	vars := map[string]expression.Type{}
+	for i, pipelineOp := range ops {
+		expr := pipelineOp.GetExpression()
+		if expr == nil {
+			return fmt.Errorf("pipeline operation %s has no expression", pipelineOp.OpType())
+		}
+
+		ctx := expression.CompileCtx{
+			Path:      fmt.Sprintf("%s[%d].%s", path, i, pipelineOp.OpType()),
+			Variables: vars,
+		}
+
+		switch pipelineOp.OpType() {
+		case "@define":
+			defs, ok := expr.Literal.(map[string]expression.Expression)
+			if expr.Op != "@dict" || !ok {
+				return expression.NewCompileError(ctx.Path, errors.New("expected a map of definitions"))
+			}
+
+			// definitions may refer to each other but not to themselves
+			scope := maps.Clone(vars)
+			for name := range defs {
+				scope[name] = expression.TypeAny
+			}
+			next := maps.Clone(scope)
+			for _, name := range slices.Sorted(maps.Keys(defs)) {
+				defCtx := expression.CompileCtx{Path: ctx.Path + "." + name, Variables: maps.Clone(scope)}
+				if !expression.IsValidVariableName(name) {
+					return expression.NewCompileError(defCtx.Path, fmt.Errorf("invalid definition name %q", name))
+				}
+				delete(defCtx.Variables, name)
+				def := defs[name]
+				t, err := def.Compile(defCtx)
+				if err != nil {
+					return err
+				}
+				next[name] = t
+			}
+			vars = next
+
+		case "@union":
+			projections, ok := expr.Literal.(map[string]expression.Expression)
+			if expr.Op != "@dict" || !ok {
+				return expression.NewCompileError(ctx.Path, errors.New("expected a map of projections"))
+			}
+			for _, input := range slices.Sorted(maps.Keys(projections)) {
+				projCtx := expression.CompileCtx{Path: ctx.Path + "." + input, Variables: vars}
+				projection := projections[input]
+				t, err := projection.Compile(projCtx)
+				if err != nil {
+					return err
+				}
+				if !expression.TypeMap.Accepts(t) && !expression.TypeList.Accepts(t) {
+					return expression.NewCompileError(projCtx.Path,
+						fmt.Errorf("expected a map or a list expression, got %s", t))
+				}
+			}
+
+		case "@join", "@leftJoin", "@antiJoin", "@select":
+			t, err := expr.Compile(ctx)
+			if err != nil {
+				return err
+			}
+			if !expression.TypeBool.Accepts(t) {
+				return expression.NewCompileError(ctx.Path, fmt.Errorf("expected a bool expression, got %s", t))
+			}
+
+		case "@project":
+			t, err := expr.Compile(ctx)
+			if err != nil {
+				return err
+			}
+			if !expression.TypeMap.Accepts(t) && !expression.TypeList.Accepts(t) {
+				return expression.NewCompileError(ctx.Path, fmt.Errorf("expected a map or a list expression, got %s", t))
+			}
+
+		case "@unwind", "@demux":
+			if _, err := expr.Compile(ctx); err != nil {
+				return err
+			}
+
+		case "@gather", "@mux":
+			args, err := expression.AsExpOrExpList(expr)
+			if err != nil {
+				return expression.NewCompileError(ctx.Path, err)
+			}
+			if len(args) != 2 {
+				return expression.NewCompileError(ctx.Path, fmt.Errorf("expected 2 expressions, got %d", len(args)))
+			}
+			if args[1].Op == "@dict" {
+				keyCtx := expression.CompileCtx{Path: ctx.Path + "[0]", Variables: vars}
+				if _, err := args[0].Compile(keyCtx); err != nil {
+					return err
+				}
+				if err := validateAggregates(&args[1], ctx.Path+"[1]", vars); err != nil {
+					return err
+				}
+				break
+			}
+			for j := range args {
+				argCtx := expression.CompileCtx{Path: fmt.Sprintf("%s[%d]", ctx.Path, j), Variables: vars}
+				if _, err := args[j].Compile(argCtx); err != nil {
+					return err
+				}
+			}
+
+		case "@distinct":
+			args, ok := expr.Literal.(map[string]expression.Expression)
+			if expr.Op != "@dict" || !ok || len(args) != 0 {
+				return expression.NewCompileError(ctx.Path, errors.New("expected no arguments"))
+			}
+
+		case "@recurse":
+			recurseOp, ok := pipelineOp.(*opv1a1.RecurseOp)
+			if !ok {
+				return expression.NewCompileError(ctx.Path, errors.New("invalid @recurse op"))
+			}
+			if recurseOp.MaxIterations < 0 {
+				return expression.NewCompileError(ctx.Path, errors.New("maxIterations must not be negative"))
+			}
+			if err := validateOps(recurseOp.Base, ctx.Path+".base"); err != nil {
+				return err
+			}
+			if err := validateOps(recurseOp.Step, ctx.Path+".step"); err != nil {
+				return err
+			}
+
+		case "@window":
+			args, err := parseWindowArgs(expr)
+			if err != nil {
+				return expression.NewCompileError(ctx.Path, err)
+			}
+			keyCtx := expression.CompileCtx{Path: ctx.Path + ".key", Variables: vars}
+			if _, err := args.key.Compile(keyCtx); err != nil {
+				return err
+			}
+			tsCtx := expression.CompileCtx{Path: ctx.Path + ".timestamp", Variables: vars}
+			if _, err := args.timestamp.Compile(tsCtx); err != nil {
+				return err
+			}
+			if err := validateAggregates(args.aggregates, ctx.Path+".aggregates", vars); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("unknown pipeline op: %s", pipelineOp.OpType())
+		}
+	}
+
+	return nil
+}
+
+// validateAggregates statically checks a map of JSONPaths to aggregation function calls.
+func validateAggregates(e *expression.Expression, path string, vars map[string]expression.Type) error {
+	funcs, ok := e.Literal.(map[string]expression.Expression)
+	if e.Op != "@dict" || !ok || len(funcs) == 0 {
+		return expression.NewCompileError(path, errors.New("expected a map of aggregation functions"))
+	}
+	for _, name := range slices.Sorted(maps.Keys(funcs)) {
+		aggPath := path + "." + name
+		_, arg, err := newAggregateFunc(funcs[name])
+		if err != nil {
+			return expression.NewCompileError(aggPath, err)
+		}
+		argCtx := expression.CompileCtx{Path: aggPath + "." + funcs[name].Op, Variables: vars}
+		if _, err := arg.Compile(argCtx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isJoinOp checks whether a pipeline op is a join.
+func isJoinOp(opType string) bool {
+	return opType == "@join" || opType == "@leftJoin" || opType == "@antiJoin"
 }
 
 // String stringifies a pipeline.
 func (p *Pipeline) String() string {
-	return p.graph.String()
+	return p.dag.String()
 }
 
 // GetTargetCache returns the pipeline's internal target cache.
@@ -194,22 +576,63 @@ func (p *Pipeline) Evaluate(delta object.Delta) ([]object.Delta, error) {
 
 	p.log.V(2).Info("processing event", "event-type", delta.Type, "object", ObjectKey(delta.Object))
 
-	// Init
-	zset, err := p.ConvertDeltaToZSet(delta)
+	deltas, err := p.evaluate([]object.Delta{delta})
 	if err != nil {
-		return nil, NewPipelineError(fmt.Errorf("failed to convert delta to DBSP zset: %w", err))
+		return nil, err
 	}
 
+	p.log.V(1).Info("eval ready", "event-type", delta.Type, "object", object.Dump(delta.Object),
+		"result", util.Stringify(deltas))
+
+	return deltas, nil
+}
+
+// EvaluateBatch processes a pipeline on a batch of deltas. The deltas are applied to the source
+// caches in order and merged into a single zset per source, which is then evaluated by a single
+// run of the executor. The result is the net change of the target: changes that cancel each other
+// out within the batch, say, an object that is added and then deleted, produce no deltas at all.
+func (p *Pipeline) EvaluateBatch(batch []object.Delta) ([]object.Delta, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.log.V(2).Info("processing event batch", "events", len(batch))
+
+	deltas, err := p.evaluate(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	p.log.V(1).Info("batch eval ready", "events", len(batch), "result", util.Stringify(deltas))
+
+	return deltas, nil
+}
+
+// evaluate runs the executor on a batch of deltas. Must be called with the lock held.
+func (p *Pipeline) evaluate(batch []object.Delta) ([]object.Delta, error) {
 	// Prepare the input zset (one entry for each input): add an empty zset to each input and
-	// init the input for the changed object
+	// sum the changes of the objects per input
 	dzset := make(map[string]*dbsp.DocumentZSet, len(p.sources))
 	for _, src := range p.sources {
 		dzset[src.Kind] = dbsp.NewDocumentZSet()
 	}
-	key := delta.Object.GetKind()
-	dzset[key] = zset
 
-	p.log.V(8).Info("input zset ready", "object", ObjectKey(delta.Object), "zset", zset.String())
+	for _, delta := range batch {
+		zset, err := p.ConvertDeltaToZSet(delta)
+		if err != nil {
+			return nil, NewPipelineError(fmt.Errorf("failed to convert delta to DBSP zset: %w", err))
+		}
+
+		key := delta.Object.GetKind()
+		if input, ok := dzset[key]; ok {
+			zset, err = input.Add(zset)
+			if err != nil {
+				return nil, NewPipelineError(fmt.Errorf("failed to merge DBSP zsets: %w", err))
+			}
+		}
+		dzset[key] = zset
+
+		p.log.V(8).Info("input zset ready", "object", ObjectKey(delta.Object), "zset", zset.String())
+	}
 
 	// Run the DBSP executor
 	res, err := p.executor.Process(dzset)
@@ -227,9 +650,6 @@ func (p *Pipeline) Evaluate(delta object.Delta) ([]object.Delta, error) {
 		return nil, NewPipelineError(fmt.Errorf("failed to update target cache from delta: %w", err))
 	}
 
-	p.log.V(1).Info("eval ready", "event-type", delta.Type, "object", object.Dump(delta.Object),
-		"result", util.Stringify(deltas))
-
 	return deltas, nil
 }
 
@@ -249,14 +669,14 @@ func (p *Pipeline) Sync() ([]object.Delta, error) {
 	defer p.mu.Unlock()
 
 	// On first run, convert incremental graph to snapshot graph and create executor.
-	if p.snapshotGraph == nil {
+	if p.snapshotDAG == nil {
 		var err error
-		p.snapshotGraph, err = dbsp.ToSnapshotGraph(p.graph)
+		p.snapshotDAG, err = dbsp.ToSnapshotDAG(p.dag)
 		if err != nil {
 			return nil, NewPipelineError(fmt.Errorf("failed to convert pipeline to snapshot mode: %w", err))
 		}
 
-		p.snapshotExecutor, err = dbsp.NewSnapshotExecutor(p.snapshotGraph, p.log)
+		p.snapshotExecutor, err = dbsp.NewDAGExecutor(p.snapshotDAG, p.log)
 		if err != nil {
 			return nil, NewPipelineError(fmt.Errorf("failed to create snapshot executor: %w", err))
 		}
@@ -270,7 +690,7 @@ func (p *Pipeline) Sync() ([]object.Delta, error) {
 		cache, ok := p.sourceCache[gvk]
 		if !ok {
 			// No cache for this source yet, use empty ZSet.
-			sourceZSets[p.snapshotGraph.GetInput(gvk.Kind).Name()] = dbsp.NewDocumentZSet()
+			sourceZSets[gvk.Kind] = dbsp.NewDocumentZSet()
 			continue
 		}
 
@@ -284,7 +704,7 @@ func (p *Pipeline) Sync() ([]object.Delta, error) {
 					fmt.Errorf("failed to convert source cache %d (%s) to zset: %w", i, gvk.Kind, err))
 			}
 		}
-		sourceZSets[p.snapshotGraph.GetInput(gvk.Kind).Name()] = zset
+		sourceZSets[gvk.Kind] = zset
 	}
 
 	// Step 2: Run snapshot executor to compute required target state.
@@ -295,6 +715,19 @@ func (p *Pipeline) Sync() ([]object.Delta, error) {
 
 	p.log.V(2).Info("snapshot execution complete", "required-docs", requiredState.Size())
 
+	// Time-variant ops may have changed their output as time passed: let the incremental
+	// executor catch up so that its state remains consistent with the target cache. The
+	// output is dropped, the diff below already accounts for it.
+	if p.timeVariant {
+		inputs := make(map[string]*dbsp.DocumentZSet, len(p.sources))
+		for _, src := range p.sources {
+			inputs[src.Kind] = dbsp.NewDocumentZSet()
+		}
+		if _, err := p.executor.Process(inputs); err != nil {
+			return nil, NewPipelineError(fmt.Errorf("failed to advance the DBSP graph: %w", err))
+		}
+	}
+
 	// Step 3: Convert target cache to ZSet (current target state).
 	currentState := dbsp.NewDocumentZSet()
 	for _, obj := range p.targetCache.List() {
@@ -15,7 +15,9 @@ import (
 
 	"github.com/l7mp/dcontroller/internal/testutils"
 	opv1a1 "github.com/l7mp/dcontroller/pkg/api/operator/v1alpha1"
+	"github.com/l7mp/dcontroller/pkg/cache"
 	"github.com/l7mp/dcontroller/pkg/dbsp"
+	"github.com/l7mp/dcontroller/pkg/expression"
 	"github.com/l7mp/dcontroller/pkg/object"
 )
 
@@ -222,6 +224,432 @@ var _ = Describe("Pipelines", func() {
 			})
 		})
 
+		Describe("Evaluating pipelines with definitions", func() {
+			It("should evaluate named sub-expressions in later stages", func() {
+				jsonData := `
+- '@define':
+    parent: $.pod.spec.parent
+    podName: $.pod.metadata.name
+- '@join':
+    '@eq': [$.dep.metadata.name, $parent]
+- '@define':
+    name:
+      '@concat': [$parent, "--", $podName]
+- '@project':
+    metadata:
+      name: $name
+      namespace: $.pod.metadata.namespace`
+				p, err := newPipeline(jsonData, []string{"pod", "dep"})
+				Expect(err).NotTo(HaveOccurred())
+
+				var deltas []object.Delta
+				for _, o := range []object.Object{dep1, dep2} {
+					deltas, err = p.Evaluate(object.Delta{Type: object.Upserted, Object: o})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(deltas).To(BeEmpty())
+				}
+
+				deltas, err = p.Evaluate(object.Delta{Type: object.Upserted, Object: pod3})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(HaveLen(1))
+				Expect(deltas[0].Object.GetName()).To(Equal("dep2--pod3"))
+				Expect(deltas[0].Object.GetNamespace()).To(Equal("default"))
+			})
+
+			It("should not make definitions visible to earlier stages", func() {
+				jsonData := `
+- '@project':
+    metadata:
+      name: $name
+      namespace: $.metadata.namespace
+- '@define':
+    name: $.metadata.name`
+				_, err := newPipeline(jsonData, []string{"pod"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("pipeline[0].@project.metadata.name"))
+			})
+
+			It("should reject invalid definitions", func() {
+				jsonData := `
+- '@define':
+    invalid-name: $.metadata.name
+- '@project':
+    $.metadata: $.metadata`
+				_, err := newPipeline(jsonData, []string{"pod"})
+				Expect(err).To(HaveOccurred())
+
+				jsonData = `
+- '@define': [$.metadata.name]
+- '@project':
+    $.metadata: $.metadata`
+				_, err = newPipeline(jsonData, []string{"pod"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Describe("Validating pipelines", func() {
+			It("should accept a valid pipeline", func() {
+				jsonData := `
+- '@define':
+    ns: $.metadata.namespace
+- '@select':
+    '@eq': [$ns, "default"]
+- '@project':
+    metadata:
+      name:
+        '@concat': [$.metadata.name, "-", $ns]
+- '@gather': [$.metadata.namespace, $.metadata.name]`
+				_, err := newPipeline(jsonData, []string{"pod"})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should reject an invalid pipeline with the path of the invalid expression", func() {
+				for jsonData, path := range map[string]string{
+					`
+- '@select':
+    '@eq': [$.metadata.namespace, "default"]
+- '@project':
+    metadata: $.metadata
+- '@project':
+    spec:
+      ports:
+        '@unknown': $.spec.ports`: "pipeline[2].@project.spec.ports.@unknown",
+					`
+- '@select':
+    '@concat': [$.metadata.namespace, "default"]`: "pipeline[0].@select",
+					`
+- '@project':
+    '@eq': [$.metadata.namespace, "default"]`: "pipeline[0].@project",
+					`
+- '@define':
+    name:
+      '@len': 1
+- '@project':
+    metadata:
+      name: $name`: "pipeline[0].@define.name.@len",
+					`
+- '@gather': [$.metadata.namespace, $undefined]`: "pipeline[0].@gather[1]",
+				} {
+					_, err := newPipeline(jsonData, []string{"pod"})
+					Expect(err).To(HaveOccurred(), jsonData)
+					Expect(err.Error()).To(ContainSubstring("invalid expression at "+path+":"), jsonData)
+				}
+			})
+		})
+
+		Describe("Evaluating pipelines with @distinct", func() {
+			It("should collapse duplicate objects", func() {
+				jsonData := `
+- '@project':
+    metadata:
+      name: $.spec.image
+      namespace: $.metadata.namespace
+- '@distinct': {}`
+				p, err := newPipeline(jsonData, []string{"pod"})
+				Expect(err).NotTo(HaveOccurred())
+
+				deltas, err := p.Evaluate(object.Delta{Type: object.Upserted, Object: pod1})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(HaveLen(1))
+				Expect(deltas[0].Type).To(Equal(object.Upserted))
+				Expect(deltas[0].Object.GetName()).To(Equal("image1"))
+				Expect(deltas[0].Object.GetNamespace()).To(Equal("default"))
+
+				// pod3 projects onto the same object
+				deltas, err = p.Evaluate(object.Delta{Type: object.Upserted, Object: pod3})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(BeEmpty())
+
+				// the object remains as long as pod3 exists
+				deltas, err = p.Evaluate(object.Delta{Type: object.Deleted, Object: pod1})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(BeEmpty())
+
+				deltas, err = p.Evaluate(object.Delta{Type: object.Deleted, Object: pod3})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(HaveLen(1))
+				Expect(deltas[0].Type).To(Equal(object.Deleted))
+				Expect(deltas[0].Object.GetName()).To(Equal("image1"))
+			})
+
+			It("should reject arguments", func() {
+				_, err := newPipeline(`{"@distinct": [$.metadata.name]}`, []string{"pod"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid expression at pipeline[0].@distinct:"))
+			})
+
+			It("should survive a JSON marshal-unmarshal roundtrip", func() {
+				var config opv1a1.Pipeline
+				Expect(yaml.Unmarshal([]byte(`[{"@select": true}, {"@distinct": {}}]`), &config)).To(Succeed())
+				Expect(config.Ops).To(HaveLen(2))
+				Expect(config.Ops[1]).To(Equal(&opv1a1.DistinctOp{Expression: expression.Expression{
+					Op: "@dict", Literal: map[string]expression.Expression{}}}))
+
+				data, err := json.Marshal(config)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(Equal(`[{"@select":true},{"@distinct":{}}]`))
+
+				data, err = json.Marshal(opv1a1.Pipeline{Ops: []opv1a1.PipelineOp{&opv1a1.DistinctOp{}}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(Equal(`{"@distinct":{}}`))
+			})
+		})
+
+		Describe("Optimizing pipelines", func() {
+			jsonData := `
+- '@join':
+    '@eq':
+      - $.dep.metadata.name
+      - $.pod.spec.parent
+- '@select':
+    '@eq': ["$.pod.metadata.namespace", "default"]
+- '@project':
+    metadata:
+      name:
+        '@concat':
+          - $.dep.metadata.name
+          - "--"
+          - $.pod.metadata.name
+      namespace: $.pod.metadata.namespace`
+
+			It("should push selections and projections below the join", func() {
+				p, err := newPipeline(jsonData, []string{"pod", "dep"})
+				Expect(err).NotTo(HaveOccurred())
+
+				plan := p.(*Pipeline).executor.GetExecutionPlan()
+				Expect(plan).To(ContainSubstring("pod → σ"))
+				Expect(plan).To(ContainSubstring("SelectionPushdown"))
+				Expect(plan).To(ContainSubstring("ProjectionPushdown"))
+
+				names := []string{}
+				for _, o := range []object.Object{dep1, dep2, pod1, pod2, pod3} {
+					deltas, err := p.Evaluate(object.Delta{Type: object.Upserted, Object: o})
+					Expect(err).NotTo(HaveOccurred())
+					for _, d := range deltas {
+						Expect(d.Type).To(Equal(object.Upserted))
+						names = append(names, d.Object.GetName())
+					}
+				}
+				Expect(names).To(ConsistOf("dep1--pod1", "dep2--pod3"))
+			})
+
+			It("should apply user-supplied rewrite rules", func() {
+				rule := &countingRule{}
+				_, err := newPipelineWithOptions(jsonData, []string{"pod", "dep"},
+					Options{RewriteRules: []dbsp.LinearChainRule{rule}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rule.calls).To(BeNumerically(">", 0))
+			})
+		})
+
+		Describe("Evaluating recursive pipelines", func() {
+			jsonData := `
+- '@recurse':
+    base:
+      - '@select':
+          '@eq': ["$.spec.parent", "app"]
+      - '@project':
+          metadata:
+            name: $.metadata.name
+            namespace: $.metadata.namespace
+          root: $.spec.parent
+    step:
+      - '@join':
+          '@eq': ["$.self.metadata.name", "$.input.spec.parent"]
+      - '@project':
+          metadata:
+            name: $.input.metadata.name
+            namespace: $.input.metadata.namespace
+          root: $.self.root`
+
+			newObj := func(name, parent string) object.Object {
+				obj := object.NewViewObject("test", "obj")
+				object.SetContent(obj, map[string]any{"spec": map[string]any{"parent": parent}})
+				object.SetName(obj, "default", name)
+				return obj
+			}
+
+			names := func(deltas []object.Delta, t object.DeltaType) []string {
+				ret := []string{}
+				for _, d := range deltas {
+					Expect(d.Type).To(Equal(t))
+					Expect(d.Object.UnstructuredContent()["root"]).To(Equal("app"))
+					ret = append(ret, d.Object.GetName())
+				}
+				return ret
+			}
+
+			It("should compute the objects owned transitively", func() {
+				p, err := newPipeline(jsonData, []string{"obj"})
+				Expect(err).NotTo(HaveOccurred())
+
+				rs, pod1, pod2 := newObj("rs", "app"), newObj("pod1", "rs"), newObj("pod2", "rs")
+
+				deltas, err := p.Evaluate(object.Delta{Type: object.Upserted, Object: pod1})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(BeEmpty())
+
+				deltas, err = p.Evaluate(object.Delta{Type: object.Upserted, Object: rs})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(names(deltas, object.Upserted)).To(ConsistOf("rs", "pod1"))
+
+				deltas, err = p.Evaluate(object.Delta{Type: object.Upserted, Object: pod2})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(names(deltas, object.Upserted)).To(ConsistOf("pod2"))
+
+				// reparenting the replicaset removes the whole subtree
+				deltas, err = p.Evaluate(object.Delta{Type: object.Upserted, Object: newObj("rs", "other")})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(names(deltas, object.Deleted)).To(ConsistOf("rs", "pod1", "pod2"))
+			})
+
+			It("should reject invalid recursive pipelines", func() {
+				for pipeline, msg := range map[string]string{
+					`[{"@recurse": {"step": [{"@select": true}]}}]`:                             "must specify @join",
+					`[{"@recurse": {"step": [{"@join": {"@len": 1}}]}}]`:                        "pipeline[0].@recurse.step[0].@join",
+					`[{"@recurse": {"base": [{"@project": true}], "step": [{"@join": true}]}}]`: "pipeline[0].@recurse.base[0].@project",
+					`[{"@recurse": {"step": [{"@join": true}], "maxIterations": -1}}]`:          "maxIterations",
+					`[{"@recurse": {"step": [{"@join": true}], "limit": 1}}]`:                   `unknown field "limit"`,
+					`[{"@recurse": {"base": [{"@select": true}]}}]`:                             "no step operations",
+				} {
+					_, err := newPipeline(pipeline, []string{"obj"})
+					Expect(err).To(HaveOccurred(), pipeline)
+					Expect(err.Error()).To(ContainSubstring(msg), pipeline)
+				}
+			})
+		})
+
+		Describe("Evaluating pipelines on batches of deltas", func() {
+			jsonData := `
+- '@join':
+    '@eq':
+      - $.dep.metadata.name
+      - $.pod.spec.parent
+- '@project':
+    $.metadata.name:
+      '@concat':
+        - $.dep.metadata.name
+        - "--"
+        - $.pod.metadata.name
+    $.metadata.namespace: $.pod.metadata.namespace`
+
+			It("should evaluate a batch like the same deltas one by one", func() {
+				p, err := newPipeline(jsonData, []string{"pod", "dep"})
+				Expect(err).NotTo(HaveOccurred())
+
+				batch := []object.Delta{}
+				for _, o := range []object.Object{pod1, dep1, pod2, dep2, pod3} {
+					batch = append(batch, object.Delta{Type: object.Added, Object: o})
+				}
+				deltas, err := p.EvaluateBatch(batch)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(HaveLen(3))
+				names := []string{}
+				for _, d := range deltas {
+					Expect(d.Type).To(Equal(object.Upserted))
+					names = append(names, d.Object.GetName())
+				}
+				Expect(names).To(ConsistOf("dep1--pod1", "dep1--pod2", "dep2--pod3"))
+
+				seq, err := newPipeline(jsonData, []string{"pod", "dep"})
+				Expect(err).NotTo(HaveOccurred())
+				for _, d := range batch {
+					_, err := seq.Evaluate(d)
+					Expect(err).NotTo(HaveOccurred())
+				}
+				Expect(p.GetTargetCache().List()).To(ConsistOf(seq.GetTargetCache().List()))
+			})
+
+			It("should emit only the net change", func() {
+				p, err := newPipeline(jsonData, []string{"pod", "dep"})
+				Expect(err).NotTo(HaveOccurred())
+
+				deltas, err := p.EvaluateBatch([]object.Delta{
+					{Type: object.Added, Object: dep1},
+					{Type: object.Added, Object: dep2},
+					{Type: object.Added, Object: pod1},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(HaveLen(1))
+
+				// add and delete cancel out
+				deltas, err = p.EvaluateBatch([]object.Delta{
+					{Type: object.Added, Object: pod3},
+					{Type: object.Deleted, Object: pod3},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(BeEmpty())
+
+				// an update that is reverted within the batch is a no-op
+				newPod1 := object.DeepCopy(pod1)
+				newPod1.UnstructuredContent()["spec"].(map[string]any)["parent"] = "dep2"
+				deltas, err = p.EvaluateBatch([]object.Delta{
+					{Type: object.Updated, Object: newPod1},
+					{Type: object.Updated, Object: pod1},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(BeEmpty())
+
+				// only the final state of an object counts
+				newPod2 := object.DeepCopy(newPod1)
+				newPod2.UnstructuredContent()["spec"].(map[string]any)["parent"] = "dep3"
+				deltas, err = p.EvaluateBatch([]object.Delta{
+					{Type: object.Updated, Object: newPod1},
+					{Type: object.Updated, Object: newPod2},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(HaveLen(1))
+				Expect(deltas[0].Type).To(Equal(object.Deleted))
+				Expect(deltas[0].Object.GetName()).To(Equal("dep1--pod1"))
+			})
+		})
+
+		Describe("Evaluating pipelines on objects from several clusters", func() {
+			It("should keep the objects with the same name in different clusters apart", func() {
+				jsonData := `
+- '@project':
+    $.metadata.name:
+      '@concat':
+        - $.metadata.annotations['dcontroller.io/origin-cluster']
+        - "--"
+        - $.metadata.name
+    $.metadata.namespace: $.metadata.namespace`
+				p, err := newPipeline(jsonData, []string{"pod"})
+				Expect(err).NotTo(HaveOccurred())
+
+				podA := object.DeepCopy(pod1)
+				podA.SetAnnotations(map[string]string{cache.OriginClusterAnnotation: "cluster-a"})
+				podB := object.DeepCopy(pod1)
+				podB.SetAnnotations(map[string]string{cache.OriginClusterAnnotation: "cluster-b"})
+
+				deltas, err := p.Evaluate(object.Delta{Type: object.Added, Object: podA})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(HaveLen(1))
+				Expect(deltas[0].Type).To(Equal(object.Upserted))
+				Expect(deltas[0].Object.GetName()).To(Equal("cluster-a--pod1"))
+
+				deltas, err = p.Evaluate(object.Delta{Type: object.Added, Object: podB})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(HaveLen(1))
+				Expect(deltas[0].Type).To(Equal(object.Upserted))
+				Expect(deltas[0].Object.GetName()).To(Equal("cluster-b--pod1"))
+
+				store := p.GetSourceCache(podA.GroupVersionKind())
+				Expect(store.List()).To(HaveLen(2))
+
+				// deleting the object in one cluster leaves the other one alone
+				deltas, err = p.Evaluate(object.Delta{Type: object.Deleted, Object: podB})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deltas).To(HaveLen(1))
+				Expect(deltas[0].Type).To(Equal(object.Deleted))
+				Expect(deltas[0].Object.GetName()).To(Equal("cluster-b--pod1"))
+
+				objs := store.List()
+				Expect(objs).To(HaveLen(1))
+				Expect(objs[0].GetAnnotations()).To(HaveKeyWithValue(cache.OriginClusterAnnotation, "cluster-a"))
+			})
+		})
+
 		Describe("Evaluating pipeline expressions for Update events", func() {
 			It("should evaluate a simple pipeline - 1", func() {
 				jsonData := `
@@ -432,7 +860,7 @@ var _ = Describe("Pipelines", func() {
 				q.graph.AddToChain(dbsp.NewDifferentiator())
 				err = q.rewriter.Optimize(q.graph)
 				Expect(err).NotTo(HaveOccurred())
-				executor, err := dbsp.NewExecutor(q.graph, q.log)
+				executor, err := dbsp.NewDAGExecutor(q.dag, q.log)
 				Expect(err).NotTo(HaveOccurred())
 				q.executor = executor
 
@@ -903,6 +1331,10 @@ var _ = Describe("Pipelines", func() {
 })
 
 func newPipeline(data string, srcs []string) (Evaluator, error) {
+	return newPipelineWithOptions(data, srcs, Options{})
+}
+
+func newPipelineWithOptions(data string, srcs []string, opts Options) (Evaluator, error) {
 	var conf opv1a1.Pipeline
 	err := yaml.Unmarshal([]byte(data), &conf)
 	if err != nil {
@@ -912,9 +1344,21 @@ func newPipeline(data string, srcs []string) (Evaluator, error) {
 	for _, view := range srcs {
 		gvks = append(gvks, opv1a1.GroupVersion.WithKind(view))
 	}
-	p, err := New("test", target, gvks, conf, logger)
+	p, err := New("test", target, gvks, conf, logger, opts)
 	if err != nil {
 		return nil, err
 	}
 	return p, nil
 }
+
+// countingRule is a rewrite rule that never applies but counts how many times it was tried.
+type countingRule struct{ calls int }
+
+func (r *countingRule) Name() string { return "Counting" }
+
+func (r *countingRule) CanApply(_ *dbsp.ChainGraph) bool {
+	r.calls++
+	return false
+}
+
+func (r *countingRule) Apply(_ *dbsp.ChainGraph) error { return nil }
@@ -17,7 +17,7 @@ func (p *Pipeline) ConvertDeltaToZSet(delta object.Delta) (*dbsp.DocumentZSet, e
 	deltaObj := object.DeepCopy(delta.Object)
 	gvk := deltaObj.GetObjectKind().GroupVersionKind()
 	if _, ok := p.sourceCache[gvk]; !ok {
-		p.sourceCache[gvk] = cache.NewStore()
+		p.sourceCache[gvk] = cache.NewClusterStore()
 	}
 
 	var old object.Object
@@ -0,0 +1,184 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	runtimePredicate "sigs.k8s.io/controller-runtime/pkg/predicate"
+	runtimeSource "sigs.k8s.io/controller-runtime/pkg/source"
+
+	opv1a1 "github.com/l7mp/dcontroller/pkg/api/operator/v1alpha1"
+	"github.com/l7mp/dcontroller/pkg/cache"
+	"github.com/l7mp/dcontroller/pkg/manager"
+)
+
+var _ runtimeSource.TypedSyncingSource[Request] = &clusterRuntimeSource{}
+
+const (
+	// DefaultKubeconfigSecretKey is the default key of the kubeconfig in a Secret referred to by
+	// a cluster reference.
+	DefaultKubeconfigSecretKey = "kubeconfig"
+
+	// ClusterConfigTimeout is the timeout for loading the kubeconfig of a remote cluster.
+	ClusterConfigTimeout = 30 * time.Second
+)
+
+// checkLocalSource rejects cluster references in the sources that cannot watch remote clusters.
+func checkLocalSource(s opv1a1.Source) error {
+	if s.Cluster != nil {
+		return fmt.Errorf("%s sources do not support cluster references", s.Type)
+	}
+	return nil
+}
+
+// validateClusterReference checks a cluster reference without loading the kubeconfig.
+func validateClusterReference(ref *opv1a1.ClusterReference) error {
+	switch {
+	case ref.Name == "":
+		return errors.New("cluster reference requires a name")
+	case ref.SecretRef != nil && ref.File != "":
+		return fmt.Errorf("cluster %q: only one of secretRef and file can be given", ref.Name)
+	case ref.SecretRef == nil && ref.File == "":
+		return fmt.Errorf("cluster %q: either secretRef or file must be given", ref.Name)
+	}
+	return nil
+}
+
+// getClusterCache returns the cache that serves the objects of a remote cluster. The cache is
+// created on first use from the kubeconfig the cluster reference refers to and it is shared by
+// all the sources that refer to the same cluster.
+func getClusterCache(ctx context.Context, mgr manager.Manager, ref *opv1a1.ClusterReference) (cache.Cache, error) {
+	if err := validateClusterReference(ref); err != nil {
+		return nil, err
+	}
+
+	cc, ok := mgr.GetCache().(*cache.CompositeCache)
+	if !ok {
+		return nil, fmt.Errorf("cluster %q: watching remote clusters requires a composite cache, got %T",
+			ref.Name, mgr.GetCache())
+	}
+
+	config, err := getClusterConfig(ctx, mgr, ref)
+	if err != nil {
+		return nil, fmt.Errorf("cluster %q: %w", ref.Name, err)
+	}
+
+	return cc.NewClusterCache(ref.Name, config)
+}
+
+// getClusterConfig loads the REST config of a remote cluster from the kubeconfig Secret or file
+// the cluster reference refers to. The cluster reference must be valid.
+func getClusterConfig(ctx context.Context, mgr manager.Manager, ref *opv1a1.ClusterReference) (*rest.Config, error) {
+	var kubeconfig []byte
+	if ref.SecretRef != nil {
+		// Read the Secret directly from the API server, so that we do not start a cache for
+		// all the Secrets in the cluster.
+		var reader client.Reader = mgr.GetAPIReader()
+		if reader == nil {
+			reader = mgr.GetClient()
+		}
+
+		secret := &unstructured.Unstructured{}
+		secret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+		key := client.ObjectKey{Namespace: ref.SecretRef.Namespace, Name: ref.SecretRef.Name}
+		if err := reader.Get(ctx, key, secret); err != nil {
+			return nil, fmt.Errorf("failed to get kubeconfig secret %s: %w", key, err)
+		}
+
+		dataKey := ref.SecretRef.Key
+		if dataKey == "" {
+			dataKey = DefaultKubeconfigSecretKey
+		}
+		data, ok, err := unstructured.NestedString(secret.UnstructuredContent(), "data", dataKey)
+		if err != nil || !ok {
+			return nil, fmt.Errorf("kubeconfig secret %s has no key %q", key, dataKey)
+		}
+		kubeconfig, err = base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kubeconfig in secret %s: %w", key, err)
+		}
+	} else {
+		data, err := os.ReadFile(ref.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+		kubeconfig = data
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+
+	return config, nil
+}
+
+// clusterRuntimeSource is a controller-runtime source that watches a resource in a remote
+// cluster. The cache of the cluster is loaded when the source is started, so that the kubeconfig
+// is read in the context of the controller.
+type clusterRuntimeSource struct {
+	mgr        manager.Manager
+	ref        *opv1a1.ClusterReference
+	obj        client.Object
+	handler    EventHandler[client.Object]
+	predicates []runtimePredicate.TypedPredicate[client.Object]
+	kind       runtimeSource.TypedSyncingSource[Request]
+	mu         sync.Mutex
+	log        logr.Logger
+}
+
+// Start implements source.TypedSource. It loads the cache of the remote cluster and starts
+// watching the resource in it.
+func (s *clusterRuntimeSource) Start(ctx context.Context, queue workqueue.TypedRateLimitingInterface[Request]) error {
+	loadCtx, cancel := context.WithTimeout(ctx, ClusterConfigTimeout)
+	defer cancel()
+
+	c, err := getClusterCache(loadCtx, s.mgr, s.ref)
+	if err != nil {
+		return err
+	}
+
+	kind := runtimeSource.TypedKind(c, s.obj, s.handler, s.predicates...)
+	if err := kind.Start(ctx, queue); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.kind = kind
+	s.mu.Unlock()
+
+	s.log.V(4).Info("cluster source: started", "cluster", s.ref.Name)
+
+	return nil
+}
+
+// WaitForSync implements source.TypedSyncingSource and waits until the cache of the remote
+// cluster syncs.
+func (s *clusterRuntimeSource) WaitForSync(ctx context.Context) error {
+	s.mu.Lock()
+	kind := s.kind
+	s.mu.Unlock()
+
+	if kind == nil {
+		return fmt.Errorf("cluster %q: source is not started", s.ref.Name)
+	}
+
+	return kind.WaitForSync(ctx)
+}
+
+// String stringifies a cluster source.
+func (s *clusterRuntimeSource) String() string {
+	return fmt.Sprintf("cluster source: %s/%s", s.ref.Name, s.obj.GetObjectKind().GroupVersionKind())
+}
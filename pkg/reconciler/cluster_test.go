@@ -0,0 +1,190 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	runtimeSource "sigs.k8s.io/controller-runtime/pkg/source"
+
+	opv1a1 "github.com/l7mp/dcontroller/pkg/api/operator/v1alpha1"
+	"github.com/l7mp/dcontroller/pkg/cache"
+	"github.com/l7mp/dcontroller/pkg/manager"
+	"github.com/l7mp/dcontroller/pkg/object"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster-b
+  cluster:
+    server: https://cluster-b.example.com:6443
+contexts:
+- name: cluster-b
+  context:
+    cluster: cluster-b
+    user: admin
+current-context: cluster-b
+users:
+- name: admin
+  user:
+    token: secret-token
+`
+
+var _ = Describe("Cluster Sources", func() {
+	var (
+		ctx         context.Context
+		cancel      context.CancelFunc
+		queue       workqueue.TypedRateLimitingInterface[Request]
+		mgr         *manager.FakeManager
+		remoteCache *cache.FakeRuntimeCache
+		group       = ""
+		version     = "v1"
+	)
+
+	newPod := func(name string) object.Object {
+		pod := &unstructured.Unstructured{}
+		pod.SetAPIVersion("v1")
+		pod.SetKind("Pod")
+		object.SetName(pod, "default", name)
+		return pod
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+
+		secret := &unstructured.Unstructured{}
+		secret.SetAPIVersion("v1")
+		secret.SetKind("Secret")
+		object.SetName(secret, "fleet", "cluster-b-kubeconfig")
+		Expect(unstructured.SetNestedField(secret.Object, map[string]any{
+			"kubeconfig": base64.StdEncoding.EncodeToString([]byte(testKubeconfig)),
+		}, "data")).To(Succeed())
+
+		var err error
+		mgr, err = manager.NewFakeManager(manager.Options{Logger: logger}, newPod("local-pod"), secret)
+		Expect(err).NotTo(HaveOccurred())
+
+		remoteCache = cache.NewFakeRuntimeCache(nil)
+		Expect(mgr.GetCompositeCache().AddClusterCache("cluster-b", remoteCache)).To(Succeed())
+
+		queue = workqueue.NewTypedRateLimitingQueue[Request](workqueue.DefaultTypedControllerRateLimiter[Request]())
+	})
+
+	AfterEach(func() {
+		cancel()
+		queue.ShutDown()
+	})
+
+	It("should watch objects in a remote cluster and annotate them with the origin cluster", func() {
+		Expect(remoteCache.Add(newPod("remote-pod"))).To(Succeed())
+
+		s := NewSource(mgr, "test", opv1a1.Source{
+			Resource: opv1a1.Resource{Group: &group, Version: &version, Kind: "Pod"},
+			Cluster: &opv1a1.ClusterReference{
+				Name: "cluster-b",
+				SecretRef: &opv1a1.KubeconfigSecretReference{
+					Name:      "cluster-b-kubeconfig",
+					Namespace: "fleet",
+				},
+			},
+		})
+		Expect(s.String()).To(ContainSubstring("cluster=cluster-b"))
+		src, err := s.GetSource()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(src.Start(ctx, queue)).To(Succeed())
+		Expect(src.(runtimeSource.TypedSyncingSource[Request]).WaitForSync(ctx)).To(Succeed())
+
+		Eventually(queue.Len, time.Second, 10*time.Millisecond).Should(Equal(1))
+		req, _ := queue.Get()
+		queue.Done(req)
+		Expect(req.Name).To(Equal("remote-pod"))
+		Expect(req.EventType).To(Equal(object.Added))
+		Expect(req.Object.GetAnnotations()).To(HaveKeyWithValue(cache.OriginClusterAnnotation, "cluster-b"))
+
+		// the local pod is not watched
+		Consistently(queue.Len, 100*time.Millisecond, 10*time.Millisecond).Should(Equal(0))
+
+		// the object in the remote cache is not modified
+		obj := newPod("remote-pod")
+		Expect(remoteCache.Get(ctx, client.ObjectKeyFromObject(obj), obj)).To(Succeed())
+		Expect(obj.GetAnnotations()).NotTo(HaveKey(cache.OriginClusterAnnotation))
+	})
+
+	It("should load the kubeconfig from a file", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "kubeconfig")
+		Expect(os.WriteFile(file, []byte(testKubeconfig), 0600)).To(Succeed())
+
+		config, err := getClusterConfig(ctx, mgr, &opv1a1.ClusterReference{Name: "cluster-b", File: file})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Host).To(Equal("https://cluster-b.example.com:6443"))
+		Expect(config.BearerToken).To(Equal("secret-token"))
+	})
+
+	It("should reject invalid cluster references", func() {
+		for _, ref := range []*opv1a1.ClusterReference{
+			// no name
+			{File: "/kubeconfig"},
+			// no kubeconfig
+			{Name: "cluster-b"},
+			// both a Secret and a file
+			{Name: "cluster-b", File: "/kubeconfig", SecretRef: &opv1a1.KubeconfigSecretReference{
+				Name: "cluster-b-kubeconfig", Namespace: "fleet"}},
+		} {
+			s := NewSource(mgr, "test", opv1a1.Source{
+				Resource: opv1a1.Resource{Group: &group, Version: &version, Kind: "Pod"},
+				Cluster:  ref,
+			})
+			_, err := s.GetSource()
+			Expect(err).To(HaveOccurred(), "%#v", ref)
+		}
+	})
+
+	It("should fail to start when the kubeconfig cannot be loaded", func() {
+		for _, ref := range []*opv1a1.ClusterReference{
+			// no such Secret
+			{Name: "cluster-c", SecretRef: &opv1a1.KubeconfigSecretReference{
+				Name: "dummy", Namespace: "fleet"}},
+			// no such key
+			{Name: "cluster-c", SecretRef: &opv1a1.KubeconfigSecretReference{
+				Name: "cluster-b-kubeconfig", Namespace: "fleet", Key: "dummy"}},
+			// no such file
+			{Name: "cluster-c", File: filepath.Join(GinkgoT().TempDir(), "dummy")},
+		} {
+			s := NewSource(mgr, "test", opv1a1.Source{
+				Resource: opv1a1.Resource{Group: &group, Version: &version, Kind: "Pod"},
+				Cluster:  ref,
+			})
+			src, err := s.GetSource()
+			Expect(err).NotTo(HaveOccurred(), "%#v", ref)
+			Expect(src.Start(ctx, queue)).NotTo(Succeed(), "%#v", ref)
+
+			syncingSrc, ok := src.(runtimeSource.TypedSyncingSource[Request])
+			Expect(ok).To(BeTrue())
+			Expect(syncingSrc.WaitForSync(ctx)).NotTo(Succeed(), "%#v", ref)
+		}
+	})
+
+	It("should reject cluster references in sources other than watchers", func() {
+		ref := &opv1a1.ClusterReference{Name: "cluster-b", File: "/kubeconfig"}
+		for _, typ := range []opv1a1.SourceType{opv1a1.OneShot, opv1a1.Periodic, opv1a1.Cron,
+			opv1a1.Webhook, opv1a1.File, opv1a1.Events} {
+			s := NewSource(mgr, "test", opv1a1.Source{
+				Resource: opv1a1.Resource{Kind: "Trigger"},
+				Type:     typ,
+				Cluster:  ref,
+			})
+			_, err := s.GetSource()
+			Expect(err).To(HaveOccurred(), string(typ))
+			Expect(err.Error()).To(ContainSubstring("cluster"), string(typ))
+		}
+	})
+})
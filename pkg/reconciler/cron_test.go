@@ -0,0 +1,71 @@
+package reconciler
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cron schedules", func() {
+	next := func(spec string, from time.Time) time.Time {
+		s, err := ParseCronSchedule(spec)
+		Expect(err).NotTo(HaveOccurred())
+		return s.Next(from)
+	}
+
+	// 2026-01-05 is a Monday
+	monday := time.Date(2026, time.January, 5, 1, 30, 0, 0, time.UTC)
+
+	It("should compute the next activation", func() {
+		Expect(next("* * * * *", monday)).To(Equal(monday.Add(time.Minute)))
+		Expect(next("*/15 * * * *", monday.Add(time.Second))).To(Equal(monday.Add(15 * time.Minute)))
+		Expect(next("0 2 * * *", monday)).To(Equal(time.Date(2026, 1, 5, 2, 0, 0, 0, time.UTC)))
+		Expect(next("0 2 * * *", time.Date(2026, 1, 5, 2, 0, 0, 0, time.UTC))).
+			To(Equal(time.Date(2026, 1, 6, 2, 0, 0, 0, time.UTC)))
+		Expect(next("30 4 1 * *", monday)).To(Equal(time.Date(2026, 2, 1, 4, 30, 0, 0, time.UTC)))
+		Expect(next("0 0 29 feb *", monday)).To(Equal(time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)))
+		Expect(next("@hourly", monday)).To(Equal(time.Date(2026, 1, 5, 2, 0, 0, 0, time.UTC)))
+		Expect(next("@yearly", monday)).To(Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("should handle days of the week", func() {
+		// every weekday at 02:00: from Friday evening to Monday
+		friday := time.Date(2026, 1, 9, 20, 0, 0, 0, time.UTC)
+		Expect(next("0 2 * * 1-5", friday)).To(Equal(time.Date(2026, 1, 12, 2, 0, 0, 0, time.UTC)))
+		Expect(next("0 2 * * MON-FRI", friday)).To(Equal(time.Date(2026, 1, 12, 2, 0, 0, 0, time.UTC)))
+		// Sunday is both 0 and 7
+		Expect(next("0 0 * * 7", friday)).To(Equal(time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC)))
+		Expect(next("0 0 * * 0", friday)).To(Equal(time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC)))
+		// restricted day of month and day of week match either
+		Expect(next("0 0 15 * sat", friday)).To(Equal(time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)))
+		Expect(next("0 0 10 * fri", friday)).To(Equal(time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("should evaluate schedules in the time zone of the time", func() {
+		loc, err := time.LoadLocation("Europe/Budapest")
+		Expect(err).NotTo(HaveOccurred())
+
+		// 02:00 CET is 01:00 UTC in winter and 00:00 UTC in summer
+		Expect(next("0 2 * * *", monday.In(loc)).UTC()).To(Equal(time.Date(2026, 1, 6, 1, 0, 0, 0, time.UTC)))
+		summer := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+		Expect(next("0 2 * * *", summer.In(loc)).UTC()).To(Equal(time.Date(2026, 7, 2, 0, 0, 0, 0, time.UTC)))
+
+		// 02:30 does not exist on the day DST starts: the next activation is on the next day
+		dst := time.Date(2026, 3, 28, 12, 0, 0, 0, loc)
+		Expect(next("30 2 * * *", dst)).To(Equal(time.Date(2026, 3, 30, 2, 30, 0, 0, loc)))
+	})
+
+	It("should reject invalid schedules", func() {
+		for _, spec := range []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *",
+			"* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "@every",
+			"a * * * *", "1,x * * * *"} {
+			_, err := ParseCronSchedule(spec)
+			Expect(err).To(HaveOccurred(), spec)
+		}
+	})
+
+	It("should not fire on impossible dates", func() {
+		Expect(next("0 0 30 2 *", monday).IsZero()).To(BeTrue())
+	})
+})
@@ -0,0 +1,675 @@
+package reconciler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	runtimeSource "sigs.k8s.io/controller-runtime/pkg/source"
+
+	opv1a1 "github.com/l7mp/dcontroller/pkg/api/operator/v1alpha1"
+	viewv1a1 "github.com/l7mp/dcontroller/pkg/api/view/v1alpha1"
+	"github.com/l7mp/dcontroller/pkg/manager"
+	"github.com/l7mp/dcontroller/pkg/object"
+)
+
+var _ runtimeSource.TypedSource[Request] = &eventsRuntimeSource{}
+
+const (
+	// DefaultEventsTTL is the default time an event is kept in the view cache after it was last
+	// observed.
+	DefaultEventsTTL = time.Hour
+	// DefaultEventsRateLimit is the default number of events per second an events source
+	// injects into the view cache.
+	DefaultEventsRateLimit = 100.0
+	// DefaultEventsBurst is the default burst size of the rate limiter of an events source.
+	DefaultEventsBurst = 200
+)
+
+// EventsGVR is the resource the events source watches.
+var EventsGVR = schema.GroupVersionResource{Group: "events.k8s.io", Version: "v1", Resource: "events"}
+
+// eventsSource is a source that replays Kubernetes events or API server audit events into the view
+// cache. Events are read either from the events.k8s.io/v1 API, filtered with server-side field and
+// label selectors, or from an audit log file in JSON lines format that is tailed for new events.
+// Each event is injected as a view object of the kind of the source, so events flow through the
+// pipeline with Added/Updated/Deleted semantics.
+//
+// Events are high-volume and ephemeral, so the source keeps the view bounded: events that were
+// last observed longer than the TTL ago are deleted from the view cache (emitting a Deleted delta)
+// and new events exceeding the rate limit are dropped. The watched events are not cached apart
+// from the view.
+type eventsSource struct {
+	Resource
+	mgr      manager.Manager
+	client   client.Client
+	operator string
+	source   opv1a1.Source
+	params   eventsParams
+	limiter  *rate.Limiter
+	clock    clock.WithTicker
+	dynamic  dynamic.Interface // nil: created from the REST config of the manager
+
+	mu      sync.Mutex
+	expiry  map[client.ObjectKey]time.Time // last observation of the events in the view cache
+	dropped int
+
+	err error // parameter error
+	log logr.Logger
+}
+
+type eventsParams struct {
+	FieldSelector string
+	AuditLogPath  string
+	TTL           time.Duration
+	RateLimit     float64
+	Burst         int
+}
+
+// NewEventsSource creates a new events source. The parameters are as follows:
+//   - "fieldSelector": server-side field selector for the events, e.g., "type=Warning",
+//   - "auditLogPath": read the events from this audit log instead of the Kubernetes API,
+//   - "ttl": the time an event is kept after it was last observed (default: 1h),
+//   - "rateLimit" and "burst": the number of events per second injected into the view cache and
+//     the burst size (default: 100 and 200).
+func NewEventsSource(mgr manager.Manager, operator string, s opv1a1.Source) Source {
+	return newEventsSource(mgr, operator, s, clock.RealClock{}, nil)
+}
+
+func newEventsSource(mgr manager.Manager, operator string, s opv1a1.Source, clk clock.WithTicker, dc dynamic.Interface) *eventsSource {
+	src := &eventsSource{
+		mgr:      mgr,
+		client:   mgr.GetClient(),
+		source:   s,
+		operator: operator,
+		Resource: NewResource(mgr, operator, s.Resource),
+		clock:    clk,
+		dynamic:  dc,
+		expiry:   map[client.ObjectKey]time.Time{},
+		params: eventsParams{
+			TTL:       DefaultEventsTTL,
+			RateLimit: DefaultEventsRateLimit,
+			Burst:     DefaultEventsBurst,
+		},
+	}
+
+	var params struct {
+		FieldSelector string   `json:"fieldSelector"`
+		AuditLogPath  string   `json:"auditLogPath"`
+		TTL           string   `json:"ttl"`
+		RateLimit     *float64 `json:"rateLimit"`
+		Burst         *int     `json:"burst"`
+	}
+	if s.Parameters != nil && s.Parameters.Raw != nil {
+		if err := json.Unmarshal(s.Parameters.Raw, &params); err != nil {
+			src.err = fmt.Errorf("invalid events source parameters: %w", err)
+		}
+	}
+
+	src.params.FieldSelector = params.FieldSelector
+	src.params.AuditLogPath = params.AuditLogPath
+	if params.RateLimit != nil {
+		src.params.RateLimit = *params.RateLimit
+	}
+	if params.Burst != nil {
+		src.params.Burst = *params.Burst
+	}
+
+	if src.err == nil && params.TTL != "" {
+		ttl, err := time.ParseDuration(params.TTL)
+		if err != nil || ttl <= 0 {
+			src.err = fmt.Errorf("invalid ttl %q in events source", params.TTL)
+		} else {
+			src.params.TTL = ttl
+		}
+	}
+
+	if src.err == nil && (src.params.RateLimit <= 0 || src.params.Burst <= 0) {
+		src.err = fmt.Errorf("invalid rate limit in events source: rate %g, burst %d",
+			src.params.RateLimit, src.params.Burst)
+	}
+
+	if src.err == nil && params.FieldSelector != "" {
+		if _, err := fields.ParseSelector(params.FieldSelector); err != nil {
+			src.err = fmt.Errorf("invalid field selector %q in events source: %w",
+				params.FieldSelector, err)
+		}
+	}
+
+	if err := checkLocalSource(s); err != nil {
+		src.err = err
+	}
+
+	src.limiter = rate.NewLimiter(rate.Limit(src.params.RateLimit), src.params.Burst)
+
+	log := mgr.GetLogger().WithName("events-source")
+	if src.params.AuditLogPath != "" {
+		log = log.WithValues("audit-log", src.params.AuditLogPath)
+	} else {
+		log = log.WithValues("field-selector", src.params.FieldSelector)
+	}
+	src.log = log
+
+	return src
+}
+
+// String stringifies an events source.
+func (s *eventsSource) String() string {
+	if s.params.AuditLogPath != "" {
+		return fmt.Sprintf("%s(audit-log=%s,ttl=%s)", s.Resource.String(), s.params.AuditLogPath,
+			s.params.TTL)
+	}
+	return fmt.Sprintf("%s(field-selector=%s,ttl=%s)", s.Resource.String(), s.params.FieldSelector,
+		s.params.TTL)
+}
+
+// Type returns the source type.
+func (s *eventsSource) Type() opv1a1.SourceType { return opv1a1.Events }
+
+// GetSource generates a controller-runtime source that replays the events.
+func (s *eventsSource) GetSource() (runtimeSource.TypedSource[Request], error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	gvk, err := s.GetGVK()
+	if err != nil {
+		return nil, err
+	}
+	if !viewv1a1.IsViewKind(gvk) {
+		return nil, fmt.Errorf("events source must produce view objects, got %s", gvk.String())
+	}
+
+	if s.params.AuditLogPath == "" && s.dynamic == nil {
+		cfg := s.mgr.GetConfig()
+		if cfg == nil {
+			return nil, errors.New("events source requires a Kubernetes API server connection")
+		}
+		dc, err := dynamic.NewForConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for events source: %w", err)
+		}
+		s.dynamic = dc
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+
+	s.log.V(4).Info("events source: ready")
+
+	return &eventsRuntimeSource{
+		src:  s,
+		kind: runtimeSource.TypedKind[client.Object](s.mgr.GetCache(), obj, EventHandler[client.Object]{}),
+		log:  s.log,
+	}, nil
+}
+
+// listWatch returns a list-watcher for the events matching the namespace, the field selector and
+// the label selector of the source.
+func (s *eventsSource) listWatch(ctx context.Context) (toolscache.ListerWatcher, error) {
+	namespace := ""
+	if s.source.Namespace != nil {
+		namespace = *s.source.Namespace
+	}
+
+	labelSelector := ""
+	if s.source.LabelSelector != nil {
+		sel, err := metav1.LabelSelectorAsSelector(s.source.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector in events source: %w", err)
+		}
+		labelSelector = sel.String()
+	}
+
+	ri := s.dynamic.Resource(EventsGVR).Namespace(namespace)
+	setOptions := func(opts *metav1.ListOptions) {
+		opts.FieldSelector = s.params.FieldSelector
+		opts.LabelSelector = labelSelector
+	}
+
+	return &toolscache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			setOptions(&opts)
+			return ri.List(ctx, opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			setOptions(&opts)
+			return ri.Watch(ctx, opts)
+		},
+	}, nil
+}
+
+// inject adds or updates an event in the view cache. Events older than the TTL and new events
+// exceeding the rate limit are dropped. Updates of the events already in the view cache are not
+// rate-limited, so that they do not go stale.
+func (s *eventsSource) inject(ctx context.Context, obj object.Object, observed time.Time) error {
+	now := s.clock.Now()
+	if now.Sub(observed) >= s.params.TTL {
+		s.log.V(5).Info("skipping expired event", "key", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	key := client.ObjectKeyFromObject(obj)
+
+	s.mu.Lock()
+	_, exists := s.expiry[key]
+	if !exists && !s.limiter.AllowN(now, 1) {
+		s.dropped++
+		dropped := s.dropped
+		s.mu.Unlock()
+		s.log.V(2).Info("rate limit exceeded, dropping event", "key", key, "dropped", dropped)
+		return nil
+	}
+	s.expiry[key] = observed
+	s.mu.Unlock()
+
+	if exists {
+		s.log.V(4).Info("updating event", "key", key)
+		return s.client.Update(ctx, obj)
+	}
+	s.log.V(4).Info("adding event", "key", key)
+	return s.client.Create(ctx, obj)
+}
+
+// remove deletes an event from the view cache.
+func (s *eventsSource) remove(ctx context.Context, key client.ObjectKey) error {
+	s.mu.Lock()
+	_, exists := s.expiry[key]
+	delete(s.expiry, key)
+	s.mu.Unlock()
+
+	if !exists {
+		return nil
+	}
+
+	gvk, err := s.GetGVK()
+	if err != nil {
+		return err
+	}
+
+	s.log.V(4).Info("deleting event", "key", key)
+	obj := object.New()
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(key.Namespace)
+	obj.SetName(key.Name)
+	return client.IgnoreNotFound(s.client.Delete(ctx, obj))
+}
+
+// expire deletes the events that were last observed longer than the TTL ago.
+func (s *eventsSource) expire(ctx context.Context) error {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	keys := []client.ObjectKey{}
+	for key, observed := range s.expiry {
+		if now.Sub(observed) >= s.params.TTL {
+			keys = append(keys, key)
+		}
+	}
+	s.mu.Unlock()
+
+	errs := []error{}
+	for _, key := range keys {
+		errs = append(errs, s.remove(ctx, key))
+	}
+
+	return errors.Join(errs...)
+}
+
+// convertEvent converts a Kubernetes event into a view object, and returns the time the event was
+// last observed.
+func (s *eventsSource) convertEvent(u *unstructured.Unstructured) (object.Object, time.Time, error) {
+	gvk, err := s.GetGVK()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// Keep only the metadata that makes sense for a view object.
+	obj := object.New()
+	obj.SetUnstructuredContent(withMetadata(runtime.DeepCopyJSON(u.UnstructuredContent()), u))
+	obj.SetGroupVersionKind(gvk)
+
+	// The last observation is the latest of the timestamps of the event.
+	observed := u.GetCreationTimestamp().Time
+	for _, path := range [][]string{
+		{"eventTime"}, {"series", "lastObservedTime"}, {"deprecatedLastTimestamp"},
+	} {
+		if ts, ok, _ := unstructured.NestedString(u.UnstructuredContent(), path...); ok {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil && t.After(observed) {
+				observed = t
+			}
+		}
+	}
+	if observed.IsZero() {
+		observed = s.clock.Now()
+	}
+
+	return obj, observed, nil
+}
+
+// convertAuditEvent converts an audit event into a view object, and returns the time of the audit
+// event. Audit events are named after their audit ID, so the subsequent stages of the same request
+// update the same object.
+func (s *eventsSource) convertAuditEvent(line []byte) (object.Object, time.Time, error) {
+	gvk, err := s.GetGVK()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var content map[string]any
+	if err := utiljson.Unmarshal(line, &content); err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid audit event: %w", err)
+	}
+
+	id, ok, _ := unstructured.NestedString(content, "auditID")
+	if !ok || id == "" {
+		return nil, time.Time{}, errors.New("invalid audit event: missing auditID")
+	}
+
+	obj := object.New()
+	obj.SetUnstructuredContent(withMetadata(content, nil))
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(id)
+	if ns, ok, _ := unstructured.NestedString(content, "objectRef", "namespace"); ok {
+		obj.SetNamespace(ns)
+	}
+
+	observed := time.Time{}
+	for _, field := range []string{"stageTimestamp", "requestReceivedTimestamp"} {
+		if ts, ok, _ := unstructured.NestedString(content, field); ok {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				observed = t
+				break
+			}
+		}
+	}
+	if observed.IsZero() {
+		observed = s.clock.Now()
+	}
+
+	return obj, observed, nil
+}
+
+// withMetadata replaces the metadata of an object with the name, namespace, labels, annotations
+// and creation timestamp of the given object, or with an empty metadata if the object is nil.
+func withMetadata(content map[string]any, u *unstructured.Unstructured) map[string]any {
+	meta := map[string]any{}
+	if u != nil {
+		if v, ok, _ := unstructured.NestedFieldCopy(u.Object, "metadata"); ok {
+			if m, ok := v.(map[string]any); ok {
+				for _, f := range []string{"name", "namespace", "labels", "annotations", "creationTimestamp"} {
+					if fv, ok := m[f]; ok {
+						meta[f] = fv
+					}
+				}
+			}
+		}
+	}
+	content["metadata"] = meta
+	return content
+}
+
+// watchEvents watches the Kubernetes events and injects them into the view cache. The events are
+// not cached: a reflector feeds them right into the view cache, so only the events kept in the
+// view, that is, the events observed within the TTL that were not dropped by the rate limiter, are
+// held in memory.
+func (s *eventsSource) watchEvents(ctx context.Context) error {
+	lw, err := s.listWatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	reflector := toolscache.NewReflectorWithOptions(lw, &unstructured.Unstructured{},
+		&eventsStore{src: s, ctx: ctx}, toolscache.ReflectorOptions{Name: s.String()})
+	go reflector.Run(ctx.Done())
+
+	return nil
+}
+
+// eventsStore is a reflector store that injects the events into the view cache instead of storing
+// them.
+type eventsStore struct {
+	src *eventsSource
+	ctx context.Context
+}
+
+var _ toolscache.ReflectorStore = &eventsStore{}
+
+// Add injects a new event.
+func (e *eventsStore) Add(o any) error {
+	u, ok := o.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected event object of type %T", o)
+	}
+	obj, observed, err := e.src.convertEvent(u)
+	if err == nil {
+		err = e.src.inject(e.ctx, obj, observed)
+	}
+	if err != nil {
+		e.src.log.Error(err, "failed to inject event", "key", client.ObjectKeyFromObject(u))
+	}
+	return nil
+}
+
+// Update injects an updated event.
+func (e *eventsStore) Update(o any) error { return e.Add(o) }
+
+// Delete removes an event garbage-collected by the API server.
+func (e *eventsStore) Delete(o any) error {
+	if d, ok := o.(toolscache.DeletedFinalStateUnknown); ok {
+		o = d.Obj
+	}
+	u, ok := o.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected event object of type %T", o)
+	}
+	if err := e.src.remove(e.ctx, client.ObjectKeyFromObject(u)); err != nil {
+		e.src.log.Error(err, "failed to delete event", "key", client.ObjectKeyFromObject(u))
+	}
+	return nil
+}
+
+// Replace injects the listed events and removes the events that are no longer listed.
+func (e *eventsStore) Replace(list []any, _ string) error {
+	listed := map[client.ObjectKey]bool{}
+	for _, o := range list {
+		if u, ok := o.(*unstructured.Unstructured); ok {
+			listed[client.ObjectKeyFromObject(u)] = true
+		}
+		if err := e.Add(o); err != nil {
+			return err
+		}
+	}
+
+	e.src.mu.Lock()
+	keys := []client.ObjectKey{}
+	for key := range e.src.expiry {
+		if !listed[key] {
+			keys = append(keys, key)
+		}
+	}
+	e.src.mu.Unlock()
+
+	for _, key := range keys {
+		if err := e.src.remove(e.ctx, key); err != nil {
+			e.src.log.Error(err, "failed to delete event", "key", key)
+		}
+	}
+
+	return nil
+}
+
+// Resync is a no-op.
+func (e *eventsStore) Resync() error { return nil }
+
+// tailAuditLog replays the audit log and then follows the events appended to it. The log is
+// reopened from the beginning if it is rotated.
+func (s *eventsSource) tailAuditLog(ctx context.Context) error {
+	path := filepath.Clean(s.params.AuditLogPath)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	// Watch the directory to survive log rotation.
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close() //nolint:errcheck
+		return fmt.Errorf("failed to watch audit log %s: %w", path, err)
+	}
+
+	go func() {
+		defer watcher.Close() //nolint:errcheck
+
+		var file *os.File
+		var reader *bufio.Reader
+		var partial []byte
+		defer func() {
+			if file != nil {
+				file.Close() //nolint:errcheck
+			}
+		}()
+
+		open := func() {
+			if file != nil {
+				file.Close() //nolint:errcheck
+				file = nil
+			}
+			partial = nil
+			f, err := os.Open(path)
+			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					s.log.Error(err, "failed to open audit log")
+				}
+				return
+			}
+			file, reader = f, bufio.NewReader(f)
+		}
+
+		read := func() {
+			if reader == nil {
+				return
+			}
+			for {
+				line, err := reader.ReadBytes('\n')
+				if err != nil {
+					// keep the partial line until the rest is written
+					partial = append(partial, line...)
+					if !errors.Is(err, io.EOF) {
+						s.log.Error(err, "failed to read audit log")
+					}
+					return
+				}
+				if len(partial) > 0 {
+					line = append(partial, line...)
+					partial = nil
+				}
+				if len(line) <= 1 {
+					continue
+				}
+
+				obj, observed, err := s.convertAuditEvent(line)
+				if err == nil {
+					err = s.inject(ctx, obj, observed)
+				}
+				if err != nil {
+					s.log.Error(err, "failed to inject audit event")
+				}
+			}
+		}
+
+		open()
+		read()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != path {
+					continue
+				}
+				s.log.V(5).Info("audit log changed", "event", event.String())
+				if event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+					open()
+				}
+				read()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				s.log.Error(err, "file watcher error")
+			}
+		}
+	}()
+
+	return nil
+}
+
+// eventsRuntimeSource is a controller-runtime source that replays the events and watches the
+// objects injected into the view cache.
+type eventsRuntimeSource struct {
+	src  *eventsSource
+	kind runtimeSource.TypedSource[Request]
+	log  logr.Logger
+}
+
+// Start implements source.TypedSource.
+func (s *eventsRuntimeSource) Start(ctx context.Context, queue workqueue.TypedRateLimitingInterface[Request]) error {
+	if err := s.kind.Start(ctx, queue); err != nil {
+		return err
+	}
+
+	var err error
+	if s.src.params.AuditLogPath != "" {
+		err = s.src.tailAuditLog(ctx)
+	} else {
+		err = s.src.watchEvents(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Expire the events periodically.
+	interval := max(s.src.params.TTL/10, time.Second)
+	go func() {
+		ticker := s.src.clock.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				if err := s.src.expire(ctx); err != nil {
+					s.log.Error(err, "failed to expire events")
+				}
+			}
+		}
+	}()
+
+	return nil
+}
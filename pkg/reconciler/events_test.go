@@ -0,0 +1,286 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/workqueue"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	opv1a1 "github.com/l7mp/dcontroller/pkg/api/operator/v1alpha1"
+	"github.com/l7mp/dcontroller/pkg/manager"
+	"github.com/l7mp/dcontroller/pkg/object"
+)
+
+var _ = Describe("Events Source", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		queue  workqueue.TypedRateLimitingInterface[Request]
+		mgr    manager.Manager
+		clk    *clocktesting.FakeClock
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		var err error
+		mgr, err = manager.NewFakeManager(manager.Options{Logger: logger})
+		Expect(err).NotTo(HaveOccurred())
+		queue = workqueue.NewTypedRateLimitingQueue[Request](workqueue.DefaultTypedControllerRateLimiter[Request]())
+		clk = clocktesting.NewFakeClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	})
+
+	AfterEach(func() {
+		cancel()
+		queue.ShutDown()
+	})
+
+	// events collects the requests in the queue, keyed by object name.
+	events := func(n int) map[string]Request {
+		ret := map[string]Request{}
+		Eventually(func() int {
+			for queue.Len() > 0 {
+				item, _ := queue.Get()
+				ret[item.Name] = item
+				queue.Done(item)
+			}
+			return len(ret)
+		}, 2*time.Second, 10*time.Millisecond).Should(Equal(n))
+		Consistently(queue.Len, 100*time.Millisecond, 10*time.Millisecond).Should(Equal(0))
+		return ret
+	}
+
+	newEvent := func(name, typ string, at time.Time) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "events.k8s.io/v1",
+			"kind":       "Event",
+			"metadata": map[string]any{
+				"name":            name,
+				"namespace":       "default",
+				"resourceVersion": "1",
+				"uid":             "uid-" + name,
+			},
+			"eventTime": at.Format(time.RFC3339Nano),
+			"type":      typ,
+			"reason":    "BackOff",
+			"regarding": map[string]any{"kind": "Deployment", "name": "web", "namespace": "default"},
+		}}
+		return u
+	}
+
+	newFakeClient := func(objs ...runtime.Object) *fake.FakeDynamicClient {
+		return fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{EventsGVR: "EventList"}, objs...)
+	}
+
+	It("should replay Kubernetes events with server-side field selectors and expire them", func() {
+		dc := newFakeClient(newEvent("ev-1", "Warning", clk.Now()))
+
+		var mu sync.Mutex
+		fieldSelectors := []string{}
+		record := func(action k8stesting.Action) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch a := action.(type) {
+			case k8stesting.ListAction:
+				fieldSelectors = append(fieldSelectors, a.GetListRestrictions().Fields.String())
+			case k8stesting.WatchAction:
+				fieldSelectors = append(fieldSelectors, a.GetWatchRestrictions().Fields.String())
+			}
+		}
+		dc.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			record(action)
+			return false, nil, nil
+		})
+		dc.PrependWatchReactor("events", func(action k8stesting.Action) (bool, watch.Interface, error) {
+			record(action)
+			return false, nil, nil
+		})
+
+		s := newEventsSource(mgr, "test", opv1a1.Source{
+			Resource:   opv1a1.Resource{Kind: "WarningEvent"},
+			Type:       opv1a1.Events,
+			Parameters: &apiextensionsv1.JSON{Raw: []byte(`{"fieldSelector": "type=Warning", "ttl": "10m"}`)},
+		}, clk, dc)
+		Expect(s.Type()).To(Equal(opv1a1.Events))
+		src, err := s.GetSource()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(src.Start(ctx, queue)).To(Succeed())
+
+		evs := events(1)
+		Expect(evs).To(HaveKey("ev-1"))
+		Expect(evs["ev-1"].EventType).To(Equal(object.Added))
+		Expect(evs["ev-1"].GVK.Kind).To(Equal("WarningEvent"))
+		Expect(evs["ev-1"].Namespace).To(Equal("default"))
+		Expect(evs["ev-1"].Object.UnstructuredContent()["reason"]).To(Equal("BackOff"))
+		Expect(evs["ev-1"].Object.GetUID()).NotTo(Equal("uid-ev-1"))
+
+		mu.Lock()
+		Expect(fieldSelectors).NotTo(BeEmpty())
+		for _, fs := range fieldSelectors {
+			Expect(fs).To(Equal("type=Warning"))
+		}
+		mu.Unlock()
+
+		// new event
+		_, err = dc.Resource(EventsGVR).Namespace("default").Create(ctx,
+			newEvent("ev-2", "Warning", clk.Now().Add(5*time.Minute)), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		evs = events(1)
+		Expect(evs).To(HaveKey("ev-2"))
+		Expect(evs["ev-2"].EventType).To(Equal(object.Added))
+
+		// ev-1 expires, ev-2 is not yet old enough
+		clk.Step(10 * time.Minute)
+		evs = events(1)
+		Expect(evs).To(HaveKey("ev-1"))
+		Expect(evs["ev-1"].EventType).To(Equal(object.Deleted))
+
+		// ev-2 is garbage-collected by the API server
+		Expect(dc.Resource(EventsGVR).Namespace("default").Delete(ctx, "ev-2",
+			metav1.DeleteOptions{})).To(Succeed())
+		evs = events(1)
+		Expect(evs).To(HaveKey("ev-2"))
+		Expect(evs["ev-2"].EventType).To(Equal(object.Deleted))
+	})
+
+	It("should replay and tail an audit log", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "audit.log")
+		auditEvent := func(id, stage string, at time.Time) string {
+			return fmt.Sprintf(`{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata",`+
+				`"auditID":"%s","stage":"%s","verb":"delete","objectRef":{"resource":"pods",`+
+				`"namespace":"default","name":"web-1"},"stageTimestamp":"%s"}`+"\n",
+				id, stage, at.Format(time.RFC3339Nano))
+		}
+		// the first event is older than the TTL
+		Expect(os.WriteFile(path, []byte(
+			auditEvent("old", "ResponseComplete", clk.Now().Add(-2*time.Hour))+
+				auditEvent("a-1", "ResponseComplete", clk.Now())), 0600)).To(Succeed())
+
+		s := newEventsSource(mgr, "test", opv1a1.Source{
+			Resource:   opv1a1.Resource{Kind: "AuditEvent"},
+			Type:       opv1a1.Events,
+			Parameters: &apiextensionsv1.JSON{Raw: []byte(`{"auditLogPath": "` + path + `"}`)},
+		}, clk, nil)
+		src, err := s.GetSource()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(src.Start(ctx, queue)).To(Succeed())
+
+		evs := events(1)
+		Expect(evs).To(HaveKey("a-1"))
+		Expect(evs["a-1"].EventType).To(Equal(object.Added))
+		Expect(evs["a-1"].Namespace).To(Equal("default"))
+		Expect(evs["a-1"].GVK.Kind).To(Equal("AuditEvent"))
+		Expect(evs["a-1"].Object.UnstructuredContent()["verb"]).To(Equal("delete"))
+
+		// append new stages, the second line in two writes
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close() //nolint:errcheck
+		_, err = f.WriteString(auditEvent("a-2", "RequestReceived", clk.Now()))
+		Expect(err).NotTo(HaveOccurred())
+		line := auditEvent("a-2", "ResponseComplete", clk.Now())
+		_, err = f.WriteString(line[:20])
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() int {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return len(s.expiry)
+		}, 2*time.Second, 10*time.Millisecond).Should(Equal(2))
+		_, err = f.WriteString(line[20:])
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() any {
+			obj := object.New()
+			obj.SetGroupVersionKind(evs["a-1"].GVK)
+			obj.SetNamespace("default")
+			obj.SetName("a-2")
+			if err := mgr.GetClient().Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+				return err.Error()
+			}
+			return obj.UnstructuredContent()["stage"]
+		}, 2*time.Second, 10*time.Millisecond).Should(Equal("ResponseComplete"))
+	})
+
+	It("should drop events exceeding the rate limit", func() {
+		dc := newFakeClient(
+			newEvent("ev-1", "Warning", clk.Now()),
+			newEvent("ev-2", "Warning", clk.Now()),
+			newEvent("ev-3", "Warning", clk.Now()),
+		)
+
+		s := newEventsSource(mgr, "test", opv1a1.Source{
+			Resource:   opv1a1.Resource{Kind: "WarningEvent"},
+			Type:       opv1a1.Events,
+			Parameters: &apiextensionsv1.JSON{Raw: []byte(`{"rateLimit": 0.001, "burst": 2}`)},
+		}, clk, dc)
+		src, err := s.GetSource()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(src.Start(ctx, queue)).To(Succeed())
+
+		evs := events(2)
+		s.mu.Lock()
+		Expect(s.dropped).To(Equal(1))
+		s.mu.Unlock()
+
+		// updates of the events in the view are not rate-limited
+		var name string
+		for name = range evs {
+			break
+		}
+		ev := newEvent(name, "Warning", clk.Now())
+		ev.Object["reason"] = "Unhealthy"
+		ev.SetResourceVersion("2")
+		_, err = dc.Resource(EventsGVR).Namespace("default").Update(ctx, ev, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		evs = events(1)
+		Expect(evs).To(HaveKey(name))
+		Expect(evs[name].EventType).To(Equal(object.Updated))
+		Expect(evs[name].Object.UnstructuredContent()["reason"]).To(Equal("Unhealthy"))
+		s.mu.Lock()
+		Expect(s.dropped).To(Equal(1))
+		s.mu.Unlock()
+	})
+
+	It("should reject invalid parameters", func() {
+		for _, params := range []string{
+			`{"ttl": "forever"}`,
+			`{"ttl": "-1m"}`,
+			`{"rateLimit": 0}`,
+			`{"burst": -1}`,
+			`{"fieldSelector": "type=="}`,
+		} {
+			s := NewSource(mgr, "test", opv1a1.Source{
+				Resource:   opv1a1.Resource{Kind: "WarningEvent"},
+				Type:       opv1a1.Events,
+				Parameters: &apiextensionsv1.JSON{Raw: []byte(params)},
+			})
+			_, err := s.GetSource()
+			Expect(err).To(HaveOccurred(), params)
+		}
+
+		// no API server
+		s := NewSource(mgr, "test", opv1a1.Source{
+			Resource: opv1a1.Resource{Kind: "WarningEvent"},
+			Type:     opv1a1.Events,
+		})
+		_, err := s.GetSource()
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,342 @@
+package reconciler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	runtimeSource "sigs.k8s.io/controller-runtime/pkg/source"
+
+	opv1a1 "github.com/l7mp/dcontroller/pkg/api/operator/v1alpha1"
+	viewv1a1 "github.com/l7mp/dcontroller/pkg/api/view/v1alpha1"
+	"github.com/l7mp/dcontroller/pkg/manager"
+	"github.com/l7mp/dcontroller/pkg/object"
+)
+
+var _ runtimeSource.TypedSource[Request] = &fileRuntimeSource{}
+
+const (
+	// DefaultFileSourceDebounce is the default time the file source waits for the changes of a
+	// directory to settle before rescanning the directory.
+	DefaultFileSourceDebounce = 100 * time.Millisecond
+
+	// FileSourceRetryPeriod is the time after which the file source retries the changes it failed
+	// to apply to the view cache.
+	FileSourceRetryPeriod = 10 * time.Second
+)
+
+// fileSourceExtensions are the extensions of the files parsed by file sources.
+var fileSourceExtensions = []string{".yaml", ".yml", ".json"}
+
+// fileSource is a source that loads objects from the YAML manifests in a directory. The objects
+// are injected into the view cache as objects of the kind of the source and the directory is
+// watched for changes: each change triggers a rescan of the directory and the objects that
+// appeared, changed or disappeared are added, updated or deleted in the view cache, so the
+// manifests flow through the pipeline with Added/Updated/Deleted semantics.
+//
+// The directory is rescanned as a whole to support volumes that are updated by atomically swapping
+// a symlink, like ConfigMap volumes or git-sync checkouts. Hidden files are ignored.
+type fileSource struct {
+	Resource
+	mgr      manager.Manager
+	client   client.Client
+	operator string
+	source   opv1a1.Source
+	dir      string
+	debounce time.Duration
+	objects  map[string]map[client.ObjectKey]object.Object // objects per file
+	applied  map[client.ObjectKey]object.Object            // objects applied to the view cache
+	err      error                                         // parameter error
+	log      logr.Logger
+}
+
+// NewFileSource creates a new file source. The directory to watch is given in the "path"
+// parameter.
+func NewFileSource(mgr manager.Manager, operator string, s opv1a1.Source) Source {
+	src := &fileSource{
+		mgr:      mgr,
+		client:   mgr.GetClient(),
+		source:   s,
+		operator: operator,
+		Resource: NewResource(mgr, operator, s.Resource),
+		debounce: DefaultFileSourceDebounce,
+		objects:  map[string]map[client.ObjectKey]object.Object{},
+		applied:  map[client.ObjectKey]object.Object{},
+	}
+
+	var params struct {
+		Path string `json:"path"`
+	}
+	if s.Parameters != nil && s.Parameters.Raw != nil {
+		if err := json.Unmarshal(s.Parameters.Raw, &params); err != nil {
+			src.err = fmt.Errorf("invalid file source parameters: %w", err)
+		}
+	}
+
+	if src.err == nil && params.Path == "" {
+		src.err = errors.New("file source requires a path parameter")
+	}
+
+	if err := checkLocalSource(s); err != nil {
+		src.err = err
+	}
+	src.dir = filepath.Clean(params.Path)
+
+	log := mgr.GetLogger().WithName("file-source").WithValues("path", src.dir)
+	src.log = log
+
+	return src
+}
+
+// String stringifies a file source.
+func (s *fileSource) String() string {
+	return fmt.Sprintf("%s(path=%s)", s.Resource.String(), s.dir)
+}
+
+// Type returns the source type.
+func (s *fileSource) Type() opv1a1.SourceType { return opv1a1.File }
+
+// GetSource generates a controller-runtime source that watches the directory.
+func (s *fileSource) GetSource() (runtimeSource.TypedSource[Request], error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	gvk, err := s.GetGVK()
+	if err != nil {
+		return nil, err
+	}
+	if !viewv1a1.IsViewKind(gvk) {
+		return nil, fmt.Errorf("file source must produce view objects, got %s", gvk.String())
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+
+	s.log.V(4).Info("file source: ready")
+
+	return &fileRuntimeSource{
+		src:  s,
+		kind: runtimeSource.TypedKind[client.Object](s.mgr.GetCache(), obj, EventHandler[client.Object]{}),
+		log:  s.log,
+	}, nil
+}
+
+// sync rescans the directory and applies the changes to the view cache.
+func (s *fileSource) sync(ctx context.Context) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", s.dir, err)
+	}
+
+	objects := map[string]map[client.ObjectKey]object.Object{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || !slices.Contains(fileSourceExtensions, filepath.Ext(name)) {
+			continue
+		}
+
+		file := filepath.Join(s.dir, name)
+		objs, err := s.parseFile(file)
+		if err != nil {
+			// Keep the objects of the last valid version of the file, the file may be in the
+			// middle of being written.
+			s.log.Error(err, "failed to parse manifest", "file", file)
+			if old, ok := s.objects[file]; ok {
+				objects[file] = old
+			}
+			continue
+		}
+		objects[file] = objs
+	}
+
+	s.objects = objects
+
+	// Diff against the objects applied to the view cache: a failed write leaves the applied state
+	// of the object unchanged, so the next sync retries it.
+	newObjs := mergeFileObjects(objects)
+	var errs []error
+	for key, obj := range newObjs {
+		oldObj, ok := s.applied[key]
+		var err error
+		switch {
+		case !ok:
+			s.log.V(4).Info("adding object", "key", key)
+			err = s.client.Create(ctx, object.DeepCopy(obj))
+		case !object.DeepEqual(oldObj, obj):
+			s.log.V(4).Info("updating object", "key", key)
+			err = s.client.Update(ctx, object.DeepCopy(obj))
+		default:
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.applied[key] = obj
+	}
+	for key, obj := range s.applied {
+		if _, ok := newObjs[key]; ok {
+			continue
+		}
+		s.log.V(4).Info("deleting object", "key", key)
+		if err := client.IgnoreNotFound(s.client.Delete(ctx, object.DeepCopy(obj))); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(s.applied, key)
+	}
+
+	return errors.Join(errs...)
+}
+
+// parseFile parses the objects from a multi-document YAML or JSON file. Documents without a name
+// are named after the file and the index of the document within the file.
+func (s *fileSource) parseFile(file string) (map[client.ObjectKey]object.Object, error) {
+	gvk, err := s.GetGVK()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	objs := map[client.ObjectKey]object.Object{}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(b)))
+	for i := 0; ; i++ {
+		raw, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read document %d in %s: %w", i, file, err)
+		}
+
+		var doc map[string]any
+		if err := utilyaml.Unmarshal(raw, &doc); err != nil {
+			return nil, fmt.Errorf("invalid document %d in %s: %w", i, file, err)
+		}
+		if doc == nil {
+			// empty document
+			continue
+		}
+
+		obj := object.New()
+		obj.SetUnstructuredContent(doc)
+		obj.SetGroupVersionKind(gvk)
+		if obj.GetName() == "" {
+			obj.SetName(fmt.Sprintf("%s-%d", base, i))
+		}
+
+		key := client.ObjectKeyFromObject(obj)
+		if _, ok := objs[key]; ok {
+			return nil, fmt.Errorf("duplicate object %s in %s", key, file)
+		}
+		objs[key] = obj
+	}
+
+	return objs, nil
+}
+
+// mergeFileObjects merges the objects of all files. If the same object appears in multiple files
+// the object in the file that comes first in lexical order wins.
+func mergeFileObjects(files map[string]map[client.ObjectKey]object.Object) map[client.ObjectKey]object.Object {
+	ret := map[client.ObjectKey]object.Object{}
+	names := make([]string, 0, len(files))
+	for file := range files {
+		names = append(names, file)
+	}
+	slices.Sort(names)
+	for _, file := range names {
+		for key, obj := range files[file] {
+			if _, ok := ret[key]; !ok {
+				ret[key] = obj
+			}
+		}
+	}
+	return ret
+}
+
+// fileRuntimeSource is a controller-runtime source that watches the directory and the objects
+// loaded from the directory.
+type fileRuntimeSource struct {
+	src  *fileSource
+	kind runtimeSource.TypedSource[Request]
+	log  logr.Logger
+}
+
+// Start implements source.TypedSource.
+func (s *fileRuntimeSource) Start(ctx context.Context, queue workqueue.TypedRateLimitingInterface[Request]) error {
+	if err := s.kind.Start(ctx, queue); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	if err := watcher.Add(s.src.dir); err != nil {
+		watcher.Close() //nolint:errcheck
+		return fmt.Errorf("failed to watch directory %s: %w", s.src.dir, err)
+	}
+
+	syncErr := s.src.sync(ctx)
+	if syncErr != nil {
+		s.log.Error(syncErr, "failed to load manifests")
+	}
+
+	go func() {
+		defer watcher.Close() //nolint:errcheck
+
+		// Rescan once the changes settle, or retry after a failed sync.
+		timer := time.NewTimer(0)
+		<-timer.C
+		defer timer.Stop()
+		if syncErr != nil {
+			timer.Reset(FileSourceRetryPeriod)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				s.log.V(5).Info("directory changed", "event", event.String())
+				timer.Reset(s.src.debounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				s.log.Error(err, "file watcher error")
+			case <-timer.C:
+				if err := s.src.sync(ctx); err != nil {
+					s.log.Error(err, "failed to sync manifests")
+					timer.Reset(FileSourceRetryPeriod)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
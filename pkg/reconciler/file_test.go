@@ -0,0 +1,261 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	opv1a1 "github.com/l7mp/dcontroller/pkg/api/operator/v1alpha1"
+	"github.com/l7mp/dcontroller/pkg/manager"
+	"github.com/l7mp/dcontroller/pkg/object"
+)
+
+var _ = Describe("File Source", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		queue  workqueue.TypedRateLimitingInterface[Request]
+		mgr    manager.Manager
+		dir    string
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		var err error
+		mgr, err = manager.NewFakeManager(manager.Options{Logger: logger})
+		Expect(err).NotTo(HaveOccurred())
+		queue = workqueue.NewTypedRateLimitingQueue[Request](workqueue.DefaultTypedControllerRateLimiter[Request]())
+		dir = GinkgoT().TempDir()
+	})
+
+	AfterEach(func() {
+		cancel()
+		queue.ShutDown()
+	})
+
+	write := func(name, content string) {
+		// write atomically so that the watcher never sees a partial file
+		tmp := filepath.Join(dir, "."+name+".tmp")
+		Expect(os.WriteFile(tmp, []byte(content), 0600)).To(Succeed())
+		Expect(os.Rename(tmp, filepath.Join(dir, name))).To(Succeed())
+	}
+
+	// events collects the requests in the queue, keyed by object name.
+	events := func(n int) map[string]Request {
+		ret := map[string]Request{}
+		Eventually(func() int {
+			for queue.Len() > 0 {
+				item, _ := queue.Get()
+				ret[item.Name] = item
+				queue.Done(item)
+			}
+			return len(ret)
+		}, 2*time.Second, 10*time.Millisecond).Should(Equal(n))
+		Consistently(queue.Len, 3*DefaultFileSourceDebounce, 10*time.Millisecond).Should(Equal(0))
+		return ret
+	}
+
+	It("should load the manifests in a directory and follow the changes", func() {
+		write("a.yaml", `
+metadata:
+  name: obj-1
+spec:
+  replicas: 1
+---
+metadata:
+  name: obj-2
+  namespace: ns
+spec:
+  replicas: 2
+`)
+		write("ignored.txt", "metadata:\n  name: ignored\n")
+
+		s := NewSource(mgr, "test", opv1a1.Source{
+			Resource:   opv1a1.Resource{Kind: "Desired"},
+			Type:       opv1a1.File,
+			Parameters: &apiextensionsv1.JSON{Raw: []byte(`{"path": "` + dir + `"}`)},
+		})
+		Expect(s.Type()).To(Equal(opv1a1.File))
+		src, err := s.GetSource()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(src.Start(ctx, queue)).To(Succeed())
+
+		// initial load
+		evs := events(2)
+		Expect(evs).To(HaveKey("obj-1"))
+		Expect(evs["obj-1"].EventType).To(Equal(object.Added))
+		Expect(evs["obj-1"].GVK.Kind).To(Equal("Desired"))
+		Expect(evs["obj-2"].EventType).To(Equal(object.Added))
+		Expect(evs["obj-2"].Namespace).To(Equal("ns"))
+		Expect(evs["obj-2"].Object.UnstructuredContent()["spec"]).
+			To(Equal(map[string]any{"replicas": int64(2)}))
+
+		// update one object, add a file with an unnamed document
+		write("a.yaml", `
+metadata:
+  name: obj-1
+spec:
+  replicas: 1
+---
+metadata:
+  name: obj-2
+  namespace: ns
+spec:
+  replicas: 3
+`)
+		write("b.yml", "spec:\n  replicas: 4\n")
+		evs = events(2)
+		Expect(evs["obj-2"].EventType).To(Equal(object.Updated))
+		Expect(evs["obj-2"].Object.UnstructuredContent()["spec"]).
+			To(Equal(map[string]any{"replicas": int64(3)}))
+		Expect(evs).To(HaveKey("b-0"))
+		Expect(evs["b-0"].EventType).To(Equal(object.Added))
+
+		// invalid manifests keep the last valid version
+		write("b.yml", "spec: [\n")
+		Consistently(queue.Len, 3*DefaultFileSourceDebounce, 10*time.Millisecond).Should(Equal(0))
+
+		// remove a file
+		Expect(os.Remove(filepath.Join(dir, "a.yaml"))).To(Succeed())
+		evs = events(2)
+		Expect(evs["obj-1"].EventType).To(Equal(object.Deleted))
+		Expect(evs["obj-2"].EventType).To(Equal(object.Deleted))
+	})
+
+	It("should follow symlink swaps", func() {
+		// mimic the layout of a ConfigMap volume
+		Expect(os.Mkdir(filepath.Join(dir, "..v1"), 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "..v1", "c.yaml"), []byte("metadata:\n  name: v1\n"), 0600)).
+			To(Succeed())
+		Expect(os.Symlink("..v1", filepath.Join(dir, "..data"))).To(Succeed())
+		Expect(os.Symlink(filepath.Join("..data", "c.yaml"), filepath.Join(dir, "c.yaml"))).To(Succeed())
+
+		s := NewSource(mgr, "test", opv1a1.Source{
+			Resource:   opv1a1.Resource{Kind: "Desired"},
+			Type:       opv1a1.File,
+			Parameters: &apiextensionsv1.JSON{Raw: []byte(`{"path": "` + dir + `"}`)},
+		})
+		src, err := s.GetSource()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(src.Start(ctx, queue)).To(Succeed())
+		Expect(events(1)).To(HaveKey("v1"))
+
+		Expect(os.Mkdir(filepath.Join(dir, "..v2"), 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "..v2", "c.yaml"), []byte("metadata:\n  name: v2\n"), 0600)).
+			To(Succeed())
+		Expect(os.Symlink("..v2", filepath.Join(dir, "..data_tmp"))).To(Succeed())
+		Expect(os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data"))).To(Succeed())
+
+		evs := events(2)
+		Expect(evs["v1"].EventType).To(Equal(object.Deleted))
+		Expect(evs["v2"].EventType).To(Equal(object.Added))
+	})
+
+	It("should retry the writes that failed", func() {
+		s := NewSource(mgr, "test", opv1a1.Source{
+			Resource:   opv1a1.Resource{Kind: "Desired"},
+			Type:       opv1a1.File,
+			Parameters: &apiextensionsv1.JSON{Raw: []byte(`{"path": "` + dir + `"}`)},
+		}).(*fileSource)
+		c := &failingClient{Client: s.client}
+		s.client = c
+
+		gvk, err := s.GetGVK()
+		Expect(err).NotTo(HaveOccurred())
+		get := func() (any, error) {
+			obj := object.New()
+			obj.SetGroupVersionKind(gvk)
+			obj.SetName("obj-1")
+			if err := mgr.GetClient().Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+				return nil, err
+			}
+			return obj.UnstructuredContent()["spec"], nil
+		}
+
+		// add
+		write("a.yaml", "metadata:\n  name: obj-1\nspec: 1\n")
+		c.fail = true
+		Expect(s.sync(ctx)).NotTo(Succeed())
+		_, err = get()
+		Expect(err).To(HaveOccurred())
+		c.fail = false
+		Expect(s.sync(ctx)).To(Succeed())
+		Expect(get()).To(Equal(int64(1)))
+
+		// update
+		write("a.yaml", "metadata:\n  name: obj-1\nspec: 2\n")
+		c.fail = true
+		Expect(s.sync(ctx)).NotTo(Succeed())
+		Expect(get()).To(Equal(int64(1)))
+		c.fail = false
+		Expect(s.sync(ctx)).To(Succeed())
+		Expect(get()).To(Equal(int64(2)))
+
+		// delete
+		Expect(os.Remove(filepath.Join(dir, "a.yaml"))).To(Succeed())
+		c.fail = true
+		Expect(s.sync(ctx)).NotTo(Succeed())
+		Expect(get()).To(Equal(int64(2)))
+		c.fail = false
+		Expect(s.sync(ctx)).To(Succeed())
+		_, err = get()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject invalid parameters", func() {
+		for _, params := range []string{`{}`, `{"path": 1}`} {
+			s := NewSource(mgr, "test", opv1a1.Source{
+				Resource:   opv1a1.Resource{Kind: "Desired"},
+				Type:       opv1a1.File,
+				Parameters: &apiextensionsv1.JSON{Raw: []byte(params)},
+			})
+			_, err := s.GetSource()
+			Expect(err).To(HaveOccurred(), params)
+		}
+
+		s := NewSource(mgr, "test", opv1a1.Source{
+			Resource:   opv1a1.Resource{Kind: "Desired"},
+			Type:       opv1a1.File,
+			Parameters: &apiextensionsv1.JSON{Raw: []byte(`{"path": "/nonexistent"}`)},
+		})
+		src, err := s.GetSource()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(src.Start(ctx, queue)).NotTo(Succeed())
+	})
+})
+
+// failingClient is a client whose writes fail on demand.
+type failingClient struct {
+	client.Client
+	fail bool
+}
+
+func (c *failingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if c.fail {
+		return errors.New("create failed")
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *failingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if c.fail {
+		return errors.New("update failed")
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *failingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if c.fail {
+		return errors.New("delete failed")
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
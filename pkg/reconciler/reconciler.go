@@ -48,12 +48,16 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/l7mp/dcontroller/pkg/cache"
 	"github.com/l7mp/dcontroller/pkg/object"
 	"github.com/l7mp/dcontroller/pkg/util"
 )
 
 type Reconciler = reconcile.TypedReconciler[Request]
-type EventHandler[object client.Object] struct{ log logr.Logger }
+type EventHandler[object client.Object] struct {
+	log     logr.Logger
+	cluster string // remote cluster the objects are watched in, if any
+}
 
 var _ handler.TypedEventHandler[client.Object, Request] = &EventHandler[client.Object]{}
 
@@ -126,6 +130,16 @@ func (h EventHandler[O]) enqueue(obj O, eventType object.DeltaType, q workqueue.
 		snapshot = object.DeepCopy(o)
 	}
 
+	// Annotate objects from remote clusters with the origin cluster.
+	if snapshot != nil && h.cluster != "" {
+		annotations := snapshot.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[cache.OriginClusterAnnotation] = h.cluster
+		snapshot.SetAnnotations(annotations)
+	}
+
 	q.Add(Request{
 		Name:      obj.GetName(),
 		Namespace: obj.GetNamespace(),
@@ -3,12 +3,15 @@ package reconciler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	runtimePredicate "sigs.k8s.io/controller-runtime/pkg/predicate"
 	runtimeSource "sigs.k8s.io/controller-runtime/pkg/source"
@@ -20,6 +23,7 @@ import (
 )
 
 var _ runtimeSource.TypedSource[Request] = &periodicRuntimeSource{}
+var _ runtimeSource.TypedSource[Request] = &cronRuntimeSource{}
 
 const (
 	// OneShotSourceObjectName is the name of the trigger object.
@@ -28,6 +32,9 @@ const (
 	// PeriodicSourceObjectName is the name of the trigger object.
 	PeriodicSourceObjectName = "periodic-trigger"
 
+	// CronSourceObjectName is the name of the trigger object.
+	CronSourceObjectName = "cron-trigger"
+
 	// VirtualSourceTriggeredLabel defines the name of the last-triggered label.
 	VirtualSourceTriggeredLabel = "dcontroller.io/last-triggered"
 )
@@ -53,6 +60,14 @@ func NewSource(mgr manager.Manager, operator string, s opv1a1.Source) Source {
 		return NewOneShotSource(mgr, operator, s)
 	case opv1a1.Periodic:
 		return NewPeriodicSource(mgr, operator, s)
+	case opv1a1.Cron:
+		return NewCronSource(mgr, operator, s)
+	case opv1a1.Webhook:
+		return NewWebhookSource(mgr, operator, s)
+	case opv1a1.File:
+		return NewFileSource(mgr, operator, s)
+	case opv1a1.Events:
+		return NewEventsSource(mgr, operator, s)
 	case opv1a1.Watcher:
 		return NewWatchSource(mgr, operator, s)
 	default:
@@ -84,7 +99,12 @@ func NewWatchSource(mgr manager.Manager, operator string, s opv1a1.Source) Sourc
 }
 
 // String stringifies a watch source.
-func (s *watchSource) String() string { return s.Resource.String() }
+func (s *watchSource) String() string {
+	if s.source.Cluster != nil {
+		return fmt.Sprintf("%s(cluster=%s)", s.Resource.String(), s.source.Cluster.Name)
+	}
+	return s.Resource.String()
+}
 
 // Type returns the source type.
 func (s *watchSource) Type() opv1a1.SourceType { return opv1a1.Watcher }
@@ -122,6 +142,25 @@ func (s *watchSource) GetSource() (runtimeSource.TypedSource[Request], error) {
 		ps = append(ps, predicate.FromNamespace(*s.source.Namespace))
 	}
 
+	// watch a remote cluster: the cluster cache is loaded when the source is started
+	if s.source.Cluster != nil {
+		if err := validateClusterReference(s.source.Cluster); err != nil {
+			return nil, err
+		}
+
+		s.log.V(4).Info("watch source: ready", "GVK", gvk.String(), "predicate-num", len(ps),
+			"cluster", s.source.Cluster.Name)
+
+		return &clusterRuntimeSource{
+			mgr:        s.mgr,
+			ref:        s.source.Cluster,
+			obj:        obj,
+			handler:    EventHandler[client.Object]{cluster: s.source.Cluster.Name},
+			predicates: ps,
+			log:        s.log,
+		}, nil
+	}
+
 	// generic handler
 	src := runtimeSource.TypedKind(s.mgr.GetCache(), obj, EventHandler[client.Object]{}, ps...)
 
@@ -137,6 +176,7 @@ type oneShotSource struct {
 	client   client.Client
 	operator string
 	source   opv1a1.Source
+	err      error // parameter error, reported when the runtime source is created
 	log      logr.Logger
 }
 
@@ -148,6 +188,7 @@ func NewOneShotSource(mgr manager.Manager, operator string, s opv1a1.Source) Sou
 		source:   s,
 		operator: operator,
 		Resource: NewResource(mgr, operator, s.Resource),
+		err:      checkLocalSource(s),
 	}
 
 	log := mgr.GetLogger().WithName("oneshot-source").WithValues("resource", src.Resource.String())
@@ -164,6 +205,10 @@ func (s *oneShotSource) Type() opv1a1.SourceType { return opv1a1.OneShot }
 
 // GetSource generates a controller-runtime source that triggers once.
 func (s *oneShotSource) GetSource() (runtimeSource.TypedSource[Request], error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
 	s.log.V(4).Info("one-shot source: ready")
 
 	// Create an empty object with the source's GVK
@@ -195,6 +240,7 @@ type periodicSource struct {
 	source   opv1a1.Source
 	operator string
 	period   time.Duration
+	err      error // parameter error, reported when the runtime source is created
 	log      logr.Logger
 }
 
@@ -207,6 +253,7 @@ func NewPeriodicSource(mgr manager.Manager, operator string, s opv1a1.Source) So
 		// Periodic sources don't have a real GVK - they just trigger reconciliation
 		Resource: NewResource(mgr, operator, s.Resource),
 		period:   5 * time.Minute, // default period
+		err:      checkLocalSource(s),
 	}
 
 	// Extract period from parameters
@@ -237,6 +284,10 @@ func (s *periodicSource) Type() opv1a1.SourceType { return opv1a1.Periodic }
 
 // GetSource generates a controller-runtime source that triggers periodic reconciliation.
 func (s *periodicSource) GetSource() (runtimeSource.TypedSource[Request], error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
 	s.log.V(4).Info("periodic source: ready")
 	return &periodicRuntimeSource{src: s, log: s.log}, nil
 }
@@ -283,3 +334,173 @@ func (s *periodicRuntimeSource) Start(ctx context.Context, queue workqueue.Typed
 
 	return nil
 }
+
+// cronSource triggers the controller on a cron schedule by injecting a trigger object into the
+// view cache. The trigger object is created when the schedule first fires and updated on each
+// subsequent activation, so the trigger flows through the incremental pipeline just like the
+// one-shot trigger.
+type cronSource struct {
+	Resource
+	mgr      manager.Manager
+	client   client.Client
+	operator string
+	source   opv1a1.Source
+	schedule *CronSchedule
+	location *time.Location
+	clock    clock.Clock
+	err      error // parameter error, reported when the runtime source is created
+	log      logr.Logger
+}
+
+// NewCronSource creates a new cron source. The schedule is given in the "schedule" parameter in
+// the standard cron syntax, the optional "timeZone" parameter is an IANA time zone name that the
+// schedule is evaluated in (default: UTC).
+func NewCronSource(mgr manager.Manager, operator string, s opv1a1.Source) Source {
+	return newCronSource(mgr, operator, s, clock.RealClock{})
+}
+
+func newCronSource(mgr manager.Manager, operator string, s opv1a1.Source, clk clock.Clock) *cronSource {
+	src := &cronSource{
+		mgr:      mgr,
+		client:   mgr.GetClient(),
+		source:   s,
+		operator: operator,
+		Resource: NewResource(mgr, operator, s.Resource),
+		location: time.UTC,
+		clock:    clk,
+	}
+
+	var params struct {
+		Schedule string `json:"schedule"`
+		TimeZone string `json:"timeZone"`
+	}
+	if s.Parameters != nil && s.Parameters.Raw != nil {
+		if err := json.Unmarshal(s.Parameters.Raw, &params); err != nil {
+			src.err = fmt.Errorf("invalid cron source parameters: %w", err)
+		}
+	}
+
+	if src.err == nil && params.Schedule == "" {
+		src.err = errors.New("cron source requires a schedule parameter")
+	}
+
+	if src.err == nil {
+		src.schedule, src.err = ParseCronSchedule(params.Schedule)
+	}
+
+	if src.err == nil && params.TimeZone != "" {
+		loc, err := time.LoadLocation(params.TimeZone)
+		if err != nil {
+			src.err = fmt.Errorf("invalid time zone %q in cron source: %w", params.TimeZone, err)
+		} else {
+			src.location = loc
+		}
+	}
+
+	if err := checkLocalSource(s); err != nil {
+		src.err = err
+	}
+
+	log := mgr.GetLogger().WithName("cron-source").WithValues("schedule", params.Schedule,
+		"time-zone", src.location.String())
+	src.log = log
+
+	return src
+}
+
+// String stringifies a cron source.
+func (s *cronSource) String() string {
+	if s.schedule == nil {
+		return fmt.Sprintf("%s(schedule=<invalid>)", s.Resource.String())
+	}
+	return fmt.Sprintf("%s(schedule=%s,tz=%s)", s.Resource.String(), s.schedule, s.location)
+}
+
+// Type returns the source type.
+func (s *cronSource) Type() opv1a1.SourceType { return opv1a1.Cron }
+
+// GetSource generates a controller-runtime source that triggers on the cron schedule.
+func (s *cronSource) GetSource() (runtimeSource.TypedSource[Request], error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	gvk, err := s.GetGVK()
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+
+	s.log.V(4).Info("cron source: ready")
+
+	return &cronRuntimeSource{
+		src:  s,
+		kind: runtimeSource.TypedKind[client.Object](s.mgr.GetCache(), obj, EventHandler[client.Object]{}),
+		log:  s.log,
+	}, nil
+}
+
+// trigger creates or updates the trigger object for an activation of the schedule.
+func (s *cronSource) trigger(ctx context.Context, at time.Time) error {
+	gvk, err := s.GetGVK()
+	if err != nil {
+		return err
+	}
+
+	obj := object.New()
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(CronSourceObjectName)
+
+	_, err = CreateOrUpdate(ctx, s.client, obj, func() error {
+		obj.SetLabels(map[string]string{VirtualSourceTriggeredLabel: strconv.FormatInt(at.Unix(), 10)})
+		return unstructured.SetNestedStringMap(obj.UnstructuredContent(), map[string]string{
+			"schedule":      s.schedule.String(),
+			"timeZone":      s.location.String(),
+			"lastTriggered": at.Format(time.RFC3339),
+		}, "spec")
+	})
+
+	return err
+}
+
+// cronRuntimeSource is a controller-runtime source that watches the trigger object and updates it
+// on each activation of the schedule.
+type cronRuntimeSource struct {
+	src  *cronSource
+	kind runtimeSource.TypedSource[Request]
+	log  logr.Logger
+}
+
+// Start implements source.TypedSource.
+func (s *cronRuntimeSource) Start(ctx context.Context, queue workqueue.TypedRateLimitingInterface[Request]) error {
+	if err := s.kind.Start(ctx, queue); err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			now := s.src.clock.Now()
+			next := s.src.schedule.Next(now.In(s.src.location))
+			if next.IsZero() {
+				s.log.Info("cron schedule never fires, stopping")
+				return
+			}
+
+			timer := s.src.clock.NewTimer(next.Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+				s.log.V(5).Info("triggering cron source", "time", next.String())
+				if err := s.src.trigger(ctx, next); err != nil {
+					s.log.Error(err, "failed to inject cron trigger")
+				}
+			}
+		}
+	}()
+
+	return nil
+}
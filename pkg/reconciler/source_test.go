@@ -11,6 +11,7 @@ import (
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/util/workqueue"
+	clocktesting "k8s.io/utils/clock/testing"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
@@ -427,4 +428,70 @@ parameters:
 		Expect(item.EventType).To(Equal(object.Updated))
 		Expect(item.Name).To(Equal(PeriodicSourceObjectName))
 	})
+
+	It("Cron Source should inject a trigger object on schedule", func() {
+		// 2026-01-09 is a Friday, 02:00 Budapest time is 01:00 UTC
+		clk := clocktesting.NewFakeClock(time.Date(2026, 1, 9, 0, 59, 0, 0, time.UTC))
+		params := apiextensionsv1.JSON{
+			Raw: []byte(`{"schedule": "0 2 * * 1-5", "timeZone": "Europe/Budapest"}`),
+		}
+		s := newCronSource(mgr, "test", opv1a1.Source{
+			Resource: opv1a1.Resource{
+				Kind: "TestCronTrigger",
+			},
+			Type:       opv1a1.Cron,
+			Parameters: &params,
+		}, clk)
+		Expect(s.Type()).To(Equal(opv1a1.Cron))
+
+		src, err := s.GetSource()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(src.Start(ctx, queue)).To(Succeed())
+
+		// Nothing happens before the schedule fires
+		Eventually(clk.HasWaiters, time.Second, 10*time.Millisecond).Should(BeTrue())
+		Consistently(queue.Len, 50*time.Millisecond, 10*time.Millisecond).Should(Equal(0))
+
+		// Friday 02:00 creates the trigger
+		clk.Step(time.Minute)
+		Eventually(queue.Len, time.Second, 10*time.Millisecond).Should(Equal(1))
+		item, shutdown := queue.Get()
+		Expect(shutdown).To(BeFalse())
+		Expect(item.EventType).To(Equal(object.Added))
+		Expect(item.Name).To(Equal(CronSourceObjectName))
+		Expect(item.GVK.Kind).To(Equal("TestCronTrigger"))
+		lastTriggered, ok, err := unstructured.NestedString(item.Object.UnstructuredContent(), "spec", "lastTriggered")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(lastTriggered).To(Equal("2026-01-09T02:00:00+01:00"))
+		queue.Done(item)
+
+		// The next activation is on Monday: the weekend is skipped
+		Eventually(clk.HasWaiters, time.Second, 10*time.Millisecond).Should(BeTrue())
+		clk.Step(24 * time.Hour)
+		Consistently(queue.Len, 50*time.Millisecond, 10*time.Millisecond).Should(Equal(0))
+		clk.Step(48 * time.Hour)
+		Eventually(queue.Len, time.Second, 10*time.Millisecond).Should(Equal(1))
+		item, shutdown = queue.Get()
+		Expect(shutdown).To(BeFalse())
+		Expect(item.EventType).To(Equal(object.Updated))
+		lastTriggered, _, err = unstructured.NestedString(item.Object.UnstructuredContent(), "spec", "lastTriggered")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lastTriggered).To(Equal("2026-01-12T02:00:00+01:00"))
+		queue.Done(item)
+	})
+
+	It("Cron Source should reject invalid parameters", func() {
+		for _, raw := range []string{`{}`, `{"schedule": "0 2 * *"}`,
+			`{"schedule": "0 2 * * *", "timeZone": "Mars/Olympus_Mons"}`} {
+			params := apiextensionsv1.JSON{Raw: []byte(raw)}
+			s := NewSource(mgr, "test", opv1a1.Source{
+				Resource:   opv1a1.Resource{Kind: "TestCronTrigger"},
+				Type:       opv1a1.Cron,
+				Parameters: &params,
+			})
+			_, err := s.GetSource()
+			Expect(err).To(HaveOccurred(), raw)
+		}
+	})
 })
@@ -0,0 +1,530 @@
+package reconciler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	runtimeSource "sigs.k8s.io/controller-runtime/pkg/source"
+
+	opv1a1 "github.com/l7mp/dcontroller/pkg/api/operator/v1alpha1"
+	viewv1a1 "github.com/l7mp/dcontroller/pkg/api/view/v1alpha1"
+	"github.com/l7mp/dcontroller/pkg/auth"
+	"github.com/l7mp/dcontroller/pkg/manager"
+	"github.com/l7mp/dcontroller/pkg/object"
+)
+
+var _ runtimeSource.TypedSource[Request] = &webhookRuntimeSource{}
+
+const (
+	// DefaultWebhookAddress is the default listen address of webhook sources. Webhook sources
+	// that listen on the same address share a server that routes the requests by URL path.
+	DefaultWebhookAddress = ":8089"
+
+	// DefaultWebhookPath is the default URL path of webhook sources.
+	DefaultWebhookPath = "/"
+
+	// MaxWebhookRequestSize is the limit on the size of the body of webhook requests.
+	MaxWebhookRequestSize = 1 << 20
+)
+
+// webhookParams are the parameters of a webhook source.
+type webhookParams struct {
+	// Address is the address the webhook server listens on.
+	Address string `json:"address"`
+	// Path is the URL path of the webhook.
+	Path string `json:"path"`
+	// Token is a static bearer token clients must present.
+	Token string `json:"token"`
+	// TokenFile is a file holding the static bearer token, e.g., a mounted secret.
+	TokenFile string `json:"tokenFile"`
+	// PublicKeyFile is a certificate file with the public key for validating the JWT bearer
+	// tokens issued by the dcontroller token generator.
+	PublicKeyFile string `json:"publicKeyFile"`
+	// Audience is the audience the JWT bearer tokens must be issued for. Default:
+	// "<operator>/<kind>".
+	Audience string `json:"audience"`
+	// CertFile and KeyFile enable TLS.
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+}
+
+// webhookSource is a source that exposes an authenticated HTTP endpoint. Each JSON document
+// POSTed to the endpoint is injected into the view cache as an object of the kind of the source,
+// and a DELETE request on the path of the object removes the object, so the posted documents flow
+// through the pipeline with Added/Deleted semantics.
+type webhookSource struct {
+	Resource
+	mgr      manager.Manager
+	client   client.Client
+	operator string
+	source   opv1a1.Source
+	params   webhookParams
+	token    string
+	jwt      *auth.JWTAuthenticator
+	mu       sync.Mutex // serializes the creation of objects
+	err      error      // parameter error, reported when the runtime source is created
+	log      logr.Logger
+}
+
+// NewWebhookSource creates a new webhook source. The parameters are the listen "address"
+// (default: ":8089"), the URL "path" (default: "/"), the credentials clients must present as a
+// bearer token, given as a static "token" or "tokenFile" or as a "publicKeyFile" for validating
+// JWTs issued for the "audience" (default: "<operator>/<kind>"), and optionally the "certFile"
+// and "keyFile" to serve HTTPS. Webhook sources with the same address share a server, so their
+// paths must differ.
+func NewWebhookSource(mgr manager.Manager, operator string, s opv1a1.Source) Source {
+	src := &webhookSource{
+		mgr:      mgr,
+		client:   mgr.GetClient(),
+		source:   s,
+		operator: operator,
+		Resource: NewResource(mgr, operator, s.Resource),
+		params:   webhookParams{Address: DefaultWebhookAddress, Path: DefaultWebhookPath},
+	}
+
+	if s.Parameters != nil && s.Parameters.Raw != nil {
+		if err := json.Unmarshal(s.Parameters.Raw, &src.params); err != nil {
+			src.err = fmt.Errorf("invalid webhook source parameters: %w", err)
+		}
+	}
+
+	if src.err == nil {
+		src.err = src.initAuth()
+	}
+
+	if src.err == nil && (src.params.CertFile == "") != (src.params.KeyFile == "") {
+		src.err = errors.New("webhook source requires both a certFile and a keyFile for TLS")
+	}
+
+	if src.err == nil && !strings.HasPrefix(src.params.Path, "/") {
+		src.err = fmt.Errorf("invalid webhook path %q: must start with a /", src.params.Path)
+	}
+
+	if err := checkLocalSource(s); err != nil {
+		src.err = err
+	}
+
+	log := mgr.GetLogger().WithName("webhook-source").WithValues("address", src.params.Address,
+		"path", src.params.Path)
+	src.log = log
+
+	return src
+}
+
+// initAuth sets up the authentication of webhook requests.
+func (s *webhookSource) initAuth() error {
+	s.token = s.params.Token
+	if s.params.TokenFile != "" {
+		b, err := os.ReadFile(s.params.TokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read webhook token: %w", err)
+		}
+		s.token = strings.TrimSpace(string(b))
+	}
+
+	if s.params.PublicKeyFile != "" {
+		key, err := auth.LoadPublicKey(s.params.PublicKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load webhook public key: %w", err)
+		}
+		audience := s.params.Audience
+		if audience == "" {
+			audience = fmt.Sprintf("%s/%s", s.operator, s.source.Kind)
+		}
+		s.jwt = auth.NewJWTAuthenticatorForAudience(key, audience)
+	}
+
+	if s.token == "" && s.jwt == nil {
+		return errors.New("webhook source requires a token, a tokenFile or a publicKeyFile")
+	}
+
+	return nil
+}
+
+// String stringifies a webhook source.
+func (s *webhookSource) String() string {
+	return fmt.Sprintf("%s(webhook=%s%s)", s.Resource.String(), s.params.Address, s.params.Path)
+}
+
+// Type returns the source type.
+func (s *webhookSource) Type() opv1a1.SourceType { return opv1a1.Webhook }
+
+// GetSource generates a controller-runtime source that serves the webhook.
+func (s *webhookSource) GetSource() (runtimeSource.TypedSource[Request], error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	gvk, err := s.GetGVK()
+	if err != nil {
+		return nil, err
+	}
+	if !viewv1a1.IsViewKind(gvk) {
+		return nil, fmt.Errorf("webhook source must produce view objects, got %s", gvk.String())
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+
+	s.log.V(4).Info("webhook source: ready")
+
+	return &webhookRuntimeSource{
+		src:  s,
+		kind: runtimeSource.TypedKind[client.Object](s.mgr.GetCache(), obj, EventHandler[client.Object]{}),
+		log:  s.log,
+	}, nil
+}
+
+// authenticate checks the bearer token of a request.
+func (s *webhookSource) authenticate(req *http.Request) bool {
+	if s.token != "" {
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1 {
+			return true
+		}
+	}
+
+	if s.jwt != nil {
+		if _, ok, err := s.jwt.AuthenticateRequest(req); ok && err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ServeHTTP serves webhook requests.
+func (s *webhookSource) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !s.authenticate(req) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPost:
+		if req.URL.Path != s.params.Path {
+			http.NotFound(w, req)
+			return
+		}
+		s.handlePost(w, req)
+
+	case http.MethodDelete:
+		prefix := strings.TrimSuffix(s.params.Path, "/") + "/"
+		name, ok := strings.CutPrefix(req.URL.Path, prefix)
+		if !ok || name == "" || strings.Contains(name, "/") {
+			http.NotFound(w, req)
+			return
+		}
+		s.handleDelete(w, req, name)
+
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePost injects the posted JSON document into the view cache.
+func (s *webhookSource) handlePost(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, MaxWebhookRequestSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read request: %s", err), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var doc map[string]any
+	if err := utiljson.Unmarshal(body, &doc); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON document: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	obj, err := s.newObject(doc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.create(req.Context(), obj); err != nil {
+		s.log.V(2).Info("failed to inject webhook object", "object", client.ObjectKeyFromObject(obj),
+			"error", err.Error())
+		writeStatusError(w, err)
+		return
+	}
+
+	s.log.V(4).Info("webhook object added", "object", client.ObjectKeyFromObject(obj))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(obj.UnstructuredContent()) //nolint:errcheck
+}
+
+// create adds a new object to the view cache. Creating an object in the view cache overwrites
+// existing objects, so the existence of the object is checked first.
+func (s *webhookSource) create(ctx context.Context, obj object.Object) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing := object.New()
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := s.client.Get(ctx, client.ObjectKeyFromObject(obj), existing); err == nil {
+		return apierrors.NewAlreadyExists(schema.GroupResource{
+			Group:    obj.GroupVersionKind().Group,
+			Resource: strings.ToLower(obj.GroupVersionKind().Kind),
+		}, obj.GetName())
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return s.client.Create(ctx, obj)
+}
+
+// handleDelete removes an object from the view cache.
+func (s *webhookSource) handleDelete(w http.ResponseWriter, req *http.Request, name string) {
+	gvk, err := s.GetGVK()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	obj := object.New()
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	obj.SetNamespace(req.URL.Query().Get("namespace"))
+
+	if err := s.client.Delete(req.Context(), obj); err != nil {
+		writeStatusError(w, err)
+		return
+	}
+
+	s.log.V(4).Info("webhook object deleted", "object", client.ObjectKeyFromObject(obj))
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// newObject converts a JSON document into an object of the kind of the source. The name,
+// namespace, labels and annotations of the object are taken from the metadata of the document, if
+// any. Documents without a name are assigned a random name.
+func (s *webhookSource) newObject(doc map[string]any) (object.Object, error) {
+	gvk, err := s.GetGVK()
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := doc["metadata"]; ok {
+		meta, ok := m.(map[string]any)
+		if !ok {
+			return nil, errors.New("invalid metadata: expected a map")
+		}
+		// Keep only the metadata the client may set.
+		for k := range meta {
+			switch k {
+			case "name", "namespace", "labels", "annotations":
+			default:
+				delete(meta, k)
+			}
+		}
+	}
+
+	obj := object.New()
+	obj.SetUnstructuredContent(doc)
+	obj.SetGroupVersionKind(gvk)
+	if obj.GetName() == "" {
+		obj.SetName(fmt.Sprintf("%s-%s", strings.ToLower(gvk.Kind), utilrand.String(8)))
+	}
+
+	return obj, nil
+}
+
+// writeStatusError writes an error returned by the client as an HTTP error.
+func writeStatusError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	var status apierrors.APIStatus
+	if errors.As(err, &status) && status.Status().Code != 0 {
+		code = int(status.Status().Code)
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// webhookRuntimeSource is a controller-runtime source that serves the webhook and watches the
+// objects injected by the webhook.
+type webhookRuntimeSource struct {
+	src  *webhookSource
+	kind runtimeSource.TypedSource[Request]
+	addr net.Addr
+	log  logr.Logger
+}
+
+// Start implements source.TypedSource.
+func (s *webhookRuntimeSource) Start(ctx context.Context, queue workqueue.TypedRateLimitingInterface[Request]) error {
+	if err := s.kind.Start(ctx, queue); err != nil {
+		return err
+	}
+
+	params := s.src.params
+	server, err := webhookServers.register(params.Address, params.Path, params.CertFile, params.KeyFile,
+		s.src, s.log)
+	if err != nil {
+		return err
+	}
+	s.addr = server.addr
+
+	go func() {
+		<-ctx.Done()
+		webhookServers.unregister(server, params.Path)
+	}()
+
+	s.log.V(2).Info("webhook source started", "address", s.addr.String())
+
+	return nil
+}
+
+// Addr returns the address the webhook server listens on, or nil if the server is not running.
+func (s *webhookRuntimeSource) Addr() net.Addr { return s.addr }
+
+// webhookServers is the registry of the webhook servers of the process.
+var webhookServers = &webhookServerRegistry{servers: map[string]*webhookServer{}}
+
+// webhookServerRegistry keeps track of the webhook servers by listen address, so that the webhook
+// sources with the same address share a server. Addresses with port 0 ask for a random port, so
+// these are never shared.
+type webhookServerRegistry struct {
+	mu      sync.Mutex
+	servers map[string]*webhookServer
+}
+
+// register adds a webhook to the server that listens on the given address, starting the server
+// if it is not running.
+func (r *webhookServerRegistry) register(address, path, certFile, keyFile string, handler http.Handler, log logr.Logger) (*webhookServer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	server, ok := r.servers[address]
+	if !ok {
+		var err error
+		server, err = newWebhookServer(address, certFile, keyFile, log)
+		if err != nil {
+			return nil, err
+		}
+		if _, port, err := net.SplitHostPort(address); err != nil || port != "0" {
+			r.servers[address] = server
+		}
+	}
+
+	if server.certFile != certFile || server.keyFile != keyFile {
+		return nil, fmt.Errorf("webhook sources listening on %s must use the same TLS certificate", address)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if _, ok := server.handlers[path]; ok {
+		return nil, fmt.Errorf("webhook path %s is already served on %s", path, address)
+	}
+	server.handlers[path] = handler
+
+	return server, nil
+}
+
+// unregister removes a webhook from a server, and stops the server after the last webhook is
+// removed.
+func (r *webhookServerRegistry) unregister(server *webhookServer, path string) {
+	r.mu.Lock()
+	server.mu.Lock()
+	delete(server.handlers, path)
+	empty := len(server.handlers) == 0
+	server.mu.Unlock()
+	if empty && r.servers[server.address] == server {
+		delete(r.servers, server.address)
+	}
+	r.mu.Unlock()
+
+	if empty {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.server.Shutdown(ctx) //nolint:errcheck
+		server.log.V(2).Info("webhook server stopped", "address", server.addr.String())
+	}
+}
+
+// webhookServer is an HTTP server that serves the webhook sources listening on the same address.
+type webhookServer struct {
+	address           string
+	certFile, keyFile string
+	server            *http.Server
+	addr              net.Addr
+	handlers          map[string]http.Handler // URL path -> webhook source
+	mu                sync.RWMutex
+	log               logr.Logger
+}
+
+// newWebhookServer starts a new webhook server.
+func newWebhookServer(address, certFile, keyFile string, log logr.Logger) (*webhookServer, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("webhook source failed to listen on %s: %w", address, err)
+	}
+
+	s := &webhookServer{
+		address:  address,
+		certFile: certFile,
+		keyFile:  keyFile,
+		addr:     listener.Addr(),
+		handlers: map[string]http.Handler{},
+		log:      log,
+	}
+	s.server = &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		var err error
+		if certFile != "" {
+			err = s.server.ServeTLS(listener, certFile, keyFile)
+		} else {
+			err = s.server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error(err, "webhook server failed")
+		}
+	}()
+
+	s.log.V(2).Info("webhook server started", "address", s.addr.String())
+
+	return s, nil
+}
+
+// ServeHTTP routes a request to the webhook with the longest path that is either equal to the
+// path of the request or is a parent of it.
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.RLock()
+	var handler http.Handler
+	longest := -1
+	for path, h := range s.handlers {
+		prefix := strings.TrimSuffix(path, "/") + "/"
+		if (req.URL.Path == path || strings.HasPrefix(req.URL.Path, prefix)) && len(path) > longest {
+			handler, longest = h, len(path)
+		}
+	}
+	s.mu.RUnlock()
+
+	if handler == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	handler.ServeHTTP(w, req)
+}
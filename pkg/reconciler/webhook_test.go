@@ -0,0 +1,243 @@
+package reconciler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/client-go/util/workqueue"
+
+	opv1a1 "github.com/l7mp/dcontroller/pkg/api/operator/v1alpha1"
+	"github.com/l7mp/dcontroller/pkg/auth"
+	"github.com/l7mp/dcontroller/pkg/manager"
+	"github.com/l7mp/dcontroller/pkg/object"
+)
+
+var _ = Describe("Webhook Source", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		queue  workqueue.TypedRateLimitingInterface[Request]
+		mgr    manager.Manager
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		var err error
+		mgr, err = manager.NewFakeManager(manager.Options{Logger: logger})
+		Expect(err).NotTo(HaveOccurred())
+		queue = workqueue.NewTypedRateLimitingQueue[Request](workqueue.DefaultTypedControllerRateLimiter[Request]())
+	})
+
+	AfterEach(func() {
+		cancel()
+		queue.ShutDown()
+	})
+
+	// startWebhook starts a webhook source and returns the URL of the endpoint.
+	startWebhook := func(params string) string {
+		s := NewSource(mgr, "test", opv1a1.Source{
+			Resource:   opv1a1.Resource{Kind: "BuildEvent"},
+			Type:       opv1a1.Webhook,
+			Parameters: &apiextensionsv1.JSON{Raw: []byte(params)},
+		})
+		Expect(s.Type()).To(Equal(opv1a1.Webhook))
+		src, err := s.GetSource()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(src.Start(ctx, queue)).To(Succeed())
+		return "http://" + src.(*webhookRuntimeSource).Addr().String() + "/events"
+	}
+
+	send := func(method, url, token, body string) *http.Response {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close() //nolint:errcheck
+		return resp
+	}
+
+	get := func() Request {
+		Eventually(queue.Len, time.Second, 10*time.Millisecond).Should(Equal(1))
+		item, shutdown := queue.Get()
+		Expect(shutdown).To(BeFalse())
+		queue.Done(item)
+		return item
+	}
+
+	It("should turn posted documents into objects", func() {
+		url := startWebhook(`{"address": "127.0.0.1:0", "path": "/events", "token": "secret"}`)
+
+		// authentication
+		Expect(send(http.MethodPost, url, "", `{}`).StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(send(http.MethodPost, url, "wrong", `{}`).StatusCode).To(Equal(http.StatusUnauthorized))
+		Consistently(queue.Len, 50*time.Millisecond, 10*time.Millisecond).Should(Equal(0))
+
+		// invalid requests
+		Expect(send(http.MethodPost, url, "secret", `not-json`).StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(send(http.MethodGet, url, "secret", ``).StatusCode).To(Equal(http.StatusMethodNotAllowed))
+
+		// add
+		resp := send(http.MethodPost, url, "secret",
+			`{"metadata":{"name":"build-1","namespace":"ci","uid":"x"},"image":"app:v1","replicas":3}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		item := get()
+		Expect(item.EventType).To(Equal(object.Added))
+		Expect(item.GVK.Kind).To(Equal("BuildEvent"))
+		Expect(item.Namespace).To(Equal("ci"))
+		Expect(item.Name).To(Equal("build-1"))
+		Expect(item.Object.UnstructuredContent()["image"]).To(Equal("app:v1"))
+		Expect(item.Object.UnstructuredContent()["replicas"]).To(Equal(int64(3)))
+		Expect(item.Object.GetUID()).NotTo(Equal("x"))
+
+		// duplicate
+		resp = send(http.MethodPost, url, "secret", `{"metadata":{"name":"build-1","namespace":"ci"}}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+		// documents without a name get a random name
+		resp = send(http.MethodPost, url, "secret", `{"image":"app:v2"}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		item = get()
+		Expect(item.EventType).To(Equal(object.Added))
+		Expect(item.Name).To(HavePrefix("buildevent-"))
+
+		// delete
+		Expect(send(http.MethodDelete, url+"/build-1?namespace=ci", "", ``).StatusCode).
+			To(Equal(http.StatusUnauthorized))
+		Expect(send(http.MethodDelete, url+"/build-1?namespace=ci", "secret", ``).StatusCode).
+			To(Equal(http.StatusOK))
+		item = get()
+		Expect(item.EventType).To(Equal(object.Deleted))
+		Expect(item.Name).To(Equal("build-1"))
+		Expect(send(http.MethodDelete, url+"/build-1?namespace=ci", "secret", ``).StatusCode).
+			To(Equal(http.StatusNotFound))
+	})
+
+	It("should authenticate JWTs and read tokens from files", func() {
+		dir := GinkgoT().TempDir()
+		certPEM, keyPEM, err := auth.GenerateSelfSignedCert("localhost")
+		Expect(err).NotTo(HaveOccurred())
+		certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+		Expect(auth.WriteCertAndKey(certFile, keyFile, certPEM, keyPEM)).To(Succeed())
+		tokenFile := filepath.Join(dir, "token")
+		Expect(os.WriteFile(tokenFile, []byte("file-secret\n"), 0600)).To(Succeed())
+
+		params, err := json.Marshal(map[string]string{"address": "127.0.0.1:0", "path": "/events",
+			"tokenFile": tokenFile, "publicKeyFile": certFile})
+		Expect(err).NotTo(HaveOccurred())
+		url := startWebhook(string(params))
+
+		key, err := auth.ParsePrivateKey(keyPEM)
+		Expect(err).NotTo(HaveOccurred())
+		generator := auth.NewTokenGenerator(key)
+		token, err := generator.GenerateTokenForAudience("ci", []string{"test/BuildEvent"}, nil, nil, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(send(http.MethodPost, url, token, `{"metadata":{"name":"a"}}`).StatusCode).
+			To(Equal(http.StatusCreated))
+		Expect(get().Name).To(Equal("a"))
+
+		// tokens issued for another source or without an audience are rejected
+		other, err := generator.GenerateTokenForAudience("ci", []string{"test/OtherEvent"}, nil, nil, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		unscoped, err := generator.GenerateToken("ci", nil, nil, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		for _, t := range []string{other, unscoped} {
+			Expect(send(http.MethodPost, url, t, `{"metadata":{"name":"c"}}`).StatusCode).
+				To(Equal(http.StatusUnauthorized))
+		}
+		Expect(send(http.MethodPost, url, "file-secret", `{"metadata":{"name":"b"}}`).StatusCode).
+			To(Equal(http.StatusCreated))
+		Expect(get().Name).To(Equal("b"))
+	})
+
+	It("should share the server between the webhooks on the same address", func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		address := l.Addr().String()
+		Expect(l.Close()).To(Succeed())
+
+		start := func(kind, path string) error {
+			s := NewSource(mgr, "test", opv1a1.Source{
+				Resource: opv1a1.Resource{Kind: kind},
+				Type:     opv1a1.Webhook,
+				Parameters: &apiextensionsv1.JSON{Raw: []byte(fmt.Sprintf(
+					`{"address": %q, "path": %q, "token": "secret"}`, address, path))},
+			})
+			src, err := s.GetSource()
+			Expect(err).NotTo(HaveOccurred())
+			return src.Start(ctx, queue)
+		}
+		Expect(start("BuildEvent", "/builds")).To(Succeed())
+		Expect(start("AlertEvent", "/alerts")).To(Succeed())
+		// the paths must differ
+		Expect(start("OtherEvent", "/alerts")).NotTo(Succeed())
+
+		url := "http://" + address
+		Expect(send(http.MethodPost, url+"/builds", "secret", `{"metadata":{"name":"a"}}`).StatusCode).
+			To(Equal(http.StatusCreated))
+		Expect(get().GVK.Kind).To(Equal("BuildEvent"))
+		Expect(send(http.MethodPost, url+"/alerts", "secret", `{"metadata":{"name":"a"}}`).StatusCode).
+			To(Equal(http.StatusCreated))
+		Expect(get().GVK.Kind).To(Equal("AlertEvent"))
+		Expect(send(http.MethodDelete, url+"/alerts/a", "secret", ``).StatusCode).To(Equal(http.StatusOK))
+		item := get()
+		Expect(item.GVK.Kind).To(Equal("AlertEvent"))
+		Expect(item.EventType).To(Equal(object.Deleted))
+		Expect(send(http.MethodPost, url+"/other", "secret", `{}`).StatusCode).To(Equal(http.StatusNotFound))
+
+		// the server is stopped with the last webhook
+		cancel()
+		Eventually(func() error {
+			resp, err := http.Post(url+"/builds", "application/json", strings.NewReader(`{}`))
+			if err == nil {
+				resp.Body.Close() //nolint:errcheck
+			}
+			return err
+		}, time.Second, 10*time.Millisecond).Should(HaveOccurred())
+	})
+
+	It("should reject invalid parameters", func() {
+		for _, params := range []string{`{}`, `{"token": "x", "path": "events"}`,
+			`{"token": "x", "certFile": "tls.crt"}`, `{"tokenFile": "/nonexistent"}`} {
+			s := NewSource(mgr, "test", opv1a1.Source{
+				Resource:   opv1a1.Resource{Kind: "BuildEvent"},
+				Type:       opv1a1.Webhook,
+				Parameters: &apiextensionsv1.JSON{Raw: []byte(params)},
+			})
+			_, err := s.GetSource()
+			Expect(err).To(HaveOccurred(), params)
+		}
+
+		// webhooks produce view objects
+		group := "apps"
+		s := NewSource(mgr, "test", opv1a1.Source{
+			Resource:   opv1a1.Resource{Group: &group, Kind: "Deployment"},
+			Type:       opv1a1.Webhook,
+			Parameters: &apiextensionsv1.JSON{Raw: []byte(`{"token": "x"}`)},
+		})
+		_, err := s.GetSource()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should limit the size of requests", func() {
+		url := startWebhook(`{"address": "127.0.0.1:0", "path": "/events", "token": "secret"}`)
+		body := bytes.Repeat([]byte("x"), MaxWebhookRequestSize+1)
+		resp := send(http.MethodPost, url, "secret", `{"data":"`+string(body)+`"}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+	})
+})
@@ -29,7 +29,7 @@ type ResourceRef struct {
 	Group   string
 	Version string
 	Kind    string
-	Type    string // Source type (Watcher/Periodic/OneShot) or Target type (Updater/Patcher)
+	Type    string // Source type (Watcher/Periodic/OneShot/Cron/Webhook/File/Events) or Target type (Updater/Patcher)
 }
 
 // Connection represents an edge between two controllers (via a view).
@@ -38,7 +38,7 @@ type Connection struct {
 	ToController   string
 	ViewKind       string
 	TargetType     string // Type when written (Updater/Patcher)
-	SourceType     string // Type when read (Watcher/Periodic/OneShot)
+	SourceType     string // Type when read (Watcher/Periodic/OneShot/Cron/Webhook/File/Events)
 }
 
 // BuildGraph constructs a visualization graph from an Operator spec.
@@ -96,9 +96,17 @@ func buildResourceRef(res opv1a1.Resource, resType string) ResourceRef {
 
 // extractPipelineStages extracts top-level pipeline stage names.
 func extractPipelineStages(pipeline opv1a1.Pipeline) []string {
-	stages := make([]string, 0, len(pipeline.Ops))
+	stages := make([]string, 0, len(pipeline.Branches)+len(pipeline.Ops))
+	for _, branch := range pipeline.Branches {
+		stages = append(stages, fmt.Sprintf("branch %s: ...", branch.Name))
+	}
 	for _, op := range pipeline.Ops {
 		opType := op.OpType()
+		if opType == "@distinct" {
+			// @distinct takes no arguments.
+			stages = append(stages, opType)
+			continue
+		}
 		// Add placeholder for the action.
 		stages = append(stages, fmt.Sprintf("%s: ...", opType))
 	}